@@ -1,6 +1,14 @@
 package gorpc
 
 import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
+	"github.com/solozyx/red-envelope/infra/tracing"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -13,16 +21,43 @@ type EnvelopeRpc struct{}
 // 4. 方法返回值要返回一个error类型
 // 5. 方法必须是可导出的
 
-func (e *EnvelopeRpc) SendOut(in services.RedEnvelopeSendingDTO, out *services.RedEnvelopeActivity) error {
+func (e *EnvelopeRpc) SendOut(in services.RedEnvelopeSendingDTO, out *services.RedEnvelopeActivity) (err error) {
+	ctx, span := requestContext("EnvelopeRpc.SendOut", in.RpcContext)
+	defer func() { err = endRequest(ctx, span, err) }()
 	s := services.GetRedEnvelopeService()
-	a, err := s.SendOut(in)
-	a.CopyTo(out)
+	a, err := s.SendOut(ctx, in)
+	if a != nil {
+		a.CopyTo(out)
+	}
 	return err
 }
 
-func (e *EnvelopeRpc) Receive(in services.RedEnvelopeReceiveDTO, out *services.RedEnvelopeItemDTO) error {
+func (e *EnvelopeRpc) Receive(in services.RedEnvelopeReceiveDTO, out *services.RedEnvelopeItemDTO) (err error) {
+	ctx, span := requestContext("EnvelopeRpc.Receive", in.RpcContext)
+	defer func() { err = endRequest(ctx, span, err) }()
 	s := services.GetRedEnvelopeService()
-	item, err := s.Receive(in)
-	item.CopyTo(out)
+	item, err := s.Receive(ctx, in)
+	if item != nil {
+		item.CopyTo(out)
+	}
 	return err
 }
+
+// RPC 没有请求头 请求ID和 trace context 由调用方在参数中传入
+// 没有传入请求ID时生成新的 新增的 RPC 接口都通过它创建服务的 ctx
+func requestContext(name string, rc services.RpcContext) (context.Context, trace.Span) {
+	ctx := base.WithRequestId(context.Background(), base.NormalizeRequestId(rc.RequestId))
+	ctx, span := tracing.StartServer(ctx, rc.Trace, name)
+	span.SetAttributes(attribute.String(base.RequestIdField, base.RequestId(ctx)))
+	return ctx, span
+}
+
+// 结束 RPC 调用的 span 错误由 errs.RpcError 转换 描述是和 web 接口相同的 base.Res json
+// 内部错误只返回通用描述 原因记录到日志
+func endRequest(ctx context.Context, span trace.Span, err error) error {
+	base.EndSpan(span, err)
+	if err != nil && errs.CodeOf(err) == errs.Internal {
+		base.Logger(ctx).Error(err)
+	}
+	return errs.RpcError(err)
+}
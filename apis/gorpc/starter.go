@@ -9,6 +9,10 @@ type GoRPCApiStarter struct {
 	infra.BaseStarter
 }
 
+func (s *GoRPCApiStarter) DependsOn() []string {
+	return []string{"goRPC"}
+}
+
 func (s *GoRPCApiStarter) Init(ctx infra.StarterContext) {
 	base.RpcRegister(new(EnvelopeRpc))
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/solozyx/red-envelope/infra"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -32,24 +33,21 @@ func createHandler(ctx iris.Context) {
 	// 获取请求参数 读取 request body
 	account := services.AccountCreatedDTO{}
 	err := ctx.ReadJSON(&account)
-	r := base.Res{
-		Code: base.ResCodeOk,
-	}
 	if err != nil {
-		r.Code = base.ResCodeRequestParamsErr
-		r.Message = err.Error()
-		// 向response写JSON
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
 		return
 	}
 	// 执行创建账户操作
 	service := services.GetAccountService()
-	dto, err := service.CreateAccount(account)
+	dto, err := service.CreateAccount(base.RequestContext(ctx), account)
 	if err != nil {
-		r.Code = base.ResCodeInternalServerErr
-		r.Message = err.Error()
+		writeErr(ctx, err)
+		return
+	}
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: dto,
 	}
-	r.Data = dto
 	ctx.JSON(&r)
 }
 
@@ -57,26 +55,21 @@ func createHandler(ctx iris.Context) {
 func transferHandler(ctx iris.Context) {
 	account := services.AccountTransferDTO{}
 	err := ctx.ReadJSON(&account)
-	r := base.Res{
-		Code: base.ResCodeOk,
-	}
 	if err != nil {
-		r.Code = base.ResCodeRequestParamsErr
-		r.Message = err.Error()
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
 		return
 	}
 	// 转账
 	service := services.GetAccountService()
-	status, err := service.Transfer(account)
+	status, err := service.Transfer(base.RequestContext(ctx), account)
 	if err != nil {
-		r.Code = base.ResCodeInternalServerErr
-		r.Message = err.Error()
+		// 余额不足等转账失败的错误由 errs 映射为 ResCodeBizTransferredFailure
+		writeErr(ctx, err)
+		return
 	}
-	r.Data = status
-	if status != services.TransferredStatusSuccess {
-		r.Code = base.ResCodeBizTransferredFailure
-		r.Message = err.Error()
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: status,
 	}
 	ctx.JSON(&r)
 }
@@ -85,26 +78,21 @@ func transferHandler(ctx iris.Context) {
 func rechargeHandler(ctx iris.Context) {
 	account := services.AccountTransferDTO{}
 	err := ctx.ReadJSON(&account)
-	r := base.Res{
-		Code: base.ResCodeOk,
-	}
 	if err != nil {
-		r.Code = base.ResCodeRequestParamsErr
-		r.Message = err.Error()
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
 		return
 	}
 	// 转账
 	service := services.GetAccountService()
-	status, err := service.StoreValue(account)
+	status, err := service.StoreValue(base.RequestContext(ctx), account)
 	if err != nil {
-		r.Code = base.ResCodeInternalServerErr
-		r.Message = err.Error()
+		// 余额不足等转账失败的错误由 errs 映射为 ResCodeBizTransferredFailure
+		writeErr(ctx, err)
+		return
 	}
-	r.Data = status
-	if status != services.TransferredStatusSuccess {
-		r.Code = base.ResCodeBizTransferredFailure
-		r.Message = err.Error()
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: status,
 	}
 	ctx.JSON(&r)
 }
@@ -114,16 +102,14 @@ func getEnvelopeAccountHandler(ctx iris.Context) {
 	userId := ctx.URLParam("user_id")
 	service := services.GetAccountService()
 	dto := service.GetEnvelopeAccountByUserId(userId)
-	r := base.Res{
-		Code: base.ResCodeOk,
-	}
 	if dto == nil {
-		r.Code = base.ResCodeValidationErr
-		r.Message = "没有查询到数据"
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrNotFound)
 		return
 	}
-	r.Data = dto
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: dto,
+	}
 	ctx.JSON(&r)
 }
 
@@ -132,15 +118,13 @@ func getAccountHandler(ctx iris.Context) {
 	accountNo := ctx.URLParam("account_no")
 	service := services.GetAccountService()
 	dto := service.GetAccount(accountNo)
-	r := base.Res{
-		Code: base.ResCodeOk,
-	}
 	if dto == nil {
-		r.Code = base.ResCodeValidationErr
-		r.Message = "没有查询到数据"
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrNotFound)
 		return
 	}
-	r.Data = dto
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: dto,
+	}
 	ctx.JSON(&r)
 }
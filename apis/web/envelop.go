@@ -5,6 +5,7 @@ import (
 
 	"github.com/solozyx/red-envelope/infra"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -21,6 +22,7 @@ func (api *RedEnvelopeApi) Init() {
 	groupRouter := base.Iris().Party("/v1/envelope")
 	groupRouter.Post("/sendout", api.sendOutHandler)
 	groupRouter.Post("/receive", api.receiveHandler)
+	groupRouter.Post("/cancel", api.cancelHandler)
 }
 
 /*
@@ -35,47 +37,55 @@ func (api *RedEnvelopeApi) Init() {
 func (api *RedEnvelopeApi) sendOutHandler(ctx iris.Context) {
 	dto := services.RedEnvelopeSendingDTO{}
 	err := ctx.ReadJSON(&dto)
-	r := base.Res{
-		Code: base.ResCodeOk,
-	}
 	if err != nil {
-		r.Code = base.ResCodeRequestParamsErr
-		r.Message = err.Error()
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
 		return
 	}
 	// 发红包
-	activity, err := api.service.SendOut(dto)
+	activity, err := api.service.SendOut(base.RequestContext(ctx), dto)
 	if err != nil {
-		r.Code = base.ResCodeInternalServerErr
-		r.Message = err.Error()
-		ctx.JSON(&r)
+		writeErr(ctx, err)
 		return
 	}
-	r.Data = activity
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: activity,
+	}
 	ctx.JSON(&r)
 }
 
 func (api *RedEnvelopeApi) receiveHandler(ctx iris.Context) {
 	dto := services.RedEnvelopeReceiveDTO{}
 	err := ctx.ReadJSON(&dto)
+	if err != nil {
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
+		return
+	}
+	// 收红包
+	item, err := api.service.Receive(base.RequestContext(ctx), dto)
+	if err != nil {
+		writeErr(ctx, err)
+		return
+	}
 	r := base.Res{
 		Code: base.ResCodeOk,
+		Data: item,
 	}
+	ctx.JSON(&r)
+}
+
+func (api *RedEnvelopeApi) cancelHandler(ctx iris.Context) {
+	dto := services.RedEnvelopeCancelDTO{}
+	err := ctx.ReadJSON(&dto)
 	if err != nil {
-		r.Code = base.ResCodeRequestParamsErr
-		r.Message = err.Error()
-		ctx.JSON(&r)
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
 		return
 	}
-	// 收红包
-	item, err := api.service.Receive(dto)
+	// 取消红包
+	err = api.service.Cancel(base.RequestContext(ctx), dto)
 	if err != nil {
-		r.Code = base.ResCodeInternalServerErr
-		r.Message = err.Error()
-		ctx.JSON(&r)
+		writeErr(ctx, err)
 		return
 	}
-	r.Data = item
-	ctx.JSON(&r)
+	ctx.JSON(&base.Res{Code: base.ResCodeOk})
 }
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"github.com/kataras/iris"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 聊天群的根路径 /v1/group
+
+func init() {
+	infra.RegisterApi(&GroupApi{})
+}
+
+type GroupApi struct {
+	service services.GroupService
+}
+
+func (api *GroupApi) Init() {
+	api.service = services.GetGroupService()
+	groupRouter := base.Iris().Party("/v1/group")
+	groupRouter.Post("/create", api.createHandler)
+	groupRouter.Post("/join", api.joinHandler)
+	groupRouter.Post("/leave", api.leaveHandler)
+	groupRouter.Get("/get", api.getHandler)
+}
+
+// 创建群 /v1/group/create
+func (api *GroupApi) createHandler(ctx iris.Context) {
+	dto := services.GroupCreatedDTO{}
+	err := ctx.ReadJSON(&dto)
+	if err != nil {
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
+		return
+	}
+	group, err := api.service.CreateGroup(dto)
+	if err != nil {
+		writeErr(ctx, err)
+		return
+	}
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: group,
+	}
+	ctx.JSON(&r)
+}
+
+// 加入群 /v1/group/join
+func (api *GroupApi) joinHandler(ctx iris.Context) {
+	dto := services.GroupMemberDTO{}
+	err := ctx.ReadJSON(&dto)
+	if err != nil {
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
+		return
+	}
+	err = api.service.Join(dto)
+	if err != nil {
+		writeErr(ctx, err)
+		return
+	}
+	ctx.JSON(&base.Res{Code: base.ResCodeOk})
+}
+
+// 退出群 /v1/group/leave
+func (api *GroupApi) leaveHandler(ctx iris.Context) {
+	dto := services.GroupMemberDTO{}
+	err := ctx.ReadJSON(&dto)
+	if err != nil {
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
+		return
+	}
+	err = api.service.Leave(dto)
+	if err != nil {
+		writeErr(ctx, err)
+		return
+	}
+	ctx.JSON(&base.Res{Code: base.ResCodeOk})
+}
+
+// 查询群信息 /v1/group/get
+func (api *GroupApi) getHandler(ctx iris.Context) {
+	groupId := ctx.URLParam("group_id")
+	dto := api.service.GetGroup(groupId)
+	if dto == nil {
+		writeErr(ctx, errs.ErrNotFound)
+		return
+	}
+	r := base.Res{
+		Code: base.ResCodeOk,
+		Data: dto,
+	}
+	ctx.JSON(&r)
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"github.com/kataras/iris"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
+	"github.com/solozyx/red-envelope/infra/scheduler"
+)
+
+// 定时任务管理的根路径 /v1/admin/jobs
+
+func init() {
+	infra.RegisterApi(&JobApi{})
+}
+
+type JobApi struct {
+}
+
+var errJobNameRequired = errs.New(errs.InvalidArgument, "job.name_required", "任务名称不能为空")
+
+type jobNameDTO struct {
+	Name string `json:"name"`
+}
+
+func (api *JobApi) Init() {
+	jobRouter := base.Iris().Party("/v1/admin/jobs", base.AdminAuthHandler())
+	jobRouter.Get("/", api.listHandler)
+	jobRouter.Get("/history", api.historyHandler)
+	jobRouter.Post("/pause", api.pauseHandler)
+	jobRouter.Post("/resume", api.resumeHandler)
+	jobRouter.Post("/trigger", api.triggerHandler)
+}
+
+// 所有任务的状态 /v1/admin/jobs
+func (api *JobApi) listHandler(ctx iris.Context) {
+	ctx.JSON(&base.Res{
+		Code: base.ResCodeOk,
+		Data: scheduler.GetScheduler().Jobs(),
+	})
+}
+
+// 任务执行记录 /v1/admin/jobs/history?name=refund&size=20
+func (api *JobApi) historyHandler(ctx iris.Context) {
+	name := ctx.URLParamDefault("name", "")
+	size := ctx.URLParamIntDefault("size", 20)
+	if size <= 0 || size > 100 {
+		size = 20
+	}
+	ctx.JSON(&base.Res{
+		Code: base.ResCodeOk,
+		Data: scheduler.GetScheduler().History(name, size),
+	})
+}
+
+// 暂停任务 /v1/admin/jobs/pause
+func (api *JobApi) pauseHandler(ctx iris.Context) {
+	api.handle(ctx, scheduler.GetScheduler().Pause)
+}
+
+// 恢复任务 /v1/admin/jobs/resume
+func (api *JobApi) resumeHandler(ctx iris.Context) {
+	api.handle(ctx, scheduler.GetScheduler().Resume)
+}
+
+// 立即执行1次任务 /v1/admin/jobs/trigger
+func (api *JobApi) triggerHandler(ctx iris.Context) {
+	api.handle(ctx, scheduler.GetScheduler().Trigger)
+}
+
+func (api *JobApi) handle(ctx iris.Context, fn func(name string) error) {
+	dto := jobNameDTO{}
+	if err := ctx.ReadJSON(&dto); err != nil {
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
+		return
+	}
+	if dto.Name == "" {
+		writeErr(ctx, errJobNameRequired)
+		return
+	}
+	if err := fn(dto.Name); err != nil {
+		writeErr(ctx, err)
+		return
+	}
+	ctx.JSON(&base.Res{
+		Code: base.ResCodeOk,
+	})
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"github.com/kataras/iris"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
+)
+
+// 日志级别管理的根路径 /v1/admin/log
+
+func init() {
+	infra.RegisterApi(&LogApi{})
+}
+
+type LogApi struct {
+}
+
+var (
+	errLogLevelRequired = errs.New(errs.InvalidArgument, "log.level_required", "日志级别不能为空")
+	errLogLevelInvalid  = errs.New(errs.InvalidArgument, "log.level_invalid", "日志级别错误")
+)
+
+// package 为空时修改默认级别 level 为空时恢复包的日志级别为默认级别
+type logLevelDTO struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+}
+
+func (api *LogApi) Init() {
+	logRouter := base.Iris().Party("/v1/admin/log", base.AdminAuthHandler())
+	logRouter.Get("/level", api.levelsHandler)
+	logRouter.Post("/level", api.setLevelHandler)
+}
+
+// 当前的日志级别 /v1/admin/log/level 默认级别的包名为空
+func (api *LogApi) levelsHandler(ctx iris.Context) {
+	ctx.JSON(&base.Res{
+		Code: base.ResCodeOk,
+		Data: base.LogLevels(),
+	})
+}
+
+// 修改日志级别 /v1/admin/log/level {"package":"core/envelopes","level":"debug"}
+func (api *LogApi) setLevelHandler(ctx iris.Context) {
+	dto := logLevelDTO{}
+	if err := ctx.ReadJSON(&dto); err != nil {
+		writeErr(ctx, errs.ErrBadRequest.Wrap(err))
+		return
+	}
+	if dto.Package == "" && dto.Level == "" {
+		writeErr(ctx, errLogLevelRequired)
+		return
+	}
+	if dto.Level == "" {
+		base.ResetLogLevel(dto.Package)
+	} else if err := base.SetLogLevel(dto.Package, dto.Level); err != nil {
+		writeErr(ctx, errLogLevelInvalid.With("level", dto.Level).Wrap(err))
+		return
+	}
+	ctx.JSON(&base.Res{
+		Code: base.ResCodeOk,
+		Data: base.LogLevels(),
+	})
+}
@@ -0,0 +1,20 @@
+package web
+
+import (
+	"github.com/kataras/iris"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
+)
+
+// 返回错误响应 响应码和 http 状态码由 errs 统一映射 和 RPC 接口一致
+// Data 中返回错误的消息key 和详情 参数验证错误的详情包含每个字段的错误
+// 内部错误只返回通用描述 原因记录到日志
+func writeErr(ctx iris.Context, err error) {
+	r := errs.ToRes(err)
+	if r.Code == base.ResCodeInternalServerErr {
+		base.Logger(base.RequestContext(ctx)).Error(err)
+	}
+	ctx.StatusCode(errs.HttpStatus(err))
+	ctx.JSON(&r)
+}
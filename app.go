@@ -6,27 +6,51 @@ import (
 	_ "github.com/solozyx/red-envelope/apis/web"
 	_ "github.com/solozyx/red-envelope/core/accounts"
 	_ "github.com/solozyx/red-envelope/core/envelopes"
+	_ "github.com/solozyx/red-envelope/core/groups"
 	"github.com/solozyx/red-envelope/infra"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/filter"
+	"github.com/solozyx/red-envelope/infra/lock"
+	"github.com/solozyx/red-envelope/infra/metrics"
+	"github.com/solozyx/red-envelope/infra/migrate"
+	"github.com/solozyx/red-envelope/infra/scheduler"
+	"github.com/solozyx/red-envelope/infra/tracing"
 	"github.com/solozyx/red-envelope/jobs"
+	_ "github.com/solozyx/red-envelope/migrations"
 	_ "github.com/solozyx/red-envelope/views"
 )
 
+// starter 的启动顺序由依赖关系和优先级决定 启动时打印启动计划
+// 配置 starter.<name>.enabled=false 可以禁用 starter
 func init() {
 	// 注册 配置文件读取启动器
 	infra.Register(&base.PropsStarter{})
+	// 注册 日志 按配置文件 [log] 输出
+	infra.Register(&base.LogStarter{})
+	// 注册 链路追踪 按配置文件 [tracing] 导出 span
+	infra.Register(&tracing.TracingStarter{})
 	// 注册 数据库启动
 	infra.Register(&base.DbxDatabaseStarter{})
+	// 注册 数据库迁移 检查数据库版本
+	infra.Register(&migrate.MigrationStarter{})
+	// 注册 分布式锁
+	infra.Register(&lock.LockStarter{})
 	// 注册 用户请求参数验证启动器
 	infra.Register(&base.ValidatorStarter{})
+	// 注册 红包祝福语内容过滤启动器
+	infra.Register(&filter.ContentFilterStarter{})
 	// 注册 RPC server
 	infra.Register(&base.GoRPCStarter{})
 	infra.Register(&gorpc.GoRPCApiStarter{})
-	// 注册 过期红包退款 定时任务 要放在数据库starter之后 web starter 之前
-	infra.Register(&jobs.RefundExpiredJobStarter{})
+	// 注册 任务调度器 定时任务在 jobs 包中注册
+	infra.Register(&scheduler.SchedulerStarter{})
+	// 注册 红包过期延时队列
+	infra.Register(&jobs.EnvelopeExpiryStarter{})
 	infra.Register(&base.HookStarter{})
 
-	// 注册 iris web server 是阻塞式放到最后位置
+	// 注册 iris web server 是阻塞式的 在其他starter启动后启动
 	infra.Register(&base.IrisServerStarter{})
 	infra.Register(&infra.WebApiStarter{})
+	// 注册 Prometheus 指标接口
+	infra.Register(&metrics.MetricsStarter{})
 }
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/tietang/props/ini"
 
 	// 调用app.go的init方法初始化各种资源启动器
 	_ "github.com/solozyx/red-envelope"
 	"github.com/solozyx/red-envelope/comm"
 	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/migrate"
 )
 
 func main() {
@@ -15,6 +19,18 @@ func main() {
 	// 加载和解析配置文件
 	conf := ini.NewIniFileCompositeConfigSource(path + "/config.ini")
 
+	// 数据库迁移命令 brun migrate up|down [n]|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(conf, os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := infra.New(conf)
+	// iris web server 阻塞运行 收到停止信号后返回
 	app.Start()
+	// 等待所有starter停止完成
+	app.Stop()
 }
@@ -4,6 +4,8 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
 )
 
 // 查询数据库持久化对象的单实例
@@ -61,6 +63,9 @@ func (dao *AccountDao) Insert(data *Account) (int64, error) {
 // 入参 account 变化的金额 负为扣减 正为增加
 // 返回受影响行数 成功返回1 失败返回<=0的值
 func (dao *AccountDao) UpdateBalance(accountNo string, amount decimal.Decimal) (rows int64, err error) {
+	if base.Backend() == base.BackendSqlite {
+		return dao.updateBalanceSqlite(accountNo, amount)
+	}
 	// 该SQL语句使用 [乐观锁] 概念 在 where 条件加上限制 避免金额扣减为负数
 	// and balance>=-1*CAST(? as DECIMAL(30,6))
 	sql := "update account " +
@@ -0,0 +1,49 @@
+package accounts
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+type AccountHoldDao struct {
+	runner *dbx.TxRunner
+}
+
+// 冻结记录的写入
+func (dao *AccountHoldDao) Insert(data *AccountHold) (int64, error) {
+	result, err := dao.runner.Insert(data)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// 通过交易编号查询冻结记录
+func (dao *AccountHoldDao) GetByTradeNo(tradeNo string) *AccountHold {
+	sql := "select * from account_hold where trade_no = ?"
+	out := &AccountHold{}
+	ok, err := dao.runner.Get(out, sql, tradeNo)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return out
+}
+
+// 冻结状态更新 [乐观锁] 只有处于 from 状态的记录才会被更新
+// 扣款和解冻并发执行时 只有1个能更新成功
+func (dao *AccountHoldDao) UpdateStatus(tradeNo string, from, to services.HoldStatus) (int64, error) {
+	sql := "update account_hold set status=? where trade_no=? and status=?"
+	rs, err := dao.runner.Exec(sql, to, tradeNo, from)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
@@ -0,0 +1,41 @@
+package accounts
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// SQLite 没有定点小数类型 金额以字符串保存 decimal(30,6) 精度
+// CAST 会把金额转换为浮点数丢失精度 余额在 Go 中使用 decimal 计算
+
+type sqliteBalance struct {
+	Balance string `db:"balance"`
+}
+
+// 和 UpdateBalance 相同的 [乐观锁] 语义 余额不足时不更新 返回0
+// 读出的原余额作为更新条件 原余额已经被修改时不更新 返回0
+func (dao *AccountDao) updateBalanceSqlite(accountNo string, amount decimal.Decimal) (int64, error) {
+	old := &sqliteBalance{}
+	ok, err := dao.runner.Get(old, "select balance from account where account_no=?", accountNo)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	if !ok {
+		return 0, nil
+	}
+	balance, err := decimal.NewFromString(old.Balance)
+	if err != nil {
+		return 0, err
+	}
+	balance = balance.Add(amount)
+	if balance.IsNegative() {
+		return 0, nil
+	}
+	sql := "update account set balance=? where account_no=? and balance=?"
+	rs, err := dao.runner.Exec(sql, balance.StringFixed(6), accountNo, old.Balance)
+	if err != nil {
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
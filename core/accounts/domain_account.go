@@ -2,14 +2,14 @@ package accounts
 
 import (
 	"context"
+	"time"
 
-	"github.com/kataras/iris/core/errors"
 	"github.com/segmentio/ksuid"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
-	"github.com/tietang/dbx"
 
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/metrics"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -66,8 +66,8 @@ func (domain *accountDomain) createAccountLog() {
 	domain.accountLog.ChangeFlag = services.FlagAccountCreated
 }
 
-// 账户创建的业务逻辑代码
-func (domain *accountDomain) Create(dto services.AccountDTO) (*services.AccountDTO, error) {
+// 账户创建的业务逻辑代码 ctx 中的请求ID 写入账户流水
+func (domain *accountDomain) Create(ctx context.Context, dto services.AccountDTO) (*services.AccountDTO, error) {
 	// 创建账户持久化对象
 	domain.account = Account{}
 	// DTO 转换为 DAO
@@ -78,17 +78,16 @@ func (domain *accountDomain) Create(dto services.AccountDTO) (*services.AccountD
 
 	// 创建账户流水持久化对象
 	domain.createAccountLog()
+	domain.accountLog.RequestId = base.RequestId(ctx)
 
-	// TODO:NOTICE 持久化DAO对象是有状态的 每次操作都要使用新的
+	// TODO:NOTICE 仓储对象绑定事务 每次操作都要使用新的
 	//  持久化账户和流水这2个对象在同1个数据库事务中 整个过程要么全部成功 要么全部失败
-	accountDao := AccountDao{}
-	accountLogDao := AccountLogDao{}
 	var rdto *services.AccountDTO
-	// 快捷事务操作函数 base.Tx 在该函数所有的数据库独立操作 被认为构成1个事务
-	// 在事务中返回任何非nil的error 事务操作就会失败 在 Tx 中就会把数据库操作回滚
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		accountDao.runner = runner
-		accountLogDao.runner = runner
+	// 快捷事务操作函数 base.Transact 在该函数所有的数据库独立操作 被认为构成1个事务
+	// 在事务中返回任何非nil的error 事务操作就会失败 在 Transact 中就会把数据库操作回滚
+	err := base.Transact(ctx, func(tx base.Transaction) error {
+		accountDao := NewAccountRepository(tx)
+		accountLogDao := NewAccountLogRepository(tx)
 		// 插入账户数据
 		id, err := accountDao.Insert(&domain.account)
 		if err != nil {
@@ -97,7 +96,7 @@ func (domain *accountDomain) Create(dto services.AccountDTO) (*services.AccountD
 		}
 		if id <= 0 {
 			// 插入失败 事务回滚
-			return errors.New("创建账户失败")
+			return ErrCreateAccountFailed
 		}
 		// 如果插入成功 再插入流水数据
 		id, err = accountLogDao.Insert(&domain.accountLog)
@@ -105,7 +104,7 @@ func (domain *accountDomain) Create(dto services.AccountDTO) (*services.AccountD
 			return err
 		}
 		if id <= 0 {
-			return errors.New("创建账户流水失败")
+			return ErrCreateLogFailed
 		}
 		domain.account = *accountDao.GetOne(domain.account.AccountNo)
 		return nil
@@ -115,11 +114,11 @@ func (domain *accountDomain) Create(dto services.AccountDTO) (*services.AccountD
 }
 
 // 验证用户该账户是否已经存在
-func (domain *accountDomain) GetAccountByUserIdAndType(userId string, aType services.AccountType) *services.AccountDTO {
+// ctx 中有事务时在该事务中查询 memory 后端的事务不能嵌套
+func (domain *accountDomain) GetAccountByUserIdAndType(ctx context.Context, userId string, aType services.AccountType) *services.AccountDTO {
 	var a *Account
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		accountDao := AccountDao{runner: runner}
-		a = accountDao.GetByUserId(userId, int(aType))
+	err := base.Transact(ctx, func(tx base.Transaction) error {
+		a = NewAccountRepository(tx).GetByUserId(userId, int(aType))
 		return nil
 	})
 	if err != nil || a == nil {
@@ -130,27 +129,29 @@ func (domain *accountDomain) GetAccountByUserIdAndType(userId string, aType serv
 }
 
 // 领域对象 转账业务
-func (domain *accountDomain) Transfer(dto services.AccountTransferDTO) (status services.TransferredStatus, err error) {
-	err = base.Tx(func(runner *dbx.TxRunner) error {
-		// 把 *dbx.TxRunner 事务对象绑定到 context.Background() 上下文对象中
-		ctx := base.WithValueContext(context.Background(), runner)
-		status, err = domain.TransferWithContextTx(ctx, dto)
+func (domain *accountDomain) Transfer(ctx context.Context, dto services.AccountTransferDTO) (status services.TransferredStatus, err error) {
+	err = base.Transact(ctx, func(tx base.Transaction) error {
+		// 把事务对象绑定到 ctx 上下文对象中 保留 ctx 中的请求ID
+		txCtx := base.WithValueContext(ctx, tx)
+		status, err = domain.TransferWithContextTx(txCtx, dto)
 		return err
 	})
 	return status, err
 }
 
-// TODO:NOTICE 必须在 base.TX 事务块里面运行 不能单独运行
+// TODO:NOTICE 必须在 base.Transact 事务块里面运行 不能单独运行
 func (domain *accountDomain) TransferWithContextTx(ctx context.Context, dto services.AccountTransferDTO) (status services.TransferredStatus, err error) {
+	start, result := time.Now(), metrics.ResultFailure
+	defer func() { metrics.Transfer.Observe(start, result) }()
 	// 如果交易变化是支出类型 修正amount为负值
 	var amount = dto.Amount
 	if dto.ChangeFlag == services.FlagTransferOut {
 		amount = amount.Mul(decimal.NewFromFloat(-1))
 	}
 
-	err = base.ExecuteContext(ctx, func(runner *dbx.TxRunner) error {
-		accountDao := AccountDao{runner: runner}
-		accountLogDao := AccountLogDao{runner: runner}
+	err = base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		accountDao := NewAccountRepository(tx)
+		accountLogDao := NewAccountLogRepository(tx)
 		// 账户扣减时 检查余额是否足够和更新余额 通过乐观锁验证 余额足够则更新余额
 		rows, err := accountDao.UpdateBalance(dto.TradeBody.AccountNo, amount)
 		if err != nil {
@@ -160,10 +161,11 @@ func (domain *accountDomain) TransferWithContextTx(ctx context.Context, dto serv
 		}
 		if rows <= 0 && dto.ChangeFlag == services.FlagTransferOut {
 			status = services.TransferredStatusSufficientFunds
-			return errors.New("余额不足")
+			result = "insufficient_funds"
+			return ErrInsufficientFunds.With("accountNo", dto.TradeBody.AccountNo)
 		}
 		if rows <= 0 && dto.ChangeFlag == services.FlagTransferIn {
-			return errors.New("增加余额失败")
+			return ErrUpdateBalanceFailed.With("accountNo", dto.TradeBody.AccountNo)
 		}
 
 		// 交易主体ChangeFlag是资金转出,则交易目标余额增加
@@ -171,14 +173,14 @@ func (domain *accountDomain) TransferWithContextTx(ctx context.Context, dto serv
 			rows, err = accountDao.UpdateBalance(dto.TradeTarget.AccountNo, amount.Abs())
 			if rows < 1 || err != nil {
 				status = services.TransferredStatusFailure
-				return errors.New("目标账户余额增加失败")
+				return ErrUpdateBalanceFailed.With("accountNo", dto.TradeTarget.AccountNo).Wrap(err)
 			}
 		}
 
 		// 转账成功后 写入流水记录
 		account := accountDao.GetOne(dto.TradeBody.AccountNo)
 		if account == nil {
-			return errors.New("查询账户信息出错")
+			return ErrAccountNotFound.With("accountNo", dto.TradeBody.AccountNo)
 		}
 
 		// 创建账户流水记录
@@ -187,29 +189,30 @@ func (domain *accountDomain) TransferWithContextTx(ctx context.Context, dto serv
 		domain.createAccountLogNo()
 		domain.account = *account
 		domain.accountLog.Balance = domain.account.Balance
+		domain.accountLog.RequestId = base.RequestId(ctx)
 
 		id, err := accountLogDao.Insert(&domain.accountLog)
 		if err != nil || id <= 0 {
 			status = services.TransferredStatusFailure
 			// 返回错误 回滚事务
-			return errors.New("转账账户流水创建失败")
+			return ErrCreateLogFailed.Wrap(err)
 		}
 		return nil
 	})
 	if err != nil {
-		logrus.Error(err)
+		base.Logger(ctx).Error(err)
 	} else {
 		status = services.TransferredStatusSuccess
+		result = metrics.ResultSuccess
 	}
 	return
 }
 
-// 根据账户编号来查询账户信息
+// 根据账户编号来查询账户信息 只读查询 在从库执行
 func (domain *accountDomain) GetAccount(accountNo string) *services.AccountDTO {
 	var account *Account
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		accountDao := AccountDao{runner: runner}
-		account = accountDao.GetOne(accountNo)
+	err := base.ReadTransact(context.Background(), func(tx base.Transaction) error {
+		account = NewAccountRepository(tx).GetOne(accountNo)
 		return nil
 	})
 
@@ -224,11 +227,11 @@ func (domain *accountDomain) GetAccount(accountNo string) *services.AccountDTO {
 }
 
 // 根据用户ID来查询红包账户
+// 发红包 收红包时用来校验账户 刚创建的账户可能还没有同步到从库 在主库执行
 func (domain *accountDomain) GetEnvelopeAccountByUserId(userId string) *services.AccountDTO {
 	var account *Account
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		accountDao := AccountDao{runner: runner}
-		account = accountDao.GetByUserId(userId, int(services.EnvelopeAccountType))
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		account = NewAccountRepository(tx).GetByUserId(userId, int(services.EnvelopeAccountType))
 		return nil
 	})
 
@@ -242,13 +245,11 @@ func (domain *accountDomain) GetEnvelopeAccountByUserId(userId string) *services
 	return account.ToDTO()
 }
 
-// 根据流水Id查询账户流水
+// 根据流水Id查询账户流水 只读查询 在从库执行
 func (domain *accountDomain) GetAccountLog(logNo string) *services.AccountLogDTO {
-	dao := AccountLogDao{}
 	var al *AccountLog
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		dao.runner = runner
-		al = dao.GetOne(logNo)
+	err := base.ReadTransact(context.Background(), func(tx base.Transaction) error {
+		al = NewAccountLogRepository(tx).GetOne(logNo)
 		return nil
 	})
 	if err != nil {
@@ -261,13 +262,11 @@ func (domain *accountDomain) GetAccountLog(logNo string) *services.AccountLogDTO
 	return al.ToDTO()
 }
 
-// 根据交易编号查询账户流水
+// 根据交易编号查询账户流水 用于转账的幂等判断 在主库执行
 func (domain *accountDomain) GetAccountLogByTradeNo(tradeNo string) *services.AccountLogDTO {
-	dao := AccountLogDao{}
 	var al *AccountLog
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		dao.runner = runner
-		al = dao.GetByTradeNo(tradeNo)
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		al = NewAccountLogRepository(tx).GetByTradeNo(tradeNo)
 		return nil
 	})
 	if err != nil {
@@ -0,0 +1,132 @@
+package accounts
+
+import (
+	"context"
+
+	"github.com/segmentio/ksuid"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// TODO:NOTICE 资金冻结 扣款 解冻 都必须在 base.Transact 事务块里面运行 不能单独运行
+//  冻结: 账户可用余额扣减 写入冻结记录 用于预约红包 发红包时先冻结 到发布时间再扣款
+//  扣款: 冻结资金转入交易对方账户 冻结记录状态 冻结中 -> 已扣款
+//  解冻: 冻结资金退回可用余额 冻结记录状态 冻结中 -> 已解冻
+
+// 冻结资金
+func (domain *accountDomain) HoldWithContextTx(ctx context.Context, dto services.AccountHoldDTO) error {
+	return base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		accountDao := NewAccountRepository(tx)
+		holdDao := NewAccountHoldRepository(tx)
+		// 乐观锁扣减可用余额 余额不足时不更新
+		rows, err := accountDao.UpdateBalance(dto.TradeBody.AccountNo, dto.Amount.Neg())
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrInsufficientFunds.With("accountNo", dto.TradeBody.AccountNo)
+		}
+		hold := AccountHold{}
+		hold.FromDTO(&dto)
+		hold.HoldNo = ksuid.New().Next().String()
+		hold.Status = services.HoldStatusHeld
+		id, err := holdDao.Insert(&hold)
+		if err != nil || id <= 0 {
+			return ErrCreateHoldFailed.Wrap(err)
+		}
+		return domain.writeHoldLog(ctx, accountDao, NewAccountLogRepository(tx),
+			dto.TradeBody, dto.TradeBody, &hold, services.AccountHoldFrozen, services.FlagTransferOut)
+	})
+}
+
+// 冻结资金扣款 转入交易对方账户
+func (domain *accountDomain) CaptureHoldWithContextTx(ctx context.Context, tradeNo string, target services.TradeParticipator) error {
+	return base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		accountDao := NewAccountRepository(tx)
+		holdDao := NewAccountHoldRepository(tx)
+		hold := holdDao.GetByTradeNo(tradeNo)
+		if hold == nil {
+			return ErrHoldNotFound.With("tradeNo", tradeNo)
+		}
+		rows, err := holdDao.UpdateStatus(tradeNo, services.HoldStatusHeld, services.HoldStatusCaptured)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrHoldNotHeld.With("tradeNo", tradeNo)
+		}
+		rows, err = accountDao.UpdateBalance(target.AccountNo, hold.Amount)
+		if err != nil || rows <= 0 {
+			return ErrUpdateBalanceFailed.With("accountNo", target.AccountNo).Wrap(err)
+		}
+		body := services.TradeParticipator{
+			AccountNo: hold.AccountNo,
+			UserId:    hold.UserId,
+			Username:  hold.Username,
+		}
+		// 流水记在收款方 冻结时已经记录了付款方的支出
+		return domain.writeHoldLog(ctx, accountDao, NewAccountLogRepository(tx),
+			target, body, hold, services.EnvelopeHoldCaptured, services.FlagTransferIn)
+	})
+}
+
+// 解冻 冻结资金退回可用余额
+func (domain *accountDomain) ReleaseHoldWithContextTx(ctx context.Context, tradeNo string) error {
+	return base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		accountDao := NewAccountRepository(tx)
+		holdDao := NewAccountHoldRepository(tx)
+		hold := holdDao.GetByTradeNo(tradeNo)
+		if hold == nil {
+			return ErrHoldNotFound.With("tradeNo", tradeNo)
+		}
+		rows, err := holdDao.UpdateStatus(tradeNo, services.HoldStatusHeld, services.HoldStatusReleased)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrHoldNotHeld.With("tradeNo", tradeNo)
+		}
+		rows, err = accountDao.UpdateBalance(hold.AccountNo, hold.Amount)
+		if err != nil || rows <= 0 {
+			return ErrUpdateBalanceFailed.With("accountNo", hold.AccountNo).Wrap(err)
+		}
+		body := services.TradeParticipator{
+			AccountNo: hold.AccountNo,
+			UserId:    hold.UserId,
+			Username:  hold.Username,
+		}
+		return domain.writeHoldLog(ctx, accountDao, NewAccountLogRepository(tx),
+			body, body, hold, services.AccountHoldReleased, services.FlagTransferIn)
+	})
+}
+
+// 写入冻结相关的账户流水 余额为交易主体当前余额
+func (domain *accountDomain) writeHoldLog(ctx context.Context, accountDao AccountRepository, accountLogDao AccountLogRepository,
+	body, target services.TradeParticipator, hold *AccountHold,
+	changeType services.ChangeType, changeFlag services.ChangeFlag) error {
+	account := accountDao.GetOne(body.AccountNo)
+	if account == nil {
+		return ErrAccountNotFound.With("accountNo", body.AccountNo)
+	}
+	domain.account = *account
+	domain.accountLog = AccountLog{}
+	domain.accountLog.FromTransferDTO(&services.AccountTransferDTO{
+		TradeNo:     hold.TradeNo,
+		TradeBody:   body,
+		TradeTarget: target,
+		Amount:      hold.Amount,
+		ChangeType:  changeType,
+		ChangeFlag:  changeFlag,
+		Desc:        hold.Desc,
+	})
+	domain.createAccountLogNo()
+	domain.accountLog.Balance = account.Balance
+	domain.accountLog.RequestId = base.RequestId(ctx)
+	id, err := accountLogDao.Insert(&domain.accountLog)
+	if err != nil || id <= 0 {
+		base.Logger(ctx).Error(err)
+		return ErrCreateLogFailed.Wrap(err)
+	}
+	return nil
+}
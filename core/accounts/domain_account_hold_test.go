@@ -0,0 +1,70 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/shopspring/decimal"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+func TestAccountDomain_Hold(t *testing.T) {
+	domain := new(accountDomain)
+	body, err := domain.Create(context.Background(), services.AccountDTO{
+		UserId:      ksuid.New().Next().String(),
+		Username:    "冻结测试",
+		Balance:     decimal.NewFromFloat(100),
+		Status:      1,
+		AccountType: int(services.EnvelopeAccountType),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	participator := services.TradeParticipator{
+		AccountNo: body.AccountNo,
+		UserId:    body.UserId,
+		Username:  body.Username,
+	}
+	hold := func(amount decimal.Decimal) (services.AccountHoldDTO, error) {
+		dto := services.AccountHoldDTO{
+			TradeNo:   ksuid.New().Next().String(),
+			TradeBody: participator,
+			Amount:    amount,
+			Desc:      "冻结测试",
+		}
+		err := base.Transact(context.Background(), func(tx base.Transaction) error {
+			ctx := base.WithValueContext(context.Background(), tx)
+			return new(accountDomain).HoldWithContextTx(ctx, dto)
+		})
+		return dto, err
+	}
+
+	Convey("资金冻结和解冻", t, func() {
+		dto, err := hold(decimal.NewFromFloat(30))
+		So(err, ShouldBeNil)
+		So(domain.GetAccount(body.AccountNo).Balance.String(), ShouldEqual, "70")
+
+		// 余额不足不能冻结
+		_, err = hold(decimal.NewFromFloat(200))
+		So(err, ShouldNotBeNil)
+		So(domain.GetAccount(body.AccountNo).Balance.String(), ShouldEqual, "70")
+
+		err = base.Transact(context.Background(), func(tx base.Transaction) error {
+			ctx := base.WithValueContext(context.Background(), tx)
+			return new(accountDomain).ReleaseHoldWithContextTx(ctx, dto.TradeNo)
+		})
+		So(err, ShouldBeNil)
+		So(domain.GetAccount(body.AccountNo).Balance.String(), ShouldEqual, "100")
+
+		// 已解冻的记录不能再次扣款
+		err = base.Transact(context.Background(), func(tx base.Transaction) error {
+			ctx := base.WithValueContext(context.Background(), tx)
+			return new(accountDomain).CaptureHoldWithContextTx(ctx, dto.TradeNo, participator)
+		})
+		So(err, ShouldNotBeNil)
+	})
+}
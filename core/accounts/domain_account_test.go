@@ -1,6 +1,7 @@
 package accounts
 
 import (
+	"context"
 	"testing"
 
 	"github.com/segmentio/ksuid"
@@ -21,7 +22,7 @@ func TestAccountDomain_Create(t *testing.T) {
 	domain := new(accountDomain)
 
 	Convey("账户创建测试", t, func() {
-		rdto, err := domain.Create(dto)
+		rdto, err := domain.Create(context.Background(), dto)
 		So(err, ShouldBeNil)
 		So(rdto, ShouldNotBeNil)
 		So(rdto.Balance.String(), ShouldEqual, dto.Balance.String())
@@ -53,7 +54,7 @@ func TestAccountDomain_Transfer(t *testing.T) {
 
 	Convey("转账测试", t, func() {
 		// 交易主体创建
-		aBody, err := domain.Create(*body)
+		aBody, err := domain.Create(context.Background(), *body)
 		So(err, ShouldBeNil)
 		So(aBody, ShouldNotBeNil)
 		So(aBody.Balance.String(), ShouldEqual, body.Balance.String())
@@ -63,7 +64,7 @@ func TestAccountDomain_Transfer(t *testing.T) {
 		So(aBody.AccountName, ShouldEqual, body.AccountName)
 
 		// 交易目标创建
-		aTarget, err := domain.Create(*target)
+		aTarget, err := domain.Create(context.Background(), *target)
 		So(err, ShouldBeNil)
 		So(aTarget, ShouldNotBeNil)
 		So(aTarget.Balance.String(), ShouldEqual, target.Balance.String())
@@ -94,7 +95,7 @@ func TestAccountDomain_Transfer(t *testing.T) {
 			}
 
 			// 执行转账
-			status, err := domain.Transfer(dto)
+			status, err := domain.Transfer(context.Background(), dto)
 			So(err, ShouldBeNil)
 			So(status, ShouldEqual, services.TransferredStatusSuccess)
 
@@ -125,7 +126,7 @@ func TestAccountDomain_Transfer(t *testing.T) {
 				ChangeFlag: services.FlagTransferOut,
 				Desc:       "余额不足，转入其他账户",
 			}
-			status, err := domain.Transfer(dto)
+			status, err := domain.Transfer(context.Background(), dto)
 			// 扣减余额不足 应该返回错误
 			So(err, ShouldNotBeNil)
 			So(status, ShouldEqual, services.TransferredStatusSufficientFunds)
@@ -157,7 +158,7 @@ func TestAccountDomain_Transfer(t *testing.T) {
 				ChangeFlag: services.FlagTransferIn,
 				Desc:       "充值",
 			}
-			status, err := domain.Transfer(dto)
+			status, err := domain.Transfer(context.Background(), dto)
 			So(err, ShouldBeNil)
 			So(status, ShouldEqual, services.TransferredStatusSuccess)
 
@@ -0,0 +1,22 @@
+package accounts
+
+import (
+	"github.com/solozyx/red-envelope/infra/errs"
+)
+
+// 资金账户的业务错误 消息key 以 account. 开头
+var (
+	ErrAccountNotFound   = errs.New(errs.NotFound, "account.not_found", "账户不存在")
+	ErrAccountExists     = errs.New(errs.AlreadyExists, "account.exists", "用户的该类型账户已经存在")
+	ErrInsufficientFunds = errs.New(errs.InsufficientFunds, "account.insufficient_funds", "余额不足")
+	// changeFlag 为支出时 changeType 必须小于0 为收入时必须大于0
+	ErrChangeTypeMismatch = errs.New(errs.InvalidArgument, "account.change_type_mismatch", "资金变化类型和变化标识不一致")
+	ErrHoldNotFound       = errs.New(errs.NotFound, "account.hold_not_found", "没有找到冻结记录")
+	ErrHoldNotHeld        = errs.New(errs.FailedPrecondition, "account.hold_not_held", "冻结记录不是冻结中状态")
+
+	// 以下是数据库写入没有生效等内部错误 客户端只会看到通用描述
+	ErrCreateAccountFailed = errs.New(errs.Internal, "account.create_failed", "创建账户失败")
+	ErrCreateLogFailed     = errs.New(errs.Internal, "account.create_log_failed", "账户流水创建失败")
+	ErrCreateHoldFailed    = errs.New(errs.Internal, "account.create_hold_failed", "资金冻结记录创建失败")
+	ErrUpdateBalanceFailed = errs.New(errs.Internal, "account.update_balance_failed", "账户余额更新失败")
+)
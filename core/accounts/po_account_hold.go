@@ -0,0 +1,33 @@
+package accounts
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 账户资金冻结记录持久化对象 映射 account_hold 表
+type AccountHold struct {
+	Id        int64               `db:"id,omitempty"`
+	HoldNo    string              `db:"hold_no,unique"`
+	TradeNo   string              `db:"trade_no"`
+	AccountNo string              `db:"account_no"`
+	UserId    string              `db:"user_id"`
+	Username  string              `db:"username"`
+	Amount    decimal.Decimal     `db:"amount"`
+	Status    services.HoldStatus `db:"status"`
+	Desc      string              `db:"desc"`
+	CreatedAt time.Time           `db:"created_at,omitempty"`
+	UpdatedAt time.Time           `db:"updated_at,omitempty"`
+}
+
+func (po *AccountHold) FromDTO(dto *services.AccountHoldDTO) {
+	po.TradeNo = dto.TradeNo
+	po.AccountNo = dto.TradeBody.AccountNo
+	po.UserId = dto.TradeBody.UserId
+	po.Username = dto.TradeBody.Username
+	po.Amount = dto.Amount
+	po.Desc = dto.Desc
+}
@@ -25,6 +25,7 @@ type AccountLog struct {
 	ChangeFlag      services.ChangeFlag `db:"change_flag"`
 	Status          int                 `db:"status"`
 	Desc            string              `db:"desc"`
+	RequestId       string              `db:"request_id"` // 产生该流水的请求ID
 	// 创建时间系统自动生成 该字段无需手动赋值
 	CreatedAt time.Time `db:"created_at,omitempty"`
 }
@@ -60,5 +61,6 @@ func (po *AccountLog) ToDTO() *services.AccountLogDTO {
 		Status:          po.Status,
 		Decs:            po.Desc,
 		CreatedAt:       po.CreatedAt,
+		RequestId:       po.RequestId,
 	}
 }
@@ -0,0 +1,71 @@
+package accounts
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 仓储接口 领域层只依赖接口 不关心数据保存在 MySQL 还是内存数据库
+// 方法语义和 dbx 的 DAO 相同 更新方法返回受影响行数
+
+// 账户仓储
+type AccountRepository interface {
+	GetOne(accountNo string) *Account
+	GetByUserId(userId string, accountType int) *Account
+	Insert(data *Account) (int64, error)
+	UpdateBalance(accountNo string, amount decimal.Decimal) (int64, error)
+	UpdateStatus(accountNo string, status int) (int64, error)
+}
+
+// 账户流水仓储
+type AccountLogRepository interface {
+	GetOne(logNo string) *AccountLog
+	GetByTradeNo(tradeNo string) *AccountLog
+	Insert(data *AccountLog) (int64, error)
+}
+
+// 资金冻结记录仓储
+type AccountHoldRepository interface {
+	Insert(data *AccountHold) (int64, error)
+	GetByTradeNo(tradeNo string) *AccountHold
+	UpdateStatus(tradeNo string, from, to services.HoldStatus) (int64, error)
+}
+
+// 根据事务类型创建仓储 tx 来自 base.Transact
+func NewAccountRepository(tx base.Transaction) AccountRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &AccountDao{runner: t}
+	case *memdb.Tx:
+		return &memAccountRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewAccountLogRepository(tx base.Transaction) AccountLogRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &AccountLogDao{runner: t}
+	case *memdb.Tx:
+		return &memAccountLogRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewAccountHoldRepository(tx base.Transaction) AccountHoldRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &AccountHoldDao{runner: t}
+	case *memdb.Tx:
+		return &memAccountHoldRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
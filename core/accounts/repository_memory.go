@@ -0,0 +1,191 @@
+package accounts
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 内存数据库的仓储实现 表中保存持久化对象的值 返回副本
+// 唯一索引和乐观锁条件和 MySQL 的表结构 SQL 保持一致
+
+const (
+	tableAccount     = "account"
+	tableAccountLog  = "account_log"
+	tableAccountHold = "account_hold"
+)
+
+func init() {
+	base.RegisterMemorySeed(seedSystemAccount)
+}
+
+// 系统红包账户 和迁移脚本中初始化的数据相同
+func seedSystemAccount(tx *memdb.Tx) error {
+	sa := base.GetSystemAccount()
+	a := &Account{
+		AccountNo:   sa.AccountNo,
+		AccountName: sa.AccountName,
+		AccountType: int(services.SystemEnvelopeAccountType),
+		UserId:      sa.UserId,
+		Username:    sql.NullString{String: sa.Username, Valid: true},
+		Status:      1,
+	}
+	_, err := (&memAccountRepository{tx: tx}).Insert(a)
+	return err
+}
+
+type memAccountRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memAccountRepository) find(match func(a *Account) bool) (int64, *Account) {
+	var id int64
+	var out *Account
+	r.tx.Scan(tableAccount, func(rowId int64, row interface{}) bool {
+		a := row.(Account)
+		if match(&a) {
+			id, out = rowId, &a
+			return false
+		}
+		return true
+	})
+	return id, out
+}
+
+func (r *memAccountRepository) GetOne(accountNo string) *Account {
+	_, a := r.find(func(a *Account) bool { return a.AccountNo == accountNo })
+	return a
+}
+
+func (r *memAccountRepository) GetByUserId(userId string, accountType int) *Account {
+	_, a := r.find(func(a *Account) bool {
+		return a.UserId == userId && a.AccountType == accountType
+	})
+	return a
+}
+
+func (r *memAccountRepository) Insert(data *Account) (int64, error) {
+	if r.GetOne(data.AccountNo) != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *data
+	row.Id = r.tx.NextId(tableAccount)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableAccount, row.Id, row)
+	return row.Id, nil
+}
+
+// 和 AccountDao.UpdateBalance 相同 余额不足时不更新 返回0
+func (r *memAccountRepository) UpdateBalance(accountNo string, amount decimal.Decimal) (int64, error) {
+	id, a := r.find(func(a *Account) bool { return a.AccountNo == accountNo })
+	if a == nil || a.Balance.LessThan(amount.Neg()) {
+		return 0, nil
+	}
+	a.Balance = a.Balance.Add(amount)
+	a.UpdatedAt = time.Now()
+	r.tx.Put(tableAccount, id, *a)
+	return 1, nil
+}
+
+func (r *memAccountRepository) UpdateStatus(accountNo string, status int) (int64, error) {
+	id, a := r.find(func(a *Account) bool { return a.AccountNo == accountNo })
+	if a == nil {
+		return 0, nil
+	}
+	a.Status = status
+	a.UpdatedAt = time.Now()
+	r.tx.Put(tableAccount, id, *a)
+	return 1, nil
+}
+
+type memAccountLogRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memAccountLogRepository) find(match func(l *AccountLog) bool) *AccountLog {
+	var out *AccountLog
+	r.tx.Scan(tableAccountLog, func(id int64, row interface{}) bool {
+		l := row.(AccountLog)
+		if match(&l) {
+			out = &l
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+func (r *memAccountLogRepository) GetOne(logNo string) *AccountLog {
+	return r.find(func(l *AccountLog) bool { return l.LogNo == logNo })
+}
+
+func (r *memAccountLogRepository) GetByTradeNo(tradeNo string) *AccountLog {
+	return r.find(func(l *AccountLog) bool { return l.TradeNo == tradeNo })
+}
+
+func (r *memAccountLogRepository) Insert(data *AccountLog) (int64, error) {
+	if r.GetOne(data.LogNo) != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *data
+	row.Id = r.tx.NextId(tableAccountLog)
+	row.CreatedAt = time.Now()
+	r.tx.Put(tableAccountLog, row.Id, row)
+	return row.Id, nil
+}
+
+type memAccountHoldRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memAccountHoldRepository) find(match func(h *AccountHold) bool) (int64, *AccountHold) {
+	var id int64
+	var out *AccountHold
+	r.tx.Scan(tableAccountHold, func(rowId int64, row interface{}) bool {
+		h := row.(AccountHold)
+		if match(&h) {
+			id, out = rowId, &h
+			return false
+		}
+		return true
+	})
+	return id, out
+}
+
+// hold_no 和 trade_no 都是唯一索引
+func (r *memAccountHoldRepository) Insert(data *AccountHold) (int64, error) {
+	_, exists := r.find(func(h *AccountHold) bool {
+		return h.HoldNo == data.HoldNo || h.TradeNo == data.TradeNo
+	})
+	if exists != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *data
+	row.Id = r.tx.NextId(tableAccountHold)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableAccountHold, row.Id, row)
+	return row.Id, nil
+}
+
+func (r *memAccountHoldRepository) GetByTradeNo(tradeNo string) *AccountHold {
+	_, h := r.find(func(h *AccountHold) bool { return h.TradeNo == tradeNo })
+	return h
+}
+
+func (r *memAccountHoldRepository) UpdateStatus(tradeNo string, from, to services.HoldStatus) (int64, error) {
+	id, h := r.find(func(h *AccountHold) bool { return h.TradeNo == tradeNo })
+	if h == nil || h.Status != from {
+		return 0, nil
+	}
+	h.Status = to
+	h.UpdatedAt = time.Now()
+	r.tx.Put(tableAccountHold, id, *h)
+	return 1, nil
+}
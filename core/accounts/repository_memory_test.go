@@ -0,0 +1,40 @@
+package accounts
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+)
+
+func TestMemAccountRepository(t *testing.T) {
+	Convey("内存账户仓储", t, func() {
+		db := memdb.New()
+		err := db.Tx(func(tx *memdb.Tx) error {
+			repo := NewAccountRepository(tx)
+			id, err := repo.Insert(&Account{AccountNo: "a1", UserId: "u1", AccountType: 1,
+				Balance: decimal.NewFromFloat(10)})
+			So(err, ShouldBeNil)
+			So(id, ShouldEqual, 1)
+
+			_, err = repo.Insert(&Account{AccountNo: "a1"})
+			So(err, ShouldEqual, memdb.ErrDuplicate)
+
+			So(repo.GetByUserId("u1", 1).AccountNo, ShouldEqual, "a1")
+			So(repo.GetByUserId("u1", 2), ShouldBeNil)
+
+			// 乐观锁 余额不足时不更新
+			rows, err := repo.UpdateBalance("a1", decimal.NewFromFloat(-11))
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 0)
+			rows, err = repo.UpdateBalance("a1", decimal.NewFromFloat(-10))
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			So(repo.GetOne("a1").Balance.IsZero(), ShouldBeTrue)
+			return nil
+		})
+		So(err, ShouldBeNil)
+	})
+}
@@ -1,8 +1,7 @@
 package accounts
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"sync"
 
 	"github.com/shopspring/decimal"
@@ -27,18 +26,19 @@ func init() {
 type accountService struct{}
 
 // 创建账户
-func (s *accountService) CreateAccount(dto services.AccountCreatedDTO) (*services.AccountDTO, error) {
+func (s *accountService) CreateAccount(ctx context.Context, dto services.AccountCreatedDTO) (_ *services.AccountDTO, err error) {
+	ctx, span := base.StartSpan(ctx, "AccountService.CreateAccount")
+	defer func() { base.EndSpan(span, err) }()
 	domain := accountDomain{}
 	// 验证输入参数
-	err := base.ValidateStruct(&dto)
+	err = base.ValidateStruct(&dto)
 	if err != nil {
 		return nil, err
 	}
 	// 验证账户是否已经存在
-	acc := domain.GetAccountByUserIdAndType(dto.UserId, services.EnvelopeAccountType)
+	acc := domain.GetAccountByUserIdAndType(ctx, dto.UserId, services.EnvelopeAccountType)
 	if acc != nil {
-		return acc, errors.New(fmt.Sprintf("用户的该类型账户已经存在，username=%s[%s],账户类型:%d",
-			acc.Username, acc.UserId, acc.AccountType))
+		return acc, ErrAccountExists.With("userId", acc.UserId).With("accountType", acc.AccountType)
 	}
 	// 执行账户创建的业务
 	amount, err := decimal.NewFromString(dto.Amount)
@@ -55,14 +55,16 @@ func (s *accountService) CreateAccount(dto services.AccountCreatedDTO) (*service
 		Status:       1,
 		Balance:      amount,
 	}
-	return domain.Create(account)
+	return domain.Create(ctx, account)
 }
 
 // 转账
-func (s *accountService) Transfer(dto services.AccountTransferDTO) (services.TransferredStatus, error) {
+func (s *accountService) Transfer(ctx context.Context, dto services.AccountTransferDTO) (_ services.TransferredStatus, err error) {
+	ctx, span := base.StartSpan(ctx, "AccountService.Transfer")
+	defer func() { base.EndSpan(span, err) }()
 	domain := accountDomain{}
 	// 验证dto参数
-	err := base.ValidateStruct(&dto)
+	err = base.ValidateStruct(&dto)
 	if err != nil {
 		return services.TransferredStatusFailure, err
 	}
@@ -73,21 +75,19 @@ func (s *accountService) Transfer(dto services.AccountTransferDTO) (services.Tra
 	dto.Amount = amount
 	if dto.ChangeFlag == services.FlagTransferOut {
 		if dto.ChangeType > 0 {
-			return services.TransferredStatusFailure,
-				errors.New("如果changeFlag为支出，那么changeType必须小于0")
+			return services.TransferredStatusFailure, ErrChangeTypeMismatch
 		}
 	} else {
 		if dto.ChangeType < 0 {
-			return services.TransferredStatusFailure,
-				errors.New("如果changeFlag为收入，那么changeType必须大于0")
+			return services.TransferredStatusFailure, ErrChangeTypeMismatch
 		}
 	}
 	// 执行转账操作
-	return domain.Transfer(dto)
+	return domain.Transfer(ctx, dto)
 }
 
 // 储值
-func (s *accountService) StoreValue(dto services.AccountTransferDTO) (services.TransferredStatus, error) {
+func (s *accountService) StoreValue(ctx context.Context, dto services.AccountTransferDTO) (services.TransferredStatus, error) {
 	// 交易对象 和 交易主体 都是自己 转账的特殊形式
 	dto.TradeTarget = dto.TradeBody
 	// 入账
@@ -95,7 +95,7 @@ func (s *accountService) StoreValue(dto services.AccountTransferDTO) (services.T
 	// 储值
 	dto.ChangeType = services.AccountStoreValue
 	// 转账储值
-	return s.Transfer(dto)
+	return s.Transfer(ctx, dto)
 }
 
 func (s *accountService) GetAccount(accountNo string) *services.AccountDTO {
@@ -1,6 +1,8 @@
 package accounts
 
 import (
+	"context"
+	"errors"
 	"github.com/shopspring/decimal"
 	"testing"
 
@@ -21,7 +23,7 @@ func TestAccountService_CreateAccount(t *testing.T) {
 	}
 	s := new(accountService)
 	Convey("账户创建测试", t, func() {
-		rdto, err := s.CreateAccount(dto)
+		rdto, err := s.CreateAccount(context.Background(), dto)
 		So(err, ShouldBeNil)
 		So(rdto, ShouldNotBeNil)
 		So(rdto.Balance.String(), ShouldEqual, dto.Amount)
@@ -59,7 +61,7 @@ func TestAccountService_Transfer(t *testing.T) {
 		s := new(accountService)
 
 		// 创建账户1
-		a1DTO, err := s.CreateAccount(a1)
+		a1DTO, err := s.CreateAccount(context.Background(), a1)
 		So(err, ShouldBeNil)
 		So(a1DTO, ShouldNotBeNil)
 		So(a1DTO.UserId, ShouldEqual, a1.UserId)
@@ -71,7 +73,7 @@ func TestAccountService_Transfer(t *testing.T) {
 		So(a1DTO.Status, ShouldEqual, 1)
 
 		// 创建账户2
-		a2DTO, err := s.CreateAccount(a2)
+		a2DTO, err := s.CreateAccount(context.Background(), a2)
 		So(err, ShouldBeNil)
 		So(a2DTO, ShouldNotBeNil)
 		So(a2DTO.UserId, ShouldEqual, a2.UserId)
@@ -102,7 +104,7 @@ func TestAccountService_Transfer(t *testing.T) {
 				ChangeFlag: services.FlagTransferOut,
 				Desc:       "从账户1转入账户2，其中账户1余额足够",
 			}
-			status, err := s.Transfer(tDTO)
+			status, err := s.Transfer(context.Background(), tDTO)
 			So(err, ShouldBeNil)
 			So(status, ShouldEqual, services.TransferredStatusSuccess)
 
@@ -136,9 +138,10 @@ func TestAccountService_Transfer(t *testing.T) {
 				ChangeFlag: services.FlagTransferOut,
 				Desc:       "从账户2转入账户1，其中账户2余额不足",
 			}
-			status, err := s.Transfer(tDTO)
+			status, err := s.Transfer(context.Background(), tDTO)
 			So(err, ShouldNotBeNil)
 			So(status, ShouldEqual, services.TransferredStatusSufficientFunds)
+			So(errors.Is(err, ErrInsufficientFunds), ShouldBeTrue)
 
 			// 验证账户1金额变化
 			a1DTOByAccountNo := s.GetAccount(a1DTO.AccountNo)
@@ -169,7 +172,7 @@ func TestAccountService_Transfer(t *testing.T) {
 				ChangeType: services.AccountStoreValue,
 				ChangeFlag: services.FlagTransferIn,
 			}
-			status, err := s.StoreValue(tDTO)
+			status, err := s.StoreValue(context.Background(), tDTO)
 			So(err, ShouldBeNil)
 			So(status, ShouldEqual, services.TransferredStatusSuccess)
 
@@ -1,12 +1,14 @@
 package envelopes
 
 import (
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 	"github.com/tietang/dbx"
 
+	"github.com/solozyx/red-envelope/infra/base"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -49,16 +51,21 @@ func (dao *RedEnvelopeGoodsDao) GetOne(envelopeNo string) *RedEnvelopeGoods {
 //  2.减少实际的库存更新 当红包剩余金额和数量不足时不去更新数据库不操作磁盘 过滤掉无效的更新 提高总体性能
 //  在100人的微信群,发个红包,数量10个,如果100人都抢,只有10个人能抢到,过滤掉无效的90次更新数据库操作
 //  整体性能提升 30%
+//  已取消的红包不能再被领取 和取消红包的 CancelIfUnclaimed 通过同一行记录的条件更新互斥
 // 返回 影响行数
 func (dao *RedEnvelopeGoodsDao) UpdateBalance(envelopeNo string, amount decimal.Decimal) (int64, error) {
+	if base.Backend() == base.BackendSqlite {
+		return dao.updateBalanceSqlite(envelopeNo, amount)
+	}
 	// CAST 函数 CAST(? as DECIMAL(30,6)) string --> decimal
 	sql := "update red_envelope_goods " +
 		" set remain_amount = remain_amount - CAST(? as DECIMAL(30,6)), " +
 		" remain_quantity = remain_quantity - 1 " +
 		" where envelope_no = ? " +
 		" and remain_quantity > 0 " +
-		" and remain_amount >= CAST(? as DECIMAL(30,6)) "
-	rs, err := dao.runner.Exec(sql, amount.String(), envelopeNo, amount.String())
+		" and remain_amount >= CAST(? as DECIMAL(30,6)) " +
+		" and status <> ? "
+	rs, err := dao.runner.Exec(sql, amount.String(), envelopeNo, amount.String(), services.OrderDisabled)
 	if err != nil {
 		logrus.Error(err)
 		return 0, err
@@ -78,15 +85,123 @@ func (dao *RedEnvelopeGoodsDao) UpdateOrderStatus(envelopeNo string, status serv
 	return rs.RowsAffected()
 }
 
-// 过期 把所有的过期红包都查询出来 分页 limit offset size
-func (dao *RedEnvelopeGoodsDao) FindExpired(offset, size int) []RedEnvelopeGoods {
+// 按状态更新订单状态和支付状态 [乐观锁] 只有处于 from 状态和 fromPay 支付状态的红包才会被更新
+// 预约红包发布和取消并发执行时 只有1个能更新成功
+func (dao *RedEnvelopeGoodsDao) UpdateStatusIf(envelopeNo string, from, to services.OrderStatus, fromPay, toPay services.PayStatus) (int64, error) {
+	sql := " update red_envelope_goods set status=?, pay_status=? where envelope_no=? and status=? and pay_status=?"
+	rs, err := dao.runner.Exec(sql, to, toPay, envelopeNo, from, fromPay)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// 按支付状态更新退款订单 [乐观锁] 只有处于 from 支付状态的退款订单才会被更新
+// 退款流程重复执行或多个节点同时恢复同1个退款订单时 只有1个能更新成功
+func (dao *RedEnvelopeGoodsDao) UpdatePayStatusIf(envelopeNo string, from, to services.PayStatus, status services.OrderStatus) (int64, error) {
+	sql := " update red_envelope_goods set pay_status=?, status=? where envelope_no=? and pay_status=?"
+	rs, err := dao.runner.Exec(sql, to, status, envelopeNo, from)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// 查询原红包正在进行中的过期退款订单
+func (dao *RedEnvelopeGoodsDao) GetRefundingByOrigin(originEnvelopeNo string) *RedEnvelopeGoods {
+	sql := "select * from red_envelope_goods " +
+		" where origin_envelope_no=? and order_type=? and status=? and pay_status in (?,?) " +
+		" order by id desc limit 1"
+	out := &RedEnvelopeGoods{}
+	ok, err := dao.runner.Get(out, sql, originEnvelopeNo, services.OrderTypeRefund,
+		services.OrderExpired, services.Refunding, services.Refunded)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return out
+}
+
+// 查询 before 之前没有完成的过期退款订单 按 id 键集分页
+// 退款中: 退款转账可能没有执行 已退款: 退款转账已完成 但订单状态还没有更新
+func (dao *RedEnvelopeGoodsDao) FindUnfinishedRefunds(lastId int64, before time.Time, size int) []RedEnvelopeGoods {
+	var goodsList []RedEnvelopeGoods
+	sql := "select * from red_envelope_goods " +
+		" where id>? and order_type=? and status=? and pay_status in (?,?) and updated_at<? " +
+		" order by id asc limit ?"
+	err := dao.runner.Find(&goodsList, sql, lastId, services.OrderTypeRefund, services.OrderExpired,
+		services.Refunding, services.Refunded, before, size)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return goodsList
+}
+
+// 取消还没有被领取的红包 [乐观锁]
+// 只有发红包本人 已支付(立即发布的红包是支付中 发布后的预约红包是已支付) 未过期 没有人领取过(remain_quantity = quantity)的红包才会被更新为失效状态
+// 和收红包的 UpdateBalance 并发执行时 数据库对同一行的更新串行执行 先执行的一方使另一方的where条件不成立
+// 返回 影响行数 0 表示红包已被领取或状态已改变
+func (dao *RedEnvelopeGoodsDao) CancelIfUnclaimed(envelopeNo, userId string) (int64, error) {
+	sql := " update red_envelope_goods set status=? " +
+		" where envelope_no=? and user_id=? " +
+		" and (status=? or status=?) and pay_status in (?,?) " +
+		" and remain_quantity=quantity and expired_at>? "
+	rs, err := dao.runner.Exec(sql, services.OrderDisabled, envelopeNo, userId,
+		services.OrderCreate, services.OrderSending, services.Paying, services.Payed, time.Now())
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// 到达发布时间的预约红包
+func (dao *RedEnvelopeGoodsDao) FindDuePublish(size int) []RedEnvelopeGoods {
+	var goodsList []RedEnvelopeGoods
+	sql := "select * from red_envelope_goods " +
+		" where status=? and pay_status=? and publish_at<=? " +
+		" order by publish_at limit ?"
+	err := dao.runner.Find(&goodsList, sql, services.OrderCreate, services.PayNothing, time.Now(), size)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return goodsList
+}
+
+// 过期 把 id 大于 lastId 的过期红包按 id 顺序查询出来
+// 未支付的预约红包没有资金转入系统红包账户 不需要退款 退款订单本身不需要退款
+func (dao *RedEnvelopeGoodsDao) FindExpired(lastId int64, maxAttempts, size int) []RedEnvelopeGoods {
 	var goodsList []RedEnvelopeGoods
 	now := time.Now()
+	// 按 id 做键集分页 退款会修改红包状态 使用 limit offset,size 分页会跳过数据
+	// 退款尝试次数达到 maxAttempts 的红包不再查询 需要人工处理
+	sql := "select g.* from red_envelope_goods g " +
+		" left join red_envelope_refund_attempt a on a.envelope_no=g.envelope_no " +
+		" where g.id>? and g.order_type=? and g.remain_quantity>0 " +
+		" and g.expired_at<? and (g.status<4 or g.status>5) and g.pay_status<>? " +
+		" and (a.id is null or a.attempts<?) " +
+		" order by g.id asc limit ?"
+	err := dao.runner.Find(&goodsList, sql, lastId, services.OrderTypeSending, now, services.PayNothing, maxAttempts, size)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return goodsList
+}
+
+// 还没有退款的已发布红包 按 id 键集分页 用于重启后重建过期延时队列
+// 过期时间 expired_at 有索引 作为持久化的到期时间索引
+func (dao *RedEnvelopeGoodsDao) FindPendingExpiry(lastId int64, size int) []RedEnvelopeGoods {
+	var goodsList []RedEnvelopeGoods
 	sql := "select * from red_envelope_goods " +
-		" where remain_quantity>0 " +
-		" and expired_at<? and (status<4 or status>5) " +
-		" limit ?,?"
-	err := dao.runner.Find(&goodsList, sql, now, offset, size)
+		" where id>? and order_type=? and remain_quantity>0 " +
+		" and (status<4 or status>5) and pay_status<>? " +
+		" order by id asc limit ?"
+	err := dao.runner.Find(&goodsList, sql, lastId, services.OrderTypeSending, services.PayNothing, size)
 	if err != nil {
 		logrus.Error(err)
 	}
@@ -114,12 +229,25 @@ func (dao *RedEnvelopeGoodsDao) FindByUser(userId string, offset, limit int) []R
 	return goods
 }
 
-func (dao *RedEnvelopeGoodsDao) ListReceivable(offset, size int) []RedEnvelopeGoods {
+// 可领取的红包列表 groupIds 为用户所在的群 不限群的红包对所有用户可见
+// 只查询发布单 退款单和原红包有相同的群编号 不能被领取
+func (dao *RedEnvelopeGoodsDao) ListReceivable(groupIds []string, offset, size int) []RedEnvelopeGoods {
 	var goods []RedEnvelopeGoods
 	now := time.Now()
+	args := []interface{}{now, now, services.OrderDisabled, services.OrderTypeSending}
+	groupCond := " and group_id='' "
+	if len(groupIds) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(groupIds)), ",")
+		groupCond = " and (group_id='' or group_id in (" + placeholders + ")) "
+		for _, id := range groupIds {
+			args = append(args, id)
+		}
+	}
+	args = append(args, offset, size)
 	sql := " select * from red_envelope_goods " +
-		" where  remain_quantity>0  and expired_at>? order by created_at desc limit ?,?"
-	err := dao.runner.Find(&goods, sql, now, offset, size)
+		" where  remain_quantity>0  and expired_at>? and publish_at<=? and status<>? and order_type=? " + groupCond +
+		" order by created_at desc limit ?,?"
+	err := dao.runner.Find(&goods, sql, args...)
 	if err != nil {
 		logrus.Error(err)
 	}
@@ -0,0 +1,49 @@
+package envelopes
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+// SQLite 没有定点小数类型 金额以字符串保存 decimal(30,6) 精度
+// 剩余金额在 Go 中使用 decimal 计算
+
+type sqliteRemain struct {
+	RemainAmount   string               `db:"remain_amount"`
+	RemainQuantity int                  `db:"remain_quantity"`
+	Status         services.OrderStatus `db:"status"`
+}
+
+// 和 UpdateBalance 相同的 [乐观锁] 语义 剩余数量或金额不足 红包已取消时不更新 返回0
+// 读出的剩余金额和数量作为更新条件 已经被其他人领取时不更新 返回0
+func (dao *RedEnvelopeGoodsDao) updateBalanceSqlite(envelopeNo string, amount decimal.Decimal) (int64, error) {
+	old := &sqliteRemain{}
+	sql := "select remain_amount, remain_quantity, status from red_envelope_goods where envelope_no=?"
+	ok, err := dao.runner.Get(old, sql, envelopeNo)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	if !ok || old.RemainQuantity <= 0 || old.Status == services.OrderDisabled {
+		return 0, nil
+	}
+	remain, err := decimal.NewFromString(old.RemainAmount)
+	if err != nil {
+		return 0, err
+	}
+	if remain.LessThan(amount) {
+		return 0, nil
+	}
+	sql = "update red_envelope_goods " +
+		" set remain_amount=?, remain_quantity=remain_quantity-1 " +
+		" where envelope_no=? and remain_amount=? and remain_quantity=? and status<>?"
+	rs, err := dao.runner.Exec(sql, remain.Sub(amount).StringFixed(6), envelopeNo,
+		old.RemainAmount, old.RemainQuantity, services.OrderDisabled)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
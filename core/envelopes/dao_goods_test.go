@@ -118,10 +118,18 @@ func TestRedEnvelopeDao_FindExpired(t *testing.T) {
 	err := base.Tx(func(runner *dbx.TxRunner) error {
 		dao := RedEnvelopeGoodsDao{runner: runner}
 		Convey("寻找过期", t, func() {
-			goods := dao.FindExpired(0, 10)
+			goods := dao.FindExpired(0, 3, 10)
 			So(len(goods), ShouldBeGreaterThan, 0)
-			for _, good := range goods {
+			for i, good := range goods {
 				fmt.Println(good.EnvelopeNo)
+				if i > 0 {
+					So(good.Id, ShouldBeGreaterThan, goods[i-1].Id)
+				}
+			}
+			// 键集分页 从上一页最后1个id之后继续查询
+			next := dao.FindExpired(goods[len(goods)-1].Id, 3, 10)
+			for _, good := range next {
+				So(good.Id, ShouldBeGreaterThan, goods[len(goods)-1].Id)
 			}
 
 		})
@@ -132,6 +140,53 @@ func TestRedEnvelopeDao_FindExpired(t *testing.T) {
 	}
 }
 
+// 可领取的红包只有发布单
+func TestRedEnvelopeDao_ListReceivable(t *testing.T) {
+	err := base.Tx(func(runner *dbx.TxRunner) error {
+		dao := RedEnvelopeGoodsDao{runner: runner}
+		Convey("退款单不在可领取列表中", t, func() {
+			groupId := ksuid.New().Next().String()
+			newGoods := func(orderType services.OrderType) *RedEnvelopeGoods {
+				return &RedEnvelopeGoods{
+					EnvelopeNo:     ksuid.New().Next().String(),
+					EnvelopeType:   int(services.LuckyEnvelopeType),
+					Username:       sql.NullString{String: ksuid.New().Next().String(), Valid: true},
+					UserId:         ksuid.New().Next().String(),
+					Blessing:       sql.NullString{String: "测试用红包商品", Valid: true},
+					Amount:         decimal.NewFromFloat(100),
+					Quantity:       10,
+					RemainAmount:   decimal.NewFromFloat(100),
+					RemainQuantity: 10,
+					ExpiredAt:      time.Now().Add(time.Hour),
+					PublishAt:      time.Now().Add(-time.Second),
+					Status:         services.OrderSending,
+					OrderType:      orderType,
+					PayStatus:      services.Payed,
+					GroupId:        groupId,
+				}
+			}
+			sending := newGoods(services.OrderTypeSending)
+			refund := newGoods(services.OrderTypeRefund)
+			_, err := dao.Insert(sending)
+			So(err, ShouldBeNil)
+			_, err = dao.Insert(refund)
+			So(err, ShouldBeNil)
+
+			envelopeNos := make(map[string]bool)
+			for _, g := range dao.ListReceivable([]string{groupId}, 0, 20) {
+				So(g.OrderType, ShouldEqual, services.OrderTypeSending)
+				envelopeNos[g.EnvelopeNo] = true
+			}
+			So(envelopeNos[sending.EnvelopeNo], ShouldBeTrue)
+			So(envelopeNos[refund.EnvelopeNo], ShouldBeFalse)
+		})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
 // 更新状态
 func TestRedEnvelopeDao_UpdateOrderStatus(t *testing.T) {
 	err := base.Tx(func(runner *dbx.TxRunner) error {
@@ -168,3 +223,121 @@ func TestRedEnvelopeDao_UpdateOrderStatus(t *testing.T) {
 		logrus.Error(err)
 	}
 }
+
+// 取消还没有被领取的红包
+func TestRedEnvelopeDao_CancelIfUnclaimed(t *testing.T) {
+	err := base.Tx(func(runner *dbx.TxRunner) error {
+		dao := RedEnvelopeGoodsDao{runner: runner}
+		newGoods := func() *RedEnvelopeGoods {
+			return &RedEnvelopeGoods{
+				EnvelopeNo:     ksuid.New().Next().String(),
+				EnvelopeType:   int(services.LuckyEnvelopeType),
+				Username:       sql.NullString{String: ksuid.New().Next().String(), Valid: true},
+				UserId:         ksuid.New().Next().String(),
+				Blessing:       sql.NullString{String: "测试用红包商品", Valid: true},
+				Amount:         decimal.NewFromFloat(100),
+				AmountOne:      decimal.Decimal{},
+				Quantity:       10,
+				RemainAmount:   decimal.NewFromFloat(100),
+				RemainQuantity: 10,
+				ExpiredAt:      time.Now().Add(time.Hour),
+				PublishAt:      time.Now(),
+				Status:         services.OrderSending,
+				OrderType:      services.OrderTypeSending,
+				PayStatus:      services.Payed,
+			}
+		}
+		Convey("取消红包", t, func() {
+			Convey("没有人领取时可以取消 取消后不能再领取", func() {
+				good := newGoods()
+				id, err := dao.Insert(good)
+				So(id, ShouldBeGreaterThan, 0)
+				So(err, ShouldBeNil)
+
+				// 非发红包本人不能取消
+				rows, err := dao.CancelIfUnclaimed(good.EnvelopeNo, ksuid.New().Next().String())
+				So(err, ShouldBeNil)
+				So(rows, ShouldEqual, 0)
+
+				rows, err = dao.CancelIfUnclaimed(good.EnvelopeNo, good.UserId)
+				So(err, ShouldBeNil)
+				So(rows, ShouldEqual, 1)
+
+				rows, err = dao.UpdateBalance(good.EnvelopeNo, decimal.NewFromFloat(1))
+				So(err, ShouldBeNil)
+				So(rows, ShouldEqual, 0)
+				So(dao.GetOne(good.EnvelopeNo).Status, ShouldEqual, services.OrderDisabled)
+			})
+			Convey("已经有人领取时不能取消", func() {
+				good := newGoods()
+				id, err := dao.Insert(good)
+				So(id, ShouldBeGreaterThan, 0)
+				So(err, ShouldBeNil)
+
+				rows, err := dao.UpdateBalance(good.EnvelopeNo, decimal.NewFromFloat(1))
+				So(err, ShouldBeNil)
+				So(rows, ShouldEqual, 1)
+
+				rows, err = dao.CancelIfUnclaimed(good.EnvelopeNo, good.UserId)
+				So(err, ShouldBeNil)
+				So(rows, ShouldEqual, 0)
+			})
+		})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+// 退款订单支付状态的条件更新
+func TestRedEnvelopeDao_UpdatePayStatusIf(t *testing.T) {
+	err := base.Tx(func(runner *dbx.TxRunner) error {
+		dao := RedEnvelopeGoodsDao{runner: runner}
+		Convey("退款订单状态", t, func() {
+			originNo := ksuid.New().Next().String()
+			refund := &RedEnvelopeGoods{
+				EnvelopeNo:       ksuid.New().Next().String(),
+				EnvelopeType:     int(services.LuckyEnvelopeType),
+				Username:         sql.NullString{String: "测试用户", Valid: true},
+				UserId:           ksuid.New().Next().String(),
+				Blessing:         sql.NullString{String: "测试用退款订单", Valid: true},
+				Amount:           decimal.NewFromFloat(100),
+				Quantity:         10,
+				RemainAmount:     decimal.NewFromFloat(50),
+				RemainQuantity:   5,
+				ExpiredAt:        time.Now().Add(24 * time.Hour),
+				PublishAt:        time.Now(),
+				Status:           services.OrderExpired,
+				OrderType:        services.OrderTypeRefund,
+				PayStatus:        services.Refunding,
+				OriginEnvelopeNo: originNo,
+			}
+			id, err := dao.Insert(refund)
+			So(id, ShouldBeGreaterThan, 0)
+			So(err, ShouldBeNil)
+
+			out := dao.GetRefundingByOrigin(originNo)
+			So(out, ShouldNotBeNil)
+			So(out.EnvelopeNo, ShouldEqual, refund.EnvelopeNo)
+
+			rows, err := dao.UpdatePayStatusIf(refund.EnvelopeNo, services.Refunding, services.Refunded, services.OrderExpired)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			// 重复退款
+			rows, err = dao.UpdatePayStatusIf(refund.EnvelopeNo, services.Refunding, services.Refunded, services.OrderExpired)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 0)
+
+			rows, err = dao.UpdateStatusIf(refund.EnvelopeNo, services.OrderExpired, services.OrderExpiredRefundSucceed,
+				services.Refunded, services.Refunded)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			So(dao.GetRefundingByOrigin(originNo), ShouldBeNil)
+		})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
@@ -0,0 +1,88 @@
+package envelopes
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// last_error varchar(255)
+const maxLastErrorLength = 255
+
+type RefundAttemptDao struct {
+	runner *dbx.TxRunner
+}
+
+// 根据红包编号查询退款尝试记录
+func (dao *RefundAttemptDao) GetOne(envelopeNo string) *RefundAttempt {
+	var out = &RefundAttempt{EnvelopeNo: envelopeNo}
+	ok, err := dao.runner.GetOne(out)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return out
+}
+
+// 认领过期红包 [乐观锁] 认领成功返回 true
+// 没有退款尝试记录时先创建1条待重试记录
+// 只有待重试状态 或者 处理中但认领已超时(认领节点可能已经宕机)的记录 并且尝试次数小于 maxAttempts 才能被认领
+// 多个 worker 或多个节点同时认领同1个红包 只有1个能认领成功
+func (dao *RefundAttemptDao) Claim(envelopeNo string, maxAttempts int, claimTimeout time.Duration) (bool, error) {
+	now := time.Now()
+	sql := base.InsertIgnore() + " into red_envelope_refund_attempt(envelope_no,attempts,status,last_error,claimed_at) " +
+		" values(?,0,?,'',?)"
+	_, err := dao.runner.Exec(sql, envelopeNo, services.RefundAttemptPending, now)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	sql = "update red_envelope_refund_attempt " +
+		" set attempts=attempts+1,status=?,claimed_at=? " +
+		" where envelope_no=? and attempts<? " +
+		" and (status=? or (status=? and claimed_at<?))"
+	rs, err := dao.runner.Exec(sql, services.RefundAttemptProcessing, now,
+		envelopeNo, maxAttempts,
+		services.RefundAttemptPending, services.RefundAttemptProcessing, now.Add(-claimTimeout))
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	rows, err := rs.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// 退款成功
+func (dao *RefundAttemptDao) Succeed(envelopeNo string) (int64, error) {
+	sql := "update red_envelope_refund_attempt set status=?,last_error='' where envelope_no=? and status=?"
+	rs, err := dao.runner.Exec(sql, services.RefundAttemptSucceeded, envelopeNo, services.RefundAttemptProcessing)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// 退款失败 记录失败原因 回到待重试状态
+func (dao *RefundAttemptDao) Fail(envelopeNo, lastError string) (int64, error) {
+	if runes := []rune(lastError); len(runes) > maxLastErrorLength {
+		lastError = string(runes[:maxLastErrorLength])
+	}
+	sql := "update red_envelope_refund_attempt set status=?,last_error=? where envelope_no=? and status=?"
+	rs, err := dao.runner.Exec(sql, services.RefundAttemptPending, lastError, envelopeNo, services.RefundAttemptProcessing)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
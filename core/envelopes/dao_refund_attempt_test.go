@@ -0,0 +1,71 @@
+package envelopes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+	_ "github.com/solozyx/red-envelope/textx"
+)
+
+func TestRefundAttemptDao_Claim(t *testing.T) {
+	err := base.Tx(func(runner *dbx.TxRunner) error {
+		dao := RefundAttemptDao{runner: runner}
+		Convey("过期红包退款认领", t, func() {
+			envelopeNo := ksuid.New().Next().String()
+			maxAttempts := 2
+
+			// 第1次认领成功
+			ok, err := dao.Claim(envelopeNo, maxAttempts, time.Minute)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			// 处理中 认领没有超时 不能重复认领
+			ok, err = dao.Claim(envelopeNo, maxAttempts, time.Minute)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+
+			// 退款失败 记录失败原因
+			rows, err := dao.Fail(envelopeNo, "转账失败")
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			out := dao.GetOne(envelopeNo)
+			So(out, ShouldNotBeNil)
+			So(out.Attempts, ShouldEqual, 1)
+			So(out.Status, ShouldEqual, services.RefundAttemptPending)
+			So(out.LastError, ShouldEqual, "转账失败")
+
+			// 第2次认领成功 之后达到最大尝试次数
+			ok, err = dao.Claim(envelopeNo, maxAttempts, time.Minute)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			_, err = dao.Fail(envelopeNo, "转账失败")
+			So(err, ShouldBeNil)
+			ok, err = dao.Claim(envelopeNo, maxAttempts, time.Minute)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+		Convey("过期红包退款成功", t, func() {
+			envelopeNo := ksuid.New().Next().String()
+			ok, err := dao.Claim(envelopeNo, 3, time.Minute)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			rows, err := dao.Succeed(envelopeNo)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			// 已完成的红包不能再次认领
+			ok, err = dao.Claim(envelopeNo, 3, time.Minute)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
@@ -0,0 +1,31 @@
+package envelopes
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+)
+
+type RefundStepDao struct {
+	runner *dbx.TxRunner
+}
+
+// 步骤记录的写入 (refund_no, step) 唯一 同1个步骤重复写入会失败 事务回滚
+func (dao *RefundStepDao) Insert(data *RefundStepLog) (int64, error) {
+	rs, err := dao.runner.Insert(data)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.LastInsertId()
+}
+
+// 查询退款订单的所有步骤记录
+func (dao *RefundStepDao) FindByRefundNo(refundNo string) []*RefundStepLog {
+	var steps []*RefundStepLog
+	sql := "select * from red_envelope_refund_step where refund_no=? order by id"
+	err := dao.runner.Find(&steps, sql, refundNo)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return steps
+}
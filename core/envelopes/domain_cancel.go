@@ -0,0 +1,85 @@
+package envelopes
+
+import (
+	"context"
+	"time"
+
+	"github.com/solozyx/red-envelope/core/accounts"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// TODO:NOTICE 取消还没有被领取的红包 以下操作在同1个数据库事务中执行
+//  1.乐观锁更新红包状态为失效 和收红包并发时只有1方能成功
+//  2.创建退款订单 通过 OriginEnvelopeNo 关联原红包
+//  3.系统红包账户把红包总金额退回发红包人账户
+func (domain *goodsDomain) CancelUnclaimed(ctx context.Context, dto services.RedEnvelopeCancelDTO) error {
+	goods := domain.Get(dto.EnvelopeNo)
+	if goods == nil {
+		return ErrEnvelopeNotFound.With("envelopeNo", dto.EnvelopeNo)
+	}
+	if goods.UserId != dto.UserId {
+		return ErrNotOwner
+	}
+	account := services.GetAccountService().GetEnvelopeAccountByUserId(goods.UserId)
+	if account == nil {
+		return accounts.ErrAccountNotFound.With("userId", goods.UserId)
+	}
+
+	// 退款订单
+	refund := *goods
+	refund.Id = 0
+	refund.OrderType = services.OrderTypeRefund
+	refund.Status = services.OrderDisabled
+	refund.PayStatus = services.Refunded
+	refund.OriginEnvelopeNo = goods.EnvelopeNo
+	refund.ExpiredAt = time.Now().Add(24 * time.Hour)
+	refundDomain := goodsDomain{RedEnvelopeGoods: refund}
+	refundDomain.createEnvelopeNo()
+
+	err := base.Transact(ctx, func(tx base.Transaction) error {
+		rows, err := NewGoodsRepository(tx).CancelIfUnclaimed(goods.EnvelopeNo, dto.UserId)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrNotCancellable.With("envelopeNo", dto.EnvelopeNo)
+		}
+		txCtx := base.WithValueContext(ctx, tx)
+		id, err := refundDomain.Save(txCtx)
+		if err != nil || id <= 0 {
+			return ErrCreateRefundFailed.Wrap(err)
+		}
+
+		systemAccount := base.GetSystemAccount()
+		transfer := services.AccountTransferDTO{
+			TradeNo: refundDomain.EnvelopeNo,
+			TradeBody: services.TradeParticipator{
+				AccountNo: systemAccount.AccountNo,
+				UserId:    systemAccount.UserId,
+				Username:  systemAccount.Username,
+			},
+			TradeTarget: services.TradeParticipator{
+				AccountNo: account.AccountNo,
+				UserId:    account.UserId,
+				Username:  account.Username,
+			},
+			// 没有人领取过 剩余金额就是红包总金额
+			Amount:     goods.Amount,
+			AmountStr:  goods.Amount.String(),
+			ChangeType: services.SysEnvelopeCanceledRefund,
+			ChangeFlag: services.FlagTransferOut,
+			Desc:       "取消红包退款,系统账户扣减资金,转给原红包发送人账户,红包编号: " + goods.EnvelopeNo,
+		}
+		status, err := accounts.NewAccountDomain().TransferWithContextTx(txCtx, transfer)
+		if status != services.TransferredStatusSuccess {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cancelExpiry(goods.EnvelopeNo)
+	return nil
+}
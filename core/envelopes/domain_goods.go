@@ -7,7 +7,6 @@ import (
 	"github.com/segmentio/ksuid"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
-	"github.com/tietang/dbx"
 
 	"github.com/solozyx/red-envelope/infra/base"
 	"github.com/solozyx/red-envelope/services"
@@ -38,24 +37,37 @@ func (domain *goodsDomain) Create(dto services.RedEnvelopeGoodsDTO) {
 		domain.RedEnvelopeGoods.AmountOne = amountOne
 		domain.RedEnvelopeGoods.Amount = amountOne.Mul(decimal.NewFromFloat(float64(dto.Quantity)))
 	}
-	if domain.EnvelopeType == int(services.LuckyEnvelopeType) {
-		// 碰运气红包 用户输入的红包金额 = 红包总金额
+	if domain.EnvelopeType == int(services.LuckyEnvelopeType) ||
+		domain.EnvelopeType == int(services.PassphraseEnvelopeType) {
+		// 碰运气红包 口令红包 用户输入的红包金额 = 红包总金额
 		domain.RedEnvelopeGoods.AmountOne = decimal.NewFromFloat(0)
 		domain.RedEnvelopeGoods.Amount, _ = decimal.NewFromString(dto.Amount)
 	}
 	domain.RemainAmount = domain.Amount
-	// 过期时间 默认24hour
-	domain.ExpiredAt = time.Now().Add(24 * time.Hour)
 	domain.Status = services.OrderCreate
 	domain.PayStatus = services.Paying
+	now := time.Now()
+	if domain.PublishAt.After(now) {
+		// 预约红包 到发布时间由定时任务扣款 状态 创建 -> 发布
+		domain.PayStatus = services.PayNothing
+	} else {
+		domain.PublishAt = now
+	}
+	// 过期时间 发布后默认24hour
+	domain.ExpiredAt = domain.PublishAt.Add(24 * time.Hour)
 	domain.createEnvelopeNo()
 }
 
-// 保存到红包商品表
+// 是否是还没有发布的预约红包
+func (domain *goodsDomain) IsScheduled() bool {
+	return domain.Status == services.OrderCreate && domain.PayStatus == services.PayNothing
+}
+
+// 保存到红包商品表 记录 ctx 中的请求ID
 func (domain *goodsDomain) Save(ctx context.Context) (id int64, err error) {
-	err = base.ExecuteContext(ctx, func(runner *dbx.TxRunner) error {
-		dao := RedEnvelopeGoodsDao{runner: runner}
-		id, err = dao.Insert(&domain.RedEnvelopeGoods)
+	domain.RedEnvelopeGoods.RequestId = base.RequestId(ctx)
+	err = base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		id, err = NewGoodsRepository(tx).Insert(&domain.RedEnvelopeGoods)
 		return err
 	})
 	// 0 表示保存到数据库失败
@@ -70,9 +82,8 @@ func (domain *goodsDomain) CreateAndSave(ctx context.Context, dto services.RedEn
 
 // 查询红包商品信息
 func (domain *goodsDomain) Get(envelopeNo string) (goods *RedEnvelopeGoods) {
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		dao := &RedEnvelopeGoodsDao{runner: runner}
-		goods = dao.GetOne(envelopeNo)
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		goods = NewGoodsRepository(tx).GetOne(envelopeNo)
 		return nil
 	})
 	if err != nil {
@@ -81,3 +92,43 @@ func (domain *goodsDomain) Get(envelopeNo string) (goods *RedEnvelopeGoods) {
 	}
 	return goods
 }
+
+// 查询用户可领取的红包 只包含用户所在群的红包和不限群的红包
+func (domain *goodsDomain) ListReceivable(userId string, offset, size int) (goods []RedEnvelopeGoods) {
+	groupIds := services.GetGroupService().ListGroupIds(userId)
+	err := base.ReadTransact(context.Background(), func(tx base.Transaction) error {
+		goods = NewGoodsRepository(tx).ListReceivable(groupIds, offset, size)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	return goods
+}
+
+// 查询用户发出的红包 只读查询 在从库执行
+func (domain *goodsDomain) FindByUser(userId string, offset, size int) (goods []RedEnvelopeGoods) {
+	err := base.ReadTransact(context.Background(), func(tx base.Transaction) error {
+		goods = NewGoodsRepository(tx).FindByUser(userId, offset, size)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	return goods
+}
+
+// 查询用户收到的红包 只读查询 在从库执行
+func (domain *goodsDomain) ListReceived(userId string, offset, size int) (items []*RedEnvelopeItem) {
+	err := base.ReadTransact(context.Background(), func(tx base.Transaction) error {
+		items = NewItemRepository(tx).ListReceivedItems(userId, offset, size)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	return items
+}
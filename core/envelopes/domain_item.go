@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/segmentio/ksuid"
-	"github.com/tietang/dbx"
 
 	"github.com/solozyx/red-envelope/infra/base"
 	"github.com/solozyx/red-envelope/services"
@@ -27,11 +26,11 @@ func (domain *itemDomain) Create(dto services.RedEnvelopeItemDTO) {
 	domain.createItemNo()
 }
 
-// 保存 item
+// 保存 item 记录 ctx 中的请求ID
 func (domain *itemDomain) Save(ctx context.Context) (id int64, err error) {
-	err = base.ExecuteContext(ctx, func(runner *dbx.TxRunner) error {
-		dao := &RedEnvelopeItemDao{runner: runner}
-		id, err = dao.Insert(&domain.RedEnvelopeItem)
+	domain.RedEnvelopeItem.RequestId = base.RequestId(ctx)
+	err = base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		id, err = NewItemRepository(tx).Insert(&domain.RedEnvelopeItem)
 		return err
 	})
 	return id, err
@@ -39,9 +38,8 @@ func (domain *itemDomain) Save(ctx context.Context) (id int64, err error) {
 
 // 通过 itemNo 查询抢红包明细数据
 func (domain *itemDomain) GetOne(ctx context.Context, itemNo string) (dto *services.RedEnvelopeItemDTO) {
-	err := base.ExecuteContext(ctx, func(runner *dbx.TxRunner) error {
-		dao := &RedEnvelopeItemDao{runner: runner}
-		po := dao.GetOne(itemNo)
+	err := base.ExecuteTransaction(ctx, func(tx base.Transaction) error {
+		po := NewItemRepository(tx).GetOne(itemNo)
 		if po != nil {
 			dto = po.ToDTO()
 		}
@@ -56,9 +54,8 @@ func (domain *itemDomain) GetOne(ctx context.Context, itemNo string) (dto *servi
 // 通过 envelopeNo 查询已抢红包列表
 func (domain *itemDomain) FindItems(envelopeNo string) (itemDTOs []*services.RedEnvelopeItemDTO) {
 	var items []*RedEnvelopeItem
-	err := base.Tx(func(runner *dbx.TxRunner) error {
-		dao := &RedEnvelopeItemDao{runner: runner}
-		items = dao.FindItems(envelopeNo)
+	err := base.ReadTransact(context.Background(), func(tx base.Transaction) error {
+		items = NewItemRepository(tx).FindItems(envelopeNo)
 		return nil
 	})
 	if err != nil {
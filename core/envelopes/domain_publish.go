@@ -0,0 +1,115 @@
+package envelopes
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/core/accounts"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 预约红包发布
+type ScheduledEnvelopeDomain struct {
+	// 到达发布时间的预约红包列表
+	dueGoods []RedEnvelopeGoods
+}
+
+// 查询出到达发布时间的预约红包
+// 发布成功的红包状态会改变 不再出现在查询结果中 所以每次都从头查询
+func (e *ScheduledEnvelopeDomain) Next() (ok bool, err error) {
+	err = base.Transact(context.Background(), func(tx base.Transaction) error {
+		e.dueGoods = NewGoodsRepository(tx).FindDuePublish(pageSize)
+		ok = len(e.dueGoods) > 0
+		return nil
+	})
+	return ok, err
+}
+
+// 发布所有到期的预约红包 查询失败时返回错误 本次任务执行失败
+func (e *ScheduledEnvelopeDomain) Publish() (err error) {
+	for {
+		ok, nextErr := e.Next()
+		if nextErr != nil {
+			return nextErr
+		}
+		if !ok {
+			break
+		}
+		succeed := 0
+		for _, g := range e.dueGoods {
+			err = e.PublishOne(g)
+			if err != nil {
+				logrus.Error(err)
+				continue
+			}
+			succeed++
+		}
+		// 本批次全部失败 避免重复查询同一批数据 等待下次定时任务
+		if succeed == 0 {
+			break
+		}
+	}
+	return err
+}
+
+// 发布1个预约红包 冻结资金扣款转入系统红包账户 红包状态 创建 -> 发布
+func (e *ScheduledEnvelopeDomain) PublishOne(goods RedEnvelopeGoods) error {
+	systemAccount := base.GetSystemAccount()
+	target := services.TradeParticipator{
+		AccountNo: systemAccount.AccountNo,
+		UserId:    systemAccount.UserId,
+		Username:  systemAccount.Username,
+	}
+	published := false
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		rows, err := NewGoodsRepository(tx).UpdateStatusIf(goods.EnvelopeNo, services.OrderCreate, services.OrderSending,
+			services.PayNothing, services.Payed)
+		if err != nil {
+			return err
+		}
+		// 已经被其他节点发布或被取消
+		if rows <= 0 {
+			return nil
+		}
+		ctx := base.WithValueContext(context.Background(), tx)
+		if err := accounts.NewAccountDomain().CaptureHoldWithContextTx(ctx, goods.EnvelopeNo, target); err != nil {
+			return err
+		}
+		published = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 发布后加入过期延时队列
+	if published {
+		scheduleExpiry(&goods)
+	}
+	return nil
+}
+
+// 取消还没有发布的预约红包 解冻发红包人的资金
+func (domain *goodsDomain) CancelScheduled(ctx context.Context, dto services.RedEnvelopeCancelDTO) error {
+	return base.Transact(ctx, func(tx base.Transaction) error {
+		dao := NewGoodsRepository(tx)
+		goods := dao.GetOne(dto.EnvelopeNo)
+		if goods == nil {
+			return ErrEnvelopeNotFound.With("envelopeNo", dto.EnvelopeNo)
+		}
+		if goods.UserId != dto.UserId {
+			return ErrNotOwner
+		}
+		rows, err := dao.UpdateStatusIf(goods.EnvelopeNo, services.OrderCreate, services.OrderDisabled,
+			services.PayNothing, services.PayNothing)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrAlreadyPublished.With("envelopeNo", dto.EnvelopeNo)
+		}
+		txCtx := base.WithValueContext(ctx, tx)
+		return accounts.NewAccountDomain().ReleaseHoldWithContextTx(txCtx, goods.EnvelopeNo)
+	})
+}
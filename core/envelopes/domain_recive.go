@@ -3,15 +3,15 @@ package envelopes
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"time"
 
 	"github.com/shopspring/decimal"
-	"github.com/tietang/dbx"
 
 	"github.com/solozyx/red-envelope/core/accounts"
 	"github.com/solozyx/red-envelope/infra/algo"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/metrics"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -20,26 +20,60 @@ var multiple = decimal.NewFromFloat(100.0)
 
 // 收红包业务
 func (domain *goodsDomain) Receive(ctx context.Context, dto services.RedEnvelopeReceiveDTO) (item *services.RedEnvelopeItemDTO, err error) {
+	start := time.Now()
+	defer func() { metrics.Receive.ObserveErr(start, err) }()
 	// 1.创建收红包的订单明细
 	domain.preCreateItem(dto)
 	// 2.查询出当前红包的剩余数量和剩余金额信息
 	goods := domain.Get(dto.EnvelopeNo)
+	if goods == nil {
+		return nil, ErrEnvelopeNotFound.With("envelopeNo", dto.EnvelopeNo)
+	}
+	// 预约红包发布前和已取消的红包不能领取
+	if goods.Status == services.OrderCreate && goods.PayStatus == services.PayNothing {
+		return nil, ErrNotPublished.With("envelopeNo", dto.EnvelopeNo)
+	}
+	if goods.Status == services.OrderDisabled {
+		return nil, ErrCancelled.With("envelopeNo", dto.EnvelopeNo)
+	}
+	// 群红包只有群成员可以领取
+	if goods.GroupId != "" && !services.GetGroupService().IsMember(goods.GroupId, dto.RecvUserId) {
+		return nil, ErrNotGroupMember.With("userId", dto.RecvUserId)
+	}
+	// 口令红包校验口令 连续输错口令的用户会被暂时禁止尝试
+	if goods.EnvelopeType == int(services.PassphraseEnvelopeType) {
+		allowed, err := getLimiter().Attempt(dto.RecvUserId)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, ErrPassphraseLocked
+		}
+		if !matchPassphrase(goods.PassphraseHash, dto.Passphrase) {
+			return nil, ErrPassphraseWrong
+		}
+		getLimiter().Reset(dto.RecvUserId)
+	}
 	// 3.校验剩余红包数量和剩余金额 如果没有剩余 直接返回无可用红包金额
 	if goods.RemainQuantity <= 0 || goods.RemainAmount.Cmp(decimal.NewFromFloat(0)) <= 0 {
-		return nil, errors.New("收红包没有足够的剩余金额")
+		return nil, ErrSoldOut.With("envelopeNo", dto.EnvelopeNo)
 	}
 	// 4.使用红包算法计算红包金额
 	nextAmount := domain.nextAmount(goods)
 
-	err = base.Tx(func(runner *dbx.TxRunner) error {
+	// ctx 中已经有事务时加入该事务
+	err = base.Transact(ctx, func(tx base.Transaction) error {
 		// 5.使用乐观锁更新语句 尝试更新剩余数量和剩余金额
 		// - 更新成功 返回1 抢到红包
 		// - 更新失败 返回0 无剩余红包金额或数量 抢红包失败
-		dao := &RedEnvelopeGoodsDao{runner: runner}
-		rows, err := dao.UpdateBalance(goods.EnvelopeNo, nextAmount)
+		rows, err := NewGoodsRepository(tx).UpdateBalance(goods.EnvelopeNo, nextAmount)
 		// 如果更新失败 row affected 返回0 表示无可用红包数量与金额
-		if rows <= 0 || err != nil {
-			return errors.New("收红包更新数据库失败 导致收红包失败")
+		if err != nil {
+			return ErrUpdateFailed.With("envelopeNo", goods.EnvelopeNo).Wrap(err)
+		}
+		if rows <= 0 {
+			metrics.ReceiveConflicts.Inc()
+			return ErrSoldOut.With("envelopeNo", goods.EnvelopeNo)
 		}
 		// 如果更新成功 row affected 返回1 表示抢到红包
 		// 6.保存订单明细数据
@@ -50,8 +84,8 @@ func (domain *goodsDomain) Receive(ctx context.Context, dto services.RedEnvelope
 		domain.itemDomain.RedEnvelopeItem.RemainAmount = goods.RemainAmount.Sub(nextAmount)
 		// 本次抢到的红包金额
 		domain.itemDomain.RedEnvelopeItem.Amount = nextAmount
-		// 构造新的上下文 传入 *TxRunner 数据库事务对象
-		txCtx := base.WithValueContext(ctx, runner)
+		// 构造新的上下文 传入数据库事务对象
+		txCtx := base.WithValueContext(ctx, tx)
 		// 写入 red_envelope_item 表
 		_, err = domain.itemDomain.Save(txCtx)
 		if err != nil {
@@ -81,9 +115,12 @@ func (domain *goodsDomain) preCreateItem(dto services.RedEnvelopeReceiveDTO) {
 
 	envelopeGoods := domain.Get(dto.EnvelopeNo)
 	var s string
-	if envelopeGoods.EnvelopeType == int(services.GeneralEnvelopeType) {
+	switch envelopeGoods.EnvelopeType {
+	case int(services.GeneralEnvelopeType):
 		s = "普通"
-	} else {
+	case int(services.PassphraseEnvelopeType):
+		s = "口令"
+	default:
 		s = "碰运气"
 	}
 	domain.itemDomain.RedEnvelopeItem.Desc = fmt.Sprintf("%s的%s红包",
@@ -100,7 +137,8 @@ func (domain *goodsDomain) nextAmount(goods *RedEnvelopeGoods) (amount decimal.D
 	if goods.EnvelopeType == int(services.GeneralEnvelopeType) {
 		return goods.AmountOne
 	}
-	if goods.EnvelopeType == int(services.LuckyEnvelopeType) {
+	if goods.EnvelopeType == int(services.LuckyEnvelopeType) ||
+		goods.EnvelopeType == int(services.PassphraseEnvelopeType) {
 		// 剩余金额 元 -> 分 *100 取出int值
 		centInt := goods.RemainAmount.Mul(multiple).IntPart()
 		next := algo.DoubleAverage(int64(goods.RemainQuantity), centInt)
@@ -125,9 +163,13 @@ func (domain *goodsDomain) transfer(ctx context.Context, dto services.RedEnvelop
 		Username:  dto.RecvUsername,
 	}
 	if target.AccountNo == "" {
+		// 在收红包的事务中查询 不能开启新的事务
 		a := accounts.
 			NewAccountDomain().
-			GetAccountByUserIdAndType(target.UserId, services.EnvelopeAccountType)
+			GetAccountByUserIdAndType(ctx, target.UserId, services.EnvelopeAccountType)
+		if a == nil {
+			return services.TransferredStatusFailure, accounts.ErrAccountNotFound.With("userId", target.UserId)
+		}
 		target.AccountNo = a.AccountNo
 	}
 	transferDTO := services.AccountTransferDTO{
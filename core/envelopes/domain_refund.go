@@ -2,36 +2,60 @@ package envelopes
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tietang/dbx"
 
 	"github.com/solozyx/red-envelope/infra/base"
-	"github.com/solozyx/red-envelope/services"
+	"github.com/solozyx/red-envelope/infra/metrics"
 )
 
 const (
 	// 分页 每页大小
 	pageSize = 100
+	// 默认并发退款的 worker 数量
+	defaultRefundConcurrency = 4
+	// 默认最大退款尝试次数
+	defaultRefundMaxAttempts = 5
+	// 默认认领超时时间 超时后认为认领节点已经宕机 其他节点可以重新认领
+	defaultRefundClaimTimeout = 5 * time.Minute
 )
 
 type ExpiredEnvelopeDomain struct {
+	// 并发退款的 worker 数量
+	Concurrency int
+	// 单个红包最大退款尝试次数 超过后不再重试 需要人工处理
+	MaxAttempts int
+	// 认领超时时间
+	ClaimTimeout time.Duration
 	// 过期待退款红包列表
 	expiredGoods []RedEnvelopeGoods
-	// 分页 偏移量 从 offset 行开始 查询 pageSize 个数据
-	offset int
+	// 键集分页 上一页最后1个红包的id
+	lastId int64
 }
 
-// 查询出过期红包
+func (e *ExpiredEnvelopeDomain) setDefaults() {
+	if e.Concurrency <= 0 {
+		e.Concurrency = defaultRefundConcurrency
+	}
+	if e.MaxAttempts <= 0 {
+		e.MaxAttempts = defaultRefundMaxAttempts
+	}
+	if e.ClaimTimeout <= 0 {
+		e.ClaimTimeout = defaultRefundClaimTimeout
+	}
+}
+
+// 查询出过期红包 按 id 键集分页
 func (e *ExpiredEnvelopeDomain) Next() (ok bool) {
-	base.Tx(func(runner *dbx.TxRunner) error {
-		dao := &RedEnvelopeGoodsDao{runner: runner}
-		e.expiredGoods = dao.FindExpired(e.offset, pageSize)
+	base.Transact(context.Background(), func(tx base.Transaction) error {
+		e.expiredGoods = NewGoodsRepository(tx).FindExpired(e.lastId, e.MaxAttempts, pageSize)
 		logrus.Infof("查询到 %d 个可退款红包", len(e.expiredGoods))
 		if len(e.expiredGoods) > 0 {
-			e.offset += len(e.expiredGoods)
+			e.lastId = e.expiredGoods[len(e.expiredGoods)-1].Id
 			ok = true
 		}
 		return nil
@@ -39,106 +63,136 @@ func (e *ExpiredEnvelopeDomain) Next() (ok bool) {
 	return ok
 }
 
-func (e *ExpiredEnvelopeDomain) Expired() (err error) {
-	for e.Next() {
+// 扫描所有过期红包 交给有限数量的 worker 并发退款
+// ctx 取消后不再分发新的红包 等待 worker 完成正在退款的红包后返回 剩余的红包由下次任务继续处理
+func (e *ExpiredEnvelopeDomain) Expired(ctx context.Context) error {
+	e.setDefaults()
+	e.lastId = 0
+	goodsCh := make(chan RedEnvelopeGoods)
+	var failed int32
+	var wg sync.WaitGroup
+	for i := 0; i < e.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range goodsCh {
+				if err := e.refund(g); err != nil {
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+feed:
+	for ctx.Err() == nil && e.Next() {
 		for _, g := range e.expiredGoods {
-			logrus.Debugf("过期红包退款开始: %+v", g)
-			err = e.ExpiredOne(g)
-			if err != nil {
-				logrus.Error(err)
+			select {
+			case goodsCh <- g:
+			case <-ctx.Done():
+				break feed
 			}
-			logrus.Debugf("过期红包退款结束: %+v", g)
 		}
 	}
-	return err
+	close(goodsCh)
+	wg.Wait()
+	if ctx.Err() != nil {
+		logrus.Info("过期红包退款被中断 剩余红包下次继续处理")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个过期红包退款失败", failed)
+	}
+	return nil
 }
 
-// 针对1个红包 发起1个退款流程
-func (e *ExpiredEnvelopeDomain) ExpiredOne(goods RedEnvelopeGoods) (err error) {
-	// 创建1个退款订单
-	refund := goods
-	refund.OrderType = services.OrderTypeRefund
-	refund.Status = services.OrderExpired
-	refund.PayStatus = services.Refunding
-	refund.OriginEnvelopeNo = goods.EnvelopeNo
-	refund.EnvelopeNo = ""
-	// 过期时间 默认24hour
-	refund.ExpiredAt = time.Now().Add(24 * time.Hour)
-	domain := goodsDomain{RedEnvelopeGoods: refund}
-	// 退款订单 红包商品生成新的红包编号 和 原过期红包编号 区分开
-	domain.createEnvelopeNo()
+// 认领过期红包后退款 记录退款结果
+func (e *ExpiredEnvelopeDomain) refund(goods RedEnvelopeGoods) error {
+	var claimed bool
+	err := base.Transact(context.Background(), func(tx base.Transaction) (err error) {
+		claimed, err = NewRefundAttemptRepository(tx).Claim(goods.EnvelopeNo, e.MaxAttempts, e.ClaimTimeout)
+		return err
+	})
+	if err != nil {
+		logrus.Error("认领过期红包失败: ", goods.EnvelopeNo, err)
+		return err
+	}
+	if !claimed {
+		// 已经被其他 worker 或节点认领 或者已经达到最大尝试次数
+		logrus.Debug("过期红包已被认领: ", goods.EnvelopeNo)
+		return nil
+	}
 
-	err = base.Tx(func(runner *dbx.TxRunner) error {
-		txCtx := base.WithValueContext(context.Background(), runner)
-		// 退款订单红包商品 写入 red_envelope_goods 表
-		id, err := domain.Save(txCtx)
-		if err != nil || id <= 0 {
-			return errors.New("创建退款订单失败")
+	logrus.Debugf("过期红包退款开始: %+v", goods)
+	refundErr := e.ExpiredOne(goods)
+	err = base.Transact(context.Background(), func(tx base.Transaction) error {
+		repo := NewRefundAttemptRepository(tx)
+		if refundErr == nil {
+			_, err := repo.Succeed(goods.EnvelopeNo)
+			return err
 		}
-
-		// 修改原过期红包订单状态
-		dao := RedEnvelopeGoodsDao{runner: runner}
-		_, err = dao.UpdateOrderStatus(goods.EnvelopeNo, services.OrderExpired)
+		_, err := repo.Fail(goods.EnvelopeNo, refundErr.Error())
 		if err != nil {
-			return errors.New("更新原过期红包订单为过期状态失败" + err.Error())
+			return err
+		}
+		attempt := repo.GetOne(goods.EnvelopeNo)
+		if attempt != nil && attempt.Attempts >= e.MaxAttempts {
+			logrus.Errorf("过期红包退款已尝试%d次 不再重试 需要人工处理: %s", attempt.Attempts, goods.EnvelopeNo)
 		}
 		return nil
 	})
 	if err != nil {
-		return err
-	}
-
-	// 调用资金账户接口退款转账 系统红包账户 --> 原过期红包发送者账户
-	systemAccount := base.GetSystemAccount()
-	account := services.GetAccountService().GetEnvelopeAccountByUserId(goods.UserId)
-	if account == nil {
-		return errors.New("没有找到该用户的红包资金账户:" + goods.UserId)
+		logrus.Error("记录过期红包退款结果失败: ", goods.EnvelopeNo, err)
 	}
-	body := services.TradeParticipator{
-		AccountNo: systemAccount.AccountNo,
-		UserId:    systemAccount.UserId,
-		Username:  systemAccount.Username,
-	}
-	target := services.TradeParticipator{
-		AccountNo: account.AccountNo,
-		UserId:    account.UserId,
-		Username:  account.Username,
+	if refundErr != nil {
+		logrus.Error(refundErr)
+		return refundErr
 	}
+	logrus.Debugf("过期红包退款结束: %+v", goods)
+	return nil
+}
 
-	// 系统账户扣减资金 转入原发红包账户
-	transfer := services.AccountTransferDTO{
-		TradeNo:     domain.RedEnvelopeGoods.EnvelopeNo,
-		TradeBody:   body,
-		TradeTarget: target,
-		Amount:      goods.RemainAmount, // 剩余金额转给红包发送人
-		AmountStr:   goods.RemainAmount.String(),
-		ChangeType:  services.SysEnvelopeExpiredRefund,
-		ChangeFlag:  services.FlagTransferOut,
-		Desc:        "过期红包退款,系统账户扣减资金,转给原红包发送人账户,红包编号: " + goods.EnvelopeNo,
-	}
-	status, err := services.GetAccountService().Transfer(transfer)
-	if status != services.TransferredStatusSuccess {
+// 针对1个红包 发起1个退款流程 上次没有完成的退款流程继续执行
+func (e *ExpiredEnvelopeDomain) ExpiredOne(goods RedEnvelopeGoods) (err error) {
+	start := time.Now()
+	defer func() { metrics.Refund.ObserveErr(start, err) }()
+	saga, err := startRefundSaga(goods)
+	if err != nil {
 		return err
 	}
+	return saga.Run()
+}
 
-	err = base.Tx(func(runner *dbx.TxRunner) error {
-		dao := RedEnvelopeGoodsDao{runner: runner}
-		// 修改原过期红包订单状态
-		rows, err := dao.UpdateOrderStatus(goods.EnvelopeNo, services.OrderExpiredRefundSucceed)
-		if err != nil || rows == 0 {
-			return errors.New("更新原过期红包订单状态为退款成功状态失败")
+// 恢复没有完成的退款流程 服务启动和每次定时任务执行时调用
+// 只处理 recoverAfter 时间内没有更新过的退款订单 避免和正在执行的退款流程争抢
+// ctx 取消后不再恢复新的退款流程
+func (e *ExpiredEnvelopeDomain) Recover(ctx context.Context, recoverAfter time.Duration) error {
+	var lastId int64
+	var failed int
+	for ctx.Err() == nil {
+		var refunds []RedEnvelopeGoods
+		err := base.Transact(context.Background(), func(tx base.Transaction) error {
+			refunds = NewGoodsRepository(tx).FindUnfinishedRefunds(lastId, time.Now().Add(-recoverAfter), pageSize)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-		// 修改退款订单状态
-		_, err = dao.UpdateOrderStatus(refund.EnvelopeNo, services.OrderExpiredRefundSucceed)
-		if err != nil || rows == 0 {
-			return errors.New("更退款订单状态为退款成功状态失败")
+		if len(refunds) == 0 {
+			break
 		}
-		return nil
-	})
-
-	if err != nil {
-		logrus.Error(err)
-		return err
+		logrus.Infof("查询到 %d 个没有完成的退款订单", len(refunds))
+		for _, refund := range refunds {
+			if ctx.Err() != nil {
+				break
+			}
+			saga := &refundSaga{refund: refund}
+			if err := saga.Run(); err != nil {
+				logrus.Error("恢复退款流程失败: ", refund.EnvelopeNo, err)
+				failed++
+			}
+		}
+		lastId = refunds[len(refunds)-1].Id
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个退款流程恢复失败", failed)
 	}
 	return nil
 }
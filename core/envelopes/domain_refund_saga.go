@@ -0,0 +1,206 @@
+package envelopes
+
+import (
+	"context"
+	"time"
+
+	"github.com/solozyx/red-envelope/core/accounts"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// TODO:NOTICE 过期红包退款流程 [Saga]
+// 退款订单就是退款流程的持久化状态 退款订单编号同时是退款转账的交易单号 每个步骤都在事务中追加1条步骤记录
+//  1.创建退款订单 支付状态为退款中 原红包状态更新为过期
+//  2.退款转账 和退款订单 退款中 -> 已退款 在同1个事务中执行
+//  3.退款完成 退款订单和原红包状态更新为退款成功
+//  退款转账确定不能成功时(账户不存在 余额不足)补偿: 退款订单 退款中 -> 退款失败 原红包更新为退款失败 由过期红包扫描重新发起退款
+// 每个步骤都以退款订单的支付状态为条件更新 重复执行是幂等的
+// 任何1个步骤之后宕机 都可以根据退款订单的支付状态 退款中 已退款 继续执行
+type refundSaga struct {
+	refund RedEnvelopeGoods
+}
+
+// 创建退款订单 原红包已经有没有完成的退款订单时继续使用该退款订单
+func startRefundSaga(goods RedEnvelopeGoods) (*refundSaga, error) {
+	saga := &refundSaga{}
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		repo := NewGoodsRepository(tx)
+		if refund := repo.GetRefundingByOrigin(goods.EnvelopeNo); refund != nil {
+			saga.refund = *refund
+			return nil
+		}
+
+		refund := goods
+		refund.Id = 0
+		refund.OrderType = services.OrderTypeRefund
+		refund.Status = services.OrderExpired
+		refund.PayStatus = services.Refunding
+		refund.OriginEnvelopeNo = goods.EnvelopeNo
+		// 过期时间 默认24hour
+		refund.ExpiredAt = time.Now().Add(24 * time.Hour)
+		domain := goodsDomain{RedEnvelopeGoods: refund}
+		// 退款订单 红包商品生成新的红包编号 和 原过期红包编号 区分开
+		domain.createEnvelopeNo()
+
+		txCtx := base.WithValueContext(context.Background(), tx)
+		id, err := domain.Save(txCtx)
+		if err != nil || id <= 0 {
+			return ErrCreateRefundFailed.Wrap(err)
+		}
+		_, err = repo.UpdateOrderStatus(goods.EnvelopeNo, services.OrderExpired)
+		if err != nil {
+			return ErrUpdateFailed.With("envelopeNo", goods.EnvelopeNo).Wrap(err)
+		}
+		saga.refund = domain.RedEnvelopeGoods
+		return saga.writeStep(tx, services.RefundStepCreated,
+			"创建退款订单,退款金额: "+goods.RemainAmount.String())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saga, nil
+}
+
+// 从退款订单当前的状态继续执行退款流程
+func (s *refundSaga) Run() error {
+	if s.refund.PayStatus == services.Refunding {
+		if err := s.transfer(); err != nil {
+			return err
+		}
+	}
+	if s.refund.PayStatus != services.Refunded {
+		return ErrRefundStateChanged.With("envelopeNo", s.refund.EnvelopeNo)
+	}
+	return s.complete()
+}
+
+// 退款转账 系统红包账户 --> 原过期红包发送者账户
+func (s *refundSaga) transfer() error {
+	refund := &s.refund
+	// 早期的退款转账和退款订单状态更新不在同1个事务中 通过交易单号确认转账是否已经执行
+	transferred := accounts.NewAccountDomain().GetAccountLogByTradeNo(refund.EnvelopeNo) != nil
+	var account *services.AccountDTO
+	if !transferred {
+		account = services.GetAccountService().GetEnvelopeAccountByUserId(refund.UserId)
+		if account == nil {
+			return s.compensate("没有找到该用户的红包资金账户:" + refund.UserId)
+		}
+	}
+
+	var status services.TransferredStatus
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		repo := NewGoodsRepository(tx)
+		rows, err := repo.UpdatePayStatusIf(refund.EnvelopeNo, services.Refunding, services.Refunded, services.OrderExpired)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrRefundStateChanged.With("envelopeNo", refund.EnvelopeNo)
+		}
+		if transferred {
+			return s.writeStep(tx, services.RefundStepTransferred, "退款转账已经执行")
+		}
+
+		systemAccount := base.GetSystemAccount()
+		transfer := services.AccountTransferDTO{
+			TradeNo: refund.EnvelopeNo,
+			TradeBody: services.TradeParticipator{
+				AccountNo: systemAccount.AccountNo,
+				UserId:    systemAccount.UserId,
+				Username:  systemAccount.Username,
+			},
+			TradeTarget: services.TradeParticipator{
+				AccountNo: account.AccountNo,
+				UserId:    account.UserId,
+				Username:  account.Username,
+			},
+			// 剩余金额转给红包发送人
+			Amount:     refund.RemainAmount,
+			AmountStr:  refund.RemainAmount.String(),
+			ChangeType: services.SysEnvelopeExpiredRefund,
+			ChangeFlag: services.FlagTransferOut,
+			Desc:       "过期红包退款,系统账户扣减资金,转给原红包发送人账户,红包编号: " + refund.OriginEnvelopeNo,
+		}
+		txCtx := base.WithValueContext(context.Background(), tx)
+		status, err = accounts.NewAccountDomain().TransferWithContextTx(txCtx, transfer)
+		if status != services.TransferredStatusSuccess {
+			return err
+		}
+		return s.writeStep(tx, services.RefundStepTransferred, "退款转账成功,转入账户: "+account.AccountNo)
+	})
+	if err != nil {
+		if status == services.TransferredStatusSufficientFunds {
+			return s.compensate("系统红包账户余额不足")
+		}
+		// 其他错误 退款订单保持退款中 由恢复流程重试
+		return err
+	}
+	refund.PayStatus = services.Refunded
+	return nil
+}
+
+// 退款完成
+func (s *refundSaga) complete() error {
+	refund := &s.refund
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		repo := NewGoodsRepository(tx)
+		rows, err := repo.UpdateStatusIf(refund.EnvelopeNo, services.OrderExpired,
+			services.OrderExpiredRefundSucceed, services.Refunded, services.Refunded)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			// 已经被其他节点的恢复流程完成
+			return nil
+		}
+		rows, err = repo.UpdateOrderStatus(refund.OriginEnvelopeNo, services.OrderExpiredRefundSucceed)
+		if err != nil || rows <= 0 {
+			return ErrUpdateFailed.With("envelopeNo", refund.OriginEnvelopeNo).Wrap(err)
+		}
+		return s.writeStep(tx, services.RefundStepCompleted, "退款完成")
+	})
+	if err != nil {
+		return err
+	}
+	refund.Status = services.OrderExpiredRefundSucceed
+	return nil
+}
+
+// 退款转账不能成功 补偿: 关闭退款订单 原红包更新为退款失败状态
+func (s *refundSaga) compensate(reason string) error {
+	refund := &s.refund
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		repo := NewGoodsRepository(tx)
+		rows, err := repo.UpdatePayStatusIf(refund.EnvelopeNo, services.Refunding,
+			services.RefundFailed, services.OrderExpiredRefundFiled)
+		if err != nil {
+			return err
+		}
+		if rows <= 0 {
+			return ErrRefundStateChanged.With("envelopeNo", refund.EnvelopeNo)
+		}
+		_, err = repo.UpdateOrderStatus(refund.OriginEnvelopeNo, services.OrderExpiredRefundFiled)
+		if err != nil {
+			return err
+		}
+		return s.writeStep(tx, services.RefundStepCompensated, reason)
+	})
+	if err != nil {
+		return err
+	}
+	refund.PayStatus = services.RefundFailed
+	refund.Status = services.OrderExpiredRefundFiled
+	return ErrRefundFailed.With("reason", reason).With("envelopeNo", refund.OriginEnvelopeNo)
+}
+
+// 在当前事务中记录退款步骤
+func (s *refundSaga) writeStep(tx base.Transaction, step services.RefundStep, desc string) error {
+	_, err := NewRefundStepRepository(tx).Insert(&RefundStepLog{
+		RefundNo:   s.refund.EnvelopeNo,
+		EnvelopeNo: s.refund.OriginEnvelopeNo,
+		Step:       step,
+		Desc:       desc,
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package envelopes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+	_ "github.com/solozyx/red-envelope/textx"
+)
+
+// memory 后端的过期退款
+func TestExpiredEnvelopeDomain_MemoryBackend(t *testing.T) {
+	restore := base.UseMemoryBackend()
+	defer restore()
+
+	as := services.GetAccountService()
+	rs := services.GetRedEnvelopeService()
+
+	Convey("memory 后端过期红包退款", t, func() {
+		account, err := as.CreateAccount(context.Background(), services.AccountCreatedDTO{
+			UserId:       ksuid.New().Next().String(),
+			Username:     "测试用户",
+			AccountName:  "测试账户",
+			AccountType:  int(services.EnvelopeAccountType),
+			Amount:       "100",
+			CurrencyCode: "CNY",
+		})
+		So(err, ShouldBeNil)
+		activity, err := rs.SendOut(context.Background(), services.RedEnvelopeSendingDTO{
+			UserId:       account.UserId,
+			Username:     account.Username,
+			EnvelopeType: int(services.GeneralEnvelopeType),
+			Amount:       "10",
+			Quantity:     2,
+		})
+		So(err, ShouldBeNil)
+		So(as.GetAccount(account.AccountNo).Balance.String(), ShouldEqual, "80")
+
+		// 把红包的过期时间改到1分钟之前
+		err = base.MemDatabase().Tx(func(tx *memdb.Tx) error {
+			repo := &memGoodsRepository{tx: tx}
+			goods := repo.GetOne(activity.EnvelopeNo)
+			goods.ExpiredAt = time.Now().Add(-time.Minute)
+			tx.Put(tableGoods, goods.Id, *goods)
+			return nil
+		})
+		So(err, ShouldBeNil)
+
+		domain := &ExpiredEnvelopeDomain{}
+		So(domain.Expired(context.Background()), ShouldBeNil)
+		So(as.GetAccount(account.AccountNo).Balance.String(), ShouldEqual, "100")
+		goods := new(goodsDomain).Get(activity.EnvelopeNo)
+		So(goods.Status, ShouldEqual, services.OrderExpiredRefundSucceed)
+
+		// 已退款的红包不会再次退款
+		So(domain.Expired(context.Background()), ShouldBeNil)
+		So(as.GetAccount(account.AccountNo).Balance.String(), ShouldEqual, "100")
+	})
+}
@@ -3,11 +3,11 @@ package envelopes
 import (
 	"context"
 	"path"
-
-	"github.com/tietang/dbx"
+	"time"
 
 	"github.com/solozyx/red-envelope/core/accounts"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/metrics"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -18,9 +18,18 @@ import (
 // 1.需要红包中间商的红包资金账户 定义在配置文件中 事先初始化到资金账户表中
 // 2.从红包发送人的资金账户中扣减红包金额
 // 3.将扣减的红包总金额转入红包中间商的红包资金账户
-func (domain *goodsDomain) SendOut(dto services.RedEnvelopeGoodsDTO) (activity *services.RedEnvelopeActivity, err error) {
+func (domain *goodsDomain) SendOut(ctx context.Context, dto services.RedEnvelopeGoodsDTO) (activity *services.RedEnvelopeActivity, err error) {
+	start := time.Now()
+	defer func() { metrics.SendOut.ObserveErr(start, err) }()
 	// 创建红包商品
 	domain.Create(dto)
+	// 口令红包 口令只保存哈希值
+	if dto.EnvelopeType == int(services.PassphraseEnvelopeType) {
+		domain.PassphraseHash, err = hashPassphrase(dto.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
 	// 创建红包活动
 	activity = new(services.RedEnvelopeActivity)
 	// 红包链接 格式 http://域名/v1/envelope/{id}/link/
@@ -30,9 +39,9 @@ func (domain *goodsDomain) SendOut(dto services.RedEnvelopeGoodsDTO) (activity *
 
 	accountDomain := accounts.NewAccountDomain()
 
-	err = base.Tx(func(runner *dbx.TxRunner) error {
-		// 创建 Context 上下文对象绑定 *dbx.TxRunner 数据库事务对象
-		ctx := base.WithValueContext(context.Background(), runner)
+	err = base.Transact(ctx, func(tx base.Transaction) error {
+		// 创建 Context 上下文对象绑定数据库事务对象 保留 ctx 中的请求ID
+		ctx := base.WithValueContext(ctx, tx)
 		// 1.保存红包商品
 		id, err := domain.Save(ctx)
 		if id <= 0 || err != nil {
@@ -45,6 +54,16 @@ func (domain *goodsDomain) SendOut(dto services.RedEnvelopeGoodsDTO) (activity *
 			UserId:    dto.UserId,
 			Username:  dto.Username,
 		}
+		// 预约红包 只冻结发红包人的资金 到发布时间再扣款
+		if domain.IsScheduled() {
+			hold := services.AccountHoldDTO{
+				TradeNo:   domain.RedEnvelopeGoods.EnvelopeNo,
+				TradeBody: body,
+				Amount:    domain.RedEnvelopeGoods.Amount,
+				Desc:      "预约红包资金冻结",
+			}
+			return accountDomain.HoldWithContextTx(ctx, hold)
+		}
 		// 交易对方 系统红包账户
 		systemAccount := base.GetSystemAccount()
 		target := services.TradeParticipator{
@@ -74,6 +93,10 @@ func (domain *goodsDomain) SendOut(dto services.RedEnvelopeGoodsDTO) (activity *
 	if err != nil {
 		return nil, err
 	}
+	// 立即发布的红包加入过期延时队列 预约红包在发布时加入
+	if !domain.IsScheduled() {
+		scheduleExpiry(&domain.RedEnvelopeGoods)
+	}
 	// 扣减金额没有问题 返回红包活动
 	activity.RedEnvelopeGoodsDTO = *domain.RedEnvelopeGoods.ToDTO()
 
@@ -1,6 +1,7 @@
 package envelopes
 
 import (
+	"context"
 	"path"
 	"testing"
 
@@ -28,7 +29,7 @@ func TestGoodsDomain_SendOut(t *testing.T) {
 
 		Convey("发送红包", t, func() {
 			// 创建账户
-			rdto, err := adomain.Create(aDto)
+			rdto, err := adomain.Create(context.Background(), aDto)
 			So(rdto, ShouldNotBeNil)
 			So(err, ShouldBeNil)
 			dto := &services.RedEnvelopeGoodsDTO{
@@ -42,7 +43,7 @@ func TestGoodsDomain_SendOut(t *testing.T) {
 				AccountNo:    adomain.GetAccountNo(),
 			}
 			// 从该账户转账
-			a, err := domain.SendOut(dto)
+			a, err := domain.SendOut(context.Background(), dto)
 			So(a, ShouldNotBeNil)
 			So(err, ShouldBeNil)
 
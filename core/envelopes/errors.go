@@ -0,0 +1,37 @@
+package envelopes
+
+import (
+	"github.com/solozyx/red-envelope/infra/errs"
+)
+
+// 红包的业务错误 消息key 以 envelope. 开头
+// 账户相关的错误使用 accounts 包的错误 比如 accounts.ErrAccountNotFound
+var (
+	ErrEnvelopeNotFound = errs.New(errs.NotFound, "envelope.not_found", "红包不存在")
+	ErrNotPublished     = errs.New(errs.FailedPrecondition, "envelope.not_published", "红包尚未生效")
+	ErrCancelled        = errs.New(errs.FailedPrecondition, "envelope.cancelled", "红包已取消")
+	ErrSoldOut          = errs.New(errs.FailedPrecondition, "envelope.sold_out", "红包已经被领完")
+	ErrNotCancellable   = errs.New(errs.FailedPrecondition, "envelope.not_cancellable", "红包已经被领取或已失效,不能取消")
+	ErrAlreadyPublished = errs.New(errs.FailedPrecondition, "envelope.already_published", "红包已经发布或已取消")
+	ErrNotOwner         = errs.New(errs.PermissionDenied, "envelope.not_owner", "只有发红包的用户可以取消红包")
+	ErrPublishTooLate   = errs.New(errs.InvalidArgument, "envelope.publish_too_late", "预约发布时间超出允许范围")
+
+	// 群红包
+	ErrGroupUnavailable       = errs.New(errs.FailedPrecondition, "envelope.group_unavailable", "群不存在或已解散")
+	ErrNotGroupMember         = errs.New(errs.PermissionDenied, "envelope.not_group_member", "用户不在该群中")
+	ErrQuantityExceedsMembers = errs.New(errs.InvalidArgument, "envelope.quantity_exceeds_members", "红包数量超过群成员数量")
+
+	// 口令红包
+	ErrPassphraseRequired = errs.New(errs.InvalidArgument, "envelope.passphrase_required", "口令红包的口令不能为空")
+	ErrPassphraseTooLong  = errs.New(errs.InvalidArgument, "envelope.passphrase_too_long", "口令长度不能超过32个字符")
+	ErrPassphraseWrong    = errs.New(errs.PermissionDenied, "envelope.passphrase_wrong", "口令错误")
+	ErrPassphraseLocked   = errs.New(errs.TooManyRequests, "envelope.passphrase_locked", "口令错误次数过多,请稍后再试")
+
+	// 过期退款
+	ErrRefundStateChanged = errs.New(errs.FailedPrecondition, "envelope.refund_state_changed", "退款订单状态已改变")
+	ErrRefundFailed       = errs.New(errs.Internal, "envelope.refund_failed", "过期红包退款失败")
+
+	// 以下是数据库写入没有生效等内部错误 客户端只会看到通用描述
+	ErrCreateRefundFailed = errs.New(errs.Internal, "envelope.create_refund_failed", "创建退款订单失败")
+	ErrUpdateFailed       = errs.New(errs.Internal, "envelope.update_failed", "红包数据更新失败")
+)
@@ -0,0 +1,94 @@
+package envelopes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/delayqueue"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 红包过期延时队列 红包发布时按过期时间加入 到期后立即退款
+// 没有设置时只由定时任务扫描退款
+var expiryQueue delayqueue.Queue
+
+func UseExpiryQueue(q delayqueue.Queue) {
+	expiryQueue = q
+}
+
+// 已发布的红包加入过期延时队列
+func scheduleExpiry(goods *RedEnvelopeGoods) {
+	if expiryQueue == nil {
+		return
+	}
+	if err := expiryQueue.Add(goods.EnvelopeNo, goods.ExpiredAt); err != nil {
+		// 加入失败由定时任务兜底扫描
+		logrus.Error("红包加入过期延时队列失败: ", goods.EnvelopeNo, err)
+	}
+}
+
+// 已取消的红包移出过期延时队列
+func cancelExpiry(envelopeNo string) {
+	if expiryQueue == nil {
+		return
+	}
+	if err := expiryQueue.Remove(envelopeNo); err != nil {
+		logrus.Error("红包移出过期延时队列失败: ", envelopeNo, err)
+	}
+}
+
+// 重启后把还没有退款的红包重新加入过期延时队列 返回加入的数量
+func LoadExpiryQueue() (int, error) {
+	if expiryQueue == nil {
+		return 0, errors.New("过期延时队列还没有设置")
+	}
+	var lastId int64
+	count := 0
+	for {
+		var goodsList []RedEnvelopeGoods
+		err := base.Transact(context.Background(), func(tx base.Transaction) error {
+			goodsList = NewGoodsRepository(tx).FindPendingExpiry(lastId, pageSize)
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+		for i := range goodsList {
+			scheduleExpiry(&goodsList[i])
+		}
+		count += len(goodsList)
+		if len(goodsList) < pageSize {
+			return count, nil
+		}
+		lastId = goodsList[len(goodsList)-1].Id
+	}
+}
+
+// 延时队列到期 对单个红包发起退款
+// 红包在入队之后可能已经被领完 被取消 或者已经被定时任务退款 需要重新检查
+func (e *ExpiredEnvelopeDomain) ExpireByEnvelopeNo(envelopeNo string) error {
+	e.setDefaults()
+	goods := new(goodsDomain).Get(envelopeNo)
+	if goods == nil {
+		return ErrEnvelopeNotFound.With("envelopeNo", envelopeNo)
+	}
+	if !goods.isRefundable(time.Now()) {
+		logrus.Debug("红包不需要退款: ", envelopeNo)
+		return nil
+	}
+	return e.refund(*goods)
+}
+
+// 和 FindExpired 的查询条件一致
+func (po *RedEnvelopeGoods) isRefundable(now time.Time) bool {
+	return po.OrderType == services.OrderTypeSending &&
+		po.RemainQuantity > 0 &&
+		po.ExpiredAt.Before(now) &&
+		po.Status != services.OrderDisabled &&
+		po.Status != services.OrderExpiredRefundSucceed &&
+		po.PayStatus != services.PayNothing
+}
@@ -0,0 +1,206 @@
+package envelopes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/width"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 口令最大长度 按字符计算
+const passphraseMaxLength = 32
+
+// 口令规范化 去掉所有空白字符 全角转半角 统一小写
+// 发红包时和收红包时使用同一规则 保证 "恭喜 发财" "恭喜发财" "ＡＢＣ" "abc" 可以匹配
+func normalizePassphrase(s string) string {
+	s = width.Fold.String(s)
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// bcrypt 只使用前72个字节 32个汉字的口令超过72个字节
+// 先计算 SHA-256 再交给 bcrypt 保证口令的每个字节都参与校验
+func prehashPassphrase(normalized string) []byte {
+	sum := sha256.Sum256([]byte(normalized))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// 口令加密存储 不保存明文
+func hashPassphrase(passphrase string) (string, error) {
+	p := normalizePassphrase(passphrase)
+	if p == "" {
+		return "", ErrPassphraseRequired
+	}
+	if utf8.RuneCountInString(p) > passphraseMaxLength {
+		return "", ErrPassphraseTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword(prehashPassphrase(p), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// 校验口令
+func matchPassphrase(hash, passphrase string) bool {
+	p := normalizePassphrase(passphrase)
+	if hash == "" || p == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), prehashPassphrase(p)) == nil
+}
+
+// 口令尝试次数限制 同一用户在时间窗口内尝试次数达到上限后 锁定一段时间 口令正确时清除计数
+// envelope.passphrase.store 选择计数的存储
+//  memory: 计数保存在进程内存中 多节点部署时每个节点单独计数 用户在 N 个节点上最多可以尝试 N 倍的次数
+//  redis: 计数保存在 redis 中 所有节点共享 集群部署时使用
+type passphraseLimiter interface {
+	// 尝试1次口令 返回是否允许本次尝试 校验口令之前调用
+	Attempt(userId string) (bool, error)
+	// 口令正确后清除计数
+	Reset(userId string)
+}
+
+var (
+	limiter     passphraseLimiter
+	limiterOnce sync.Once
+)
+
+// 第1次使用时根据配置创建
+func getLimiter() passphraseLimiter {
+	limiterOnce.Do(func() {
+		switch store := base.Props().GetDefault("envelope.passphrase.store", "memory"); store {
+		case "memory":
+			limiter = newMemPassphraseLimiter()
+		case "redis":
+			limiter = newRedisPassphraseLimiter(base.NewRedisPool(base.Props()))
+		default:
+			logrus.Panic("不支持的口令计数存储 envelope.passphrase.store=", store)
+		}
+	})
+	return limiter
+}
+
+func passphraseLimitSettings() (maxAttempts int, window, lockDuration time.Duration) {
+	maxAttempts = base.Props().GetIntDefault("envelope.passphrase.maxAttempts", 5)
+	window = base.Props().GetDurationDefault("envelope.passphrase.window", 10*time.Minute)
+	lockDuration = base.Props().GetDurationDefault("envelope.passphrase.lockDuration", 10*time.Minute)
+	return
+}
+
+// 进程内存中的计数 只适用于单节点部署
+type memPassphraseLimiter struct {
+	mutex    sync.Mutex
+	attempts map[string]*passphraseAttempts
+}
+
+type passphraseAttempts struct {
+	count       int
+	firstAt     time.Time
+	lockedUntil time.Time
+}
+
+func newMemPassphraseLimiter() *memPassphraseLimiter {
+	return &memPassphraseLimiter{attempts: make(map[string]*passphraseAttempts)}
+}
+
+// 检查是否锁定和计数在同一个锁中完成
+// 同一用户并发提交时 窗口内最多尝试 maxAttempts 次
+func (l *memPassphraseLimiter) Attempt(userId string) (bool, error) {
+	maxAttempts, window, lockDuration := passphraseLimitSettings()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	f, ok := l.attempts[userId]
+	if ok && now.Before(f.lockedUntil) {
+		return false, nil
+	}
+	// 窗口已过期 重新计数
+	if !ok || now.Sub(f.firstAt) > window {
+		f = &passphraseAttempts{firstAt: now}
+		l.attempts[userId] = f
+	}
+	f.count++
+	if f.count >= maxAttempts {
+		f.lockedUntil = now.Add(lockDuration)
+		f.count = 0
+		f.firstAt = now
+	}
+	return true, nil
+}
+
+func (l *memPassphraseLimiter) Reset(userId string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.attempts, userId)
+}
+
+// redis 中的计数 所有节点共享
+// 计数 key 第1次尝试时设置窗口过期时间 达到上限后设置锁定 key 并删除计数
+// 检查锁定 计数和锁定在1个 lua 脚本中执行 多个节点并发尝试时也是原子的
+type redisPassphraseLimiter struct {
+	pool *redis.Pool
+}
+
+// KEYS[1] 计数 key KEYS[2] 锁定 key ARGV[1] 次数上限 ARGV[2] 窗口毫秒数 ARGV[3] 锁定毫秒数
+var passphraseAttemptScript = redis.NewScript(2, `
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if n >= tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[2], 1, 'PX', ARGV[3])
+	redis.call('DEL', KEYS[1])
+end
+return 1
+`)
+
+func newRedisPassphraseLimiter(pool *redis.Pool) *redisPassphraseLimiter {
+	return &redisPassphraseLimiter{pool: pool}
+}
+
+func passphraseKeys(userId string) (attempts, locked string) {
+	return "passphrase:attempts:" + userId, "passphrase:locked:" + userId
+}
+
+func (l *redisPassphraseLimiter) Attempt(userId string) (bool, error) {
+	maxAttempts, window, lockDuration := passphraseLimitSettings()
+	attempts, locked := passphraseKeys(userId)
+	conn := l.pool.Get()
+	defer conn.Close()
+	ok, err := redis.Int(passphraseAttemptScript.Do(conn, attempts, locked,
+		maxAttempts, int64(window/time.Millisecond), int64(lockDuration/time.Millisecond)))
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+// 清除失败时 计数在窗口结束后自动过期
+func (l *redisPassphraseLimiter) Reset(userId string) {
+	attempts, locked := passphraseKeys(userId)
+	conn := l.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", attempts, locked); err != nil {
+		logrus.Warn("清除口令尝试次数失败: ", err)
+	}
+}
@@ -0,0 +1,77 @@
+package envelopes
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPassphrase_Normalize(t *testing.T) {
+	Convey("口令规范化", t, func() {
+		So(normalizePassphrase("恭喜 发财"), ShouldEqual, "恭喜发财")
+		So(normalizePassphrase(" 恭喜\t发财\n"), ShouldEqual, "恭喜发财")
+		So(normalizePassphrase("ＡＢＣ１２３"), ShouldEqual, "abc123")
+		So(normalizePassphrase("Happy　New Year"), ShouldEqual, "happynewyear")
+	})
+}
+
+func TestPassphrase_HashAndMatch(t *testing.T) {
+	Convey("口令加密和校验", t, func() {
+		hash, err := hashPassphrase("新年 快乐 ABC")
+		So(err, ShouldBeNil)
+		So(hash, ShouldNotContainSubstring, "新年")
+		So(matchPassphrase(hash, "新年快乐abc"), ShouldBeTrue)
+		So(matchPassphrase(hash, "新年快乐ａｂｃ"), ShouldBeTrue)
+		So(matchPassphrase(hash, "新年快乐"), ShouldBeFalse)
+		So(matchPassphrase(hash, ""), ShouldBeFalse)
+
+		_, err = hashPassphrase("   ")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("超过72个字节的口令每个字节都参与校验", t, func() {
+		// 24个汉字是72个字节 bcrypt 直接使用时后面的字符会被忽略
+		prefix := strings.Repeat("恭", 24)
+		hash, err := hashPassphrase(prefix + "喜发财")
+		So(err, ShouldBeNil)
+		So(matchPassphrase(hash, prefix+"喜发财"), ShouldBeTrue)
+		So(matchPassphrase(hash, prefix+"喜发达"), ShouldBeFalse)
+
+		_, err = hashPassphrase(strings.Repeat("恭", passphraseMaxLength+1))
+		So(err, ShouldEqual, ErrPassphraseTooLong)
+	})
+}
+
+func TestPassphraseLimiter_Attempt(t *testing.T) {
+	Convey("并发尝试口令 窗口内最多尝试 maxAttempts 次", t, func() {
+		limiter := newMemPassphraseLimiter()
+		maxAttempts, _, _ := passphraseLimitSettings()
+		userId := "passphrase-limiter-test"
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		allowed := 0
+		for i := 0; i < maxAttempts*4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ok, _ := limiter.Attempt(userId); ok {
+					mutex.Lock()
+					allowed++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		So(allowed, ShouldEqual, maxAttempts)
+		ok, err := limiter.Attempt(userId)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		limiter.Reset(userId)
+		ok, err = limiter.Attempt(userId)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+	})
+}
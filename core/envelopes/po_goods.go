@@ -29,6 +29,10 @@ type RedEnvelopeGoods struct {
 	CreatedAt        time.Time            `db:"created_at,omitempty"`
 	UpdatedAt        time.Time            `db:"updated_at,omitempty"`
 	OriginEnvelopeNo string               `db:"origin_envelope_no"` // 原关联订单号
+	GroupId          string               `db:"group_id"`           // 所属群编号
+	PassphraseHash   string               `db:"passphrase_hash"`    // 口令哈希值 不转换到DTO
+	PublishAt        time.Time            `db:"publish_at"`         // 发布时间
+	RequestId        string               `db:"request_id"`         // 发红包的请求ID
 }
 
 func (po *RedEnvelopeGoods) ToDTO() *services.RedEnvelopeGoodsDTO {
@@ -53,6 +57,9 @@ func (po *RedEnvelopeGoods) ToDTO() *services.RedEnvelopeGoodsDTO {
 		UpdatedAt:        po.UpdatedAt,
 		AccountNo:        "",
 		OriginEnvelopeNo: po.OriginEnvelopeNo,
+		GroupId:          po.GroupId,
+		PublishAt:        po.PublishAt,
+		RequestId:        po.RequestId,
 	}
 }
 
@@ -79,4 +86,6 @@ func (po *RedEnvelopeGoods) FromDTO(dto *services.RedEnvelopeGoodsDTO) {
 	po.OrderType = dto.OrderType
 	po.PayStatus = dto.PayStatus
 	po.OriginEnvelopeNo = dto.OriginEnvelopeNo
+	po.GroupId = dto.GroupId
+	po.PublishAt = dto.PublishAt
 }
@@ -23,6 +23,7 @@ type RedEnvelopeItem struct {
 	Desc         string          `db:"desc"`
 	CreatedAt    time.Time       `db:"created_at,omitempty"` // 创建时间
 	UpdatedAt    time.Time       `db:"updated_at,omitempty"` // 修改时间
+	RequestId    string          `db:"request_id"`           // 收红包的请求ID
 }
 
 func (po *RedEnvelopeItem) ToDTO() *services.RedEnvelopeItemDTO {
@@ -39,6 +40,7 @@ func (po *RedEnvelopeItem) ToDTO() *services.RedEnvelopeItemDTO {
 		CreatedAt:    po.CreatedAt,
 		UpdatedAt:    po.UpdatedAt,
 		Desc:         po.Desc,
+		RequestId:    po.RequestId,
 	}
 }
 
@@ -0,0 +1,20 @@
+package envelopes
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 过期红包退款尝试记录 映射 red_envelope_refund_attempt 表
+// 每个过期红包在退款前先认领该记录 记录尝试次数和最近1次失败原因 超过最大次数后不再重试
+type RefundAttempt struct {
+	Id         int64                        `db:"id,omitempty"`
+	EnvelopeNo string                       `db:"envelope_no,unique"`
+	Attempts   int                          `db:"attempts"`
+	Status     services.RefundAttemptStatus `db:"status"`
+	LastError  string                       `db:"last_error"`
+	ClaimedAt  time.Time                    `db:"claimed_at"`
+	CreatedAt  time.Time                    `db:"created_at,omitempty"`
+	UpdatedAt  time.Time                    `db:"updated_at,omitempty"`
+}
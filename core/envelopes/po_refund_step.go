@@ -0,0 +1,18 @@
+package envelopes
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 退款流程步骤记录 映射 red_envelope_refund_step 表
+// 每完成1个退款步骤追加1条记录 和该步骤的状态变更在同1个事务中写入
+type RefundStepLog struct {
+	Id         int64               `db:"id,omitempty"`
+	RefundNo   string              `db:"refund_no"`
+	EnvelopeNo string              `db:"envelope_no"`
+	Step       services.RefundStep `db:"step"`
+	Desc       string              `db:"desc"`
+	CreatedAt  time.Time           `db:"created_at,omitempty"`
+}
@@ -0,0 +1,100 @@
+package envelopes
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 红包商品仓储 方法语义和 RedEnvelopeGoodsDao 相同
+type GoodsRepository interface {
+	Insert(po *RedEnvelopeGoods) (int64, error)
+	GetOne(envelopeNo string) *RedEnvelopeGoods
+	UpdateBalance(envelopeNo string, amount decimal.Decimal) (int64, error)
+	UpdateOrderStatus(envelopeNo string, status services.OrderStatus) (int64, error)
+	UpdateStatusIf(envelopeNo string, from, to services.OrderStatus, fromPay, toPay services.PayStatus) (int64, error)
+	CancelIfUnclaimed(envelopeNo, userId string) (int64, error)
+	FindDuePublish(size int) []RedEnvelopeGoods
+	FindByUser(userId string, offset, limit int) []RedEnvelopeGoods
+	ListReceivable(groupIds []string, offset, size int) []RedEnvelopeGoods
+	// 过期退款
+	UpdatePayStatusIf(envelopeNo string, from, to services.PayStatus, status services.OrderStatus) (int64, error)
+	GetRefundingByOrigin(originEnvelopeNo string) *RedEnvelopeGoods
+	FindUnfinishedRefunds(lastId int64, before time.Time, size int) []RedEnvelopeGoods
+	FindExpired(lastId int64, maxAttempts, size int) []RedEnvelopeGoods
+	FindPendingExpiry(lastId int64, size int) []RedEnvelopeGoods
+}
+
+// 红包明细仓储 方法语义和 RedEnvelopeItemDao 相同
+type ItemRepository interface {
+	GetOne(itemNo string) *RedEnvelopeItem
+	Insert(data *RedEnvelopeItem) (int64, error)
+	FindItems(envelopeNo string) []*RedEnvelopeItem
+	ListReceivedItems(userId string, offset, limit int) []*RedEnvelopeItem
+	GetByUser(envelopeNo, userId string) *RedEnvelopeItem
+}
+
+// 退款尝试记录仓储 方法语义和 RefundAttemptDao 相同
+type RefundAttemptRepository interface {
+	GetOne(envelopeNo string) *RefundAttempt
+	Claim(envelopeNo string, maxAttempts int, claimTimeout time.Duration) (bool, error)
+	Succeed(envelopeNo string) (int64, error)
+	Fail(envelopeNo, lastError string) (int64, error)
+}
+
+// 退款步骤记录仓储 方法语义和 RefundStepDao 相同
+type RefundStepRepository interface {
+	Insert(data *RefundStepLog) (int64, error)
+	FindByRefundNo(refundNo string) []*RefundStepLog
+}
+
+// 根据事务类型创建仓储 tx 来自 base.Transact
+func NewGoodsRepository(tx base.Transaction) GoodsRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &RedEnvelopeGoodsDao{runner: t}
+	case *memdb.Tx:
+		return &memGoodsRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewItemRepository(tx base.Transaction) ItemRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &RedEnvelopeItemDao{runner: t}
+	case *memdb.Tx:
+		return &memItemRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewRefundAttemptRepository(tx base.Transaction) RefundAttemptRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &RefundAttemptDao{runner: t}
+	case *memdb.Tx:
+		return &memRefundAttemptRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewRefundStepRepository(tx base.Transaction) RefundStepRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &RefundStepDao{runner: t}
+	case *memdb.Tx:
+		return &memRefundStepRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
@@ -0,0 +1,423 @@
+package envelopes
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 内存数据库的仓储实现 查询和更新条件和 DAO 中的 SQL 保持一致
+// 按 created_at 倒序的查询 使用自增 id 倒序代替
+
+const (
+	tableGoods         = "red_envelope_goods"
+	tableItem          = "red_envelope_item"
+	tableRefundAttempt = "red_envelope_refund_attempt"
+	tableRefundStep    = "red_envelope_refund_step"
+)
+
+type memGoodsRepository struct {
+	tx *memdb.Tx
+}
+
+// 按 id 从小到大返回满足条件的红包
+func (r *memGoodsRepository) filter(match func(g *RedEnvelopeGoods) bool) []RedEnvelopeGoods {
+	var out []RedEnvelopeGoods
+	r.tx.Scan(tableGoods, func(id int64, row interface{}) bool {
+		g := row.(RedEnvelopeGoods)
+		if match(&g) {
+			out = append(out, g)
+		}
+		return true
+	})
+	return out
+}
+
+// 按条件更新1个红包 返回影响行数
+func (r *memGoodsRepository) update(envelopeNo string, where func(g *RedEnvelopeGoods) bool,
+	set func(g *RedEnvelopeGoods)) (int64, error) {
+	g := r.GetOne(envelopeNo)
+	if g == nil || !where(g) {
+		return 0, nil
+	}
+	set(g)
+	g.UpdatedAt = time.Now()
+	r.tx.Put(tableGoods, g.Id, *g)
+	return 1, nil
+}
+
+func (r *memGoodsRepository) Insert(po *RedEnvelopeGoods) (int64, error) {
+	if r.GetOne(po.EnvelopeNo) != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *po
+	row.Id = r.tx.NextId(tableGoods)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableGoods, row.Id, row)
+	return row.Id, nil
+}
+
+func (r *memGoodsRepository) GetOne(envelopeNo string) *RedEnvelopeGoods {
+	var out *RedEnvelopeGoods
+	r.tx.Scan(tableGoods, func(id int64, row interface{}) bool {
+		g := row.(RedEnvelopeGoods)
+		if g.EnvelopeNo == envelopeNo {
+			out = &g
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+func (r *memGoodsRepository) UpdateBalance(envelopeNo string, amount decimal.Decimal) (int64, error) {
+	return r.update(envelopeNo, func(g *RedEnvelopeGoods) bool {
+		return g.RemainQuantity > 0 &&
+			g.RemainAmount.GreaterThanOrEqual(amount) &&
+			g.Status != services.OrderDisabled
+	}, func(g *RedEnvelopeGoods) {
+		g.RemainAmount = g.RemainAmount.Sub(amount)
+		g.RemainQuantity--
+	})
+}
+
+func (r *memGoodsRepository) UpdateOrderStatus(envelopeNo string, status services.OrderStatus) (int64, error) {
+	return r.update(envelopeNo, func(g *RedEnvelopeGoods) bool {
+		return true
+	}, func(g *RedEnvelopeGoods) {
+		g.Status = status
+	})
+}
+
+func (r *memGoodsRepository) UpdateStatusIf(envelopeNo string, from, to services.OrderStatus, fromPay, toPay services.PayStatus) (int64, error) {
+	return r.update(envelopeNo, func(g *RedEnvelopeGoods) bool {
+		return g.Status == from && g.PayStatus == fromPay
+	}, func(g *RedEnvelopeGoods) {
+		g.Status = to
+		g.PayStatus = toPay
+	})
+}
+
+func (r *memGoodsRepository) CancelIfUnclaimed(envelopeNo, userId string) (int64, error) {
+	now := time.Now()
+	return r.update(envelopeNo, func(g *RedEnvelopeGoods) bool {
+		return g.UserId == userId &&
+			(g.Status == services.OrderCreate || g.Status == services.OrderSending) &&
+			(g.PayStatus == services.Paying || g.PayStatus == services.Payed) &&
+			g.RemainQuantity == g.Quantity &&
+			g.ExpiredAt.After(now)
+	}, func(g *RedEnvelopeGoods) {
+		g.Status = services.OrderDisabled
+	})
+}
+
+// 按发布时间排序 发布时间相同时按 id 排序
+func (r *memGoodsRepository) FindDuePublish(size int) []RedEnvelopeGoods {
+	now := time.Now()
+	due := r.filter(func(g *RedEnvelopeGoods) bool {
+		return g.Status == services.OrderCreate &&
+			g.PayStatus == services.PayNothing &&
+			!g.PublishAt.After(now)
+	})
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].PublishAt.Before(due[j].PublishAt)
+	})
+	return page(due, 0, size)
+}
+
+func (r *memGoodsRepository) FindByUser(userId string, offset, limit int) []RedEnvelopeGoods {
+	goods := r.filter(func(g *RedEnvelopeGoods) bool {
+		return g.UserId == userId
+	})
+	return page(reverse(goods), offset, limit)
+}
+
+func (r *memGoodsRepository) ListReceivable(groupIds []string, offset, size int) []RedEnvelopeGoods {
+	now := time.Now()
+	groups := make(map[string]bool, len(groupIds))
+	for _, id := range groupIds {
+		groups[id] = true
+	}
+	goods := r.filter(func(g *RedEnvelopeGoods) bool {
+		return g.RemainQuantity > 0 &&
+			g.ExpiredAt.After(now) &&
+			!g.PublishAt.After(now) &&
+			g.Status != services.OrderDisabled &&
+			g.OrderType == services.OrderTypeSending &&
+			(g.GroupId == "" || groups[g.GroupId])
+	})
+	return page(reverse(goods), offset, size)
+}
+
+func (r *memGoodsRepository) UpdatePayStatusIf(envelopeNo string, from, to services.PayStatus, status services.OrderStatus) (int64, error) {
+	return r.update(envelopeNo, func(g *RedEnvelopeGoods) bool {
+		return g.PayStatus == from
+	}, func(g *RedEnvelopeGoods) {
+		g.PayStatus = to
+		g.Status = status
+	})
+}
+
+// 退款中 已退款的退款订单
+func isUnfinishedRefund(g *RedEnvelopeGoods) bool {
+	return g.OrderType == services.OrderTypeRefund &&
+		g.Status == services.OrderExpired &&
+		(g.PayStatus == services.Refunding || g.PayStatus == services.Refunded)
+}
+
+func (r *memGoodsRepository) GetRefundingByOrigin(originEnvelopeNo string) *RedEnvelopeGoods {
+	refunds := r.filter(func(g *RedEnvelopeGoods) bool {
+		return g.OriginEnvelopeNo == originEnvelopeNo && isUnfinishedRefund(g)
+	})
+	if len(refunds) == 0 {
+		return nil
+	}
+	return &refunds[len(refunds)-1]
+}
+
+func (r *memGoodsRepository) FindUnfinishedRefunds(lastId int64, before time.Time, size int) []RedEnvelopeGoods {
+	refunds := r.filter(func(g *RedEnvelopeGoods) bool {
+		return g.Id > lastId && isUnfinishedRefund(g) && g.UpdatedAt.Before(before)
+	})
+	return page(refunds, 0, size)
+}
+
+// 没有退款的已发布红包 状态不是已失效和退款成功
+func isPendingExpiry(g *RedEnvelopeGoods) bool {
+	return g.OrderType == services.OrderTypeSending &&
+		g.RemainQuantity > 0 &&
+		g.Status != services.OrderDisabled &&
+		g.Status != services.OrderExpiredRefundSucceed &&
+		g.PayStatus != services.PayNothing
+}
+
+// 和 SQL 的 left join 相同 没有退款尝试记录或尝试次数小于 maxAttempts
+func (r *memGoodsRepository) FindExpired(lastId int64, maxAttempts, size int) []RedEnvelopeGoods {
+	now := time.Now()
+	attempts := make(map[string]int)
+	r.tx.Scan(tableRefundAttempt, func(id int64, row interface{}) bool {
+		a := row.(RefundAttempt)
+		attempts[a.EnvelopeNo] = a.Attempts
+		return true
+	})
+	goods := r.filter(func(g *RedEnvelopeGoods) bool {
+		n, ok := attempts[g.EnvelopeNo]
+		return g.Id > lastId && isPendingExpiry(g) && g.ExpiredAt.Before(now) &&
+			(!ok || n < maxAttempts)
+	})
+	return page(goods, 0, size)
+}
+
+func (r *memGoodsRepository) FindPendingExpiry(lastId int64, size int) []RedEnvelopeGoods {
+	goods := r.filter(func(g *RedEnvelopeGoods) bool {
+		return g.Id > lastId && isPendingExpiry(g)
+	})
+	return page(goods, 0, size)
+}
+
+func reverse(goods []RedEnvelopeGoods) []RedEnvelopeGoods {
+	for i, j := 0, len(goods)-1; i < j; i, j = i+1, j-1 {
+		goods[i], goods[j] = goods[j], goods[i]
+	}
+	return goods
+}
+
+// 和 SQL 的 limit offset,size 相同
+func page(goods []RedEnvelopeGoods, offset, size int) []RedEnvelopeGoods {
+	if offset >= len(goods) {
+		return nil
+	}
+	goods = goods[offset:]
+	if size < len(goods) {
+		goods = goods[:size]
+	}
+	return goods
+}
+
+type memItemRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memItemRepository) filter(match func(item *RedEnvelopeItem) bool) []*RedEnvelopeItem {
+	items := make([]*RedEnvelopeItem, 0)
+	r.tx.Scan(tableItem, func(id int64, row interface{}) bool {
+		item := row.(RedEnvelopeItem)
+		if match(&item) {
+			items = append(items, &item)
+		}
+		return true
+	})
+	return items
+}
+
+func (r *memItemRepository) first(match func(item *RedEnvelopeItem) bool) *RedEnvelopeItem {
+	var out *RedEnvelopeItem
+	r.tx.Scan(tableItem, func(id int64, row interface{}) bool {
+		item := row.(RedEnvelopeItem)
+		if match(&item) {
+			out = &item
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+func (r *memItemRepository) GetOne(itemNo string) *RedEnvelopeItem {
+	return r.first(func(item *RedEnvelopeItem) bool { return item.ItemNo == itemNo })
+}
+
+func (r *memItemRepository) Insert(data *RedEnvelopeItem) (int64, error) {
+	if r.GetOne(data.ItemNo) != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *data
+	row.Id = r.tx.NextId(tableItem)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableItem, row.Id, row)
+	return row.Id, nil
+}
+
+func (r *memItemRepository) FindItems(envelopeNo string) []*RedEnvelopeItem {
+	return r.filter(func(item *RedEnvelopeItem) bool { return item.EnvelopeNo == envelopeNo })
+}
+
+func (r *memItemRepository) ListReceivedItems(userId string, offset, limit int) []*RedEnvelopeItem {
+	items := r.filter(func(item *RedEnvelopeItem) bool { return item.RecvUserId == userId })
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func (r *memItemRepository) GetByUser(envelopeNo, userId string) *RedEnvelopeItem {
+	return r.first(func(item *RedEnvelopeItem) bool {
+		return item.EnvelopeNo == envelopeNo && item.RecvUserId == userId
+	})
+}
+
+type memRefundAttemptRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memRefundAttemptRepository) find(envelopeNo string) (int64, *RefundAttempt) {
+	var id int64
+	var out *RefundAttempt
+	r.tx.Scan(tableRefundAttempt, func(rowId int64, row interface{}) bool {
+		a := row.(RefundAttempt)
+		if a.EnvelopeNo == envelopeNo {
+			id, out = rowId, &a
+			return false
+		}
+		return true
+	})
+	return id, out
+}
+
+// 按条件更新退款尝试记录 返回影响行数
+func (r *memRefundAttemptRepository) update(envelopeNo string, where func(a *RefundAttempt) bool,
+	set func(a *RefundAttempt)) int64 {
+	id, a := r.find(envelopeNo)
+	if a == nil || !where(a) {
+		return 0
+	}
+	set(a)
+	a.UpdatedAt = time.Now()
+	r.tx.Put(tableRefundAttempt, id, *a)
+	return 1
+}
+
+func (r *memRefundAttemptRepository) GetOne(envelopeNo string) *RefundAttempt {
+	_, a := r.find(envelopeNo)
+	return a
+}
+
+// 和 RefundAttemptDao.Claim 相同 没有记录时先创建待重试记录
+func (r *memRefundAttemptRepository) Claim(envelopeNo string, maxAttempts int, claimTimeout time.Duration) (bool, error) {
+	now := time.Now()
+	if _, a := r.find(envelopeNo); a == nil {
+		row := RefundAttempt{
+			EnvelopeNo: envelopeNo,
+			Status:     services.RefundAttemptPending,
+			ClaimedAt:  now,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		row.Id = r.tx.NextId(tableRefundAttempt)
+		r.tx.Put(tableRefundAttempt, row.Id, row)
+	}
+	rows := r.update(envelopeNo, func(a *RefundAttempt) bool {
+		return a.Attempts < maxAttempts &&
+			(a.Status == services.RefundAttemptPending ||
+				(a.Status == services.RefundAttemptProcessing && a.ClaimedAt.Before(now.Add(-claimTimeout))))
+	}, func(a *RefundAttempt) {
+		a.Attempts++
+		a.Status = services.RefundAttemptProcessing
+		a.ClaimedAt = now
+	})
+	return rows == 1, nil
+}
+
+func (r *memRefundAttemptRepository) Succeed(envelopeNo string) (int64, error) {
+	return r.update(envelopeNo, func(a *RefundAttempt) bool {
+		return a.Status == services.RefundAttemptProcessing
+	}, func(a *RefundAttempt) {
+		a.Status = services.RefundAttemptSucceeded
+		a.LastError = ""
+	}), nil
+}
+
+func (r *memRefundAttemptRepository) Fail(envelopeNo, lastError string) (int64, error) {
+	if runes := []rune(lastError); len(runes) > maxLastErrorLength {
+		lastError = string(runes[:maxLastErrorLength])
+	}
+	return r.update(envelopeNo, func(a *RefundAttempt) bool {
+		return a.Status == services.RefundAttemptProcessing
+	}, func(a *RefundAttempt) {
+		a.Status = services.RefundAttemptPending
+		a.LastError = lastError
+	}), nil
+}
+
+type memRefundStepRepository struct {
+	tx *memdb.Tx
+}
+
+// (refund_no, step) 是唯一索引
+func (r *memRefundStepRepository) Insert(data *RefundStepLog) (int64, error) {
+	for _, s := range r.FindByRefundNo(data.RefundNo) {
+		if s.Step == data.Step {
+			return 0, memdb.ErrDuplicate
+		}
+	}
+	row := *data
+	row.Id = r.tx.NextId(tableRefundStep)
+	row.CreatedAt = time.Now()
+	r.tx.Put(tableRefundStep, row.Id, row)
+	return row.Id, nil
+}
+
+func (r *memRefundStepRepository) FindByRefundNo(refundNo string) []*RefundStepLog {
+	var steps []*RefundStepLog
+	r.tx.Scan(tableRefundStep, func(id int64, row interface{}) bool {
+		s := row.(RefundStepLog)
+		if s.RefundNo == refundNo {
+			steps = append(steps, &s)
+		}
+		return true
+	})
+	return steps
+}
@@ -0,0 +1,64 @@
+package envelopes
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+func TestMemGoodsRepository_Status(t *testing.T) {
+	Convey("内存红包仓储的状态更新", t, func() {
+		newGoods := func(publishAt time.Time) RedEnvelopeGoods {
+			domain := &goodsDomain{}
+			domain.Create(services.RedEnvelopeGoodsDTO{
+				EnvelopeType: int(services.LuckyEnvelopeType),
+				UserId:       "u1",
+				Amount:       "10",
+				Quantity:     2,
+				OrderType:    services.OrderTypeSending,
+				PublishAt:    publishAt,
+			})
+			return domain.RedEnvelopeGoods
+		}
+		immediate := newGoods(time.Time{})
+		scheduled := newGoods(time.Now().Add(time.Hour))
+		// 立即发布的红包 创建 支付中 预约红包 创建 未支付
+		So(immediate.Status, ShouldEqual, services.OrderCreate)
+		So(immediate.PayStatus, ShouldEqual, services.Paying)
+		So(scheduled.Status, ShouldEqual, services.OrderCreate)
+		So(scheduled.PayStatus, ShouldEqual, services.PayNothing)
+
+		db := memdb.New()
+		err := db.Tx(func(tx *memdb.Tx) error {
+			repo := NewGoodsRepository(tx)
+			_, err := repo.Insert(&immediate)
+			So(err, ShouldBeNil)
+			_, err = repo.Insert(&scheduled)
+			So(err, ShouldBeNil)
+
+			// 发布只更新未支付的预约红包
+			rows, err := repo.UpdateStatusIf(immediate.EnvelopeNo, services.OrderCreate, services.OrderSending,
+				services.PayNothing, services.Payed)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 0)
+			rows, err = repo.UpdateStatusIf(scheduled.EnvelopeNo, services.OrderCreate, services.OrderSending,
+				services.PayNothing, services.Payed)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+
+			// 立即发布的红包和发布后的预约红包都可以取消
+			rows, err = repo.CancelIfUnclaimed(immediate.EnvelopeNo, "u1")
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			rows, err = repo.CancelIfUnclaimed(scheduled.EnvelopeNo, "u1")
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			return nil
+		})
+		So(err, ShouldBeNil)
+	})
+}
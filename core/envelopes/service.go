@@ -2,12 +2,12 @@ package envelopes
 
 import (
 	"context"
-	"errors"
 	"sync"
+	"time"
 
-	"github.com/sirupsen/logrus"
-
+	"github.com/solozyx/red-envelope/core/accounts"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/filter"
 	"github.com/solozyx/red-envelope/services"
 )
 
@@ -22,14 +22,40 @@ func init() {
 type redEnvelopeService struct{}
 
 // 发红包
-func (s *redEnvelopeService) SendOut(dto services.RedEnvelopeSendingDTO) (*services.RedEnvelopeActivity, error) {
+func (s *redEnvelopeService) SendOut(ctx context.Context, dto services.RedEnvelopeSendingDTO) (activity *services.RedEnvelopeActivity, err error) {
+	ctx, span := base.StartSpan(ctx, "RedEnvelopeService.SendOut")
+	defer func() { base.EndSpan(span, err) }()
 	if err := base.ValidateStruct(&dto); err != nil {
 		return nil, err
 	}
 	// 获取红包发送人的资金账户信息
 	account := services.GetAccountService().GetEnvelopeAccountByUserId(dto.UserId)
 	if account == nil {
-		return nil, errors.New("用户的账户不存在:" + dto.UserId)
+		return nil, accounts.ErrAccountNotFound.With("userId", dto.UserId)
+	}
+
+	// 只有口令红包需要口令
+	if dto.EnvelopeType == int(services.PassphraseEnvelopeType) {
+		if dto.Passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+	} else {
+		dto.Passphrase = ""
+	}
+
+	// 祝福语内容过滤 长度 表情 敏感词 HTML转义
+	if dto.Blessing != "" {
+		blessing, err := filter.BlessingFilter().Filter(dto.Blessing)
+		if err != nil {
+			return nil, err
+		}
+		dto.Blessing = blessing
+	}
+
+	// 预约发布时间不能太晚
+	maxAhead := base.Props().GetDurationDefault("envelope.schedule.maxAhead", 30*24*time.Hour)
+	if dto.PublishAt.After(time.Now().Add(maxAhead)) {
+		return nil, ErrPublishTooLate
 	}
 
 	goods := (&dto).ToGoods()
@@ -39,6 +65,13 @@ func (s *redEnvelopeService) SendOut(dto services.RedEnvelopeSendingDTO) (*servi
 		goods.Blessing = services.DefaultBlessing
 	}
 
+	// 群红包 发送人必须在群中 开启 envelope.group.checkSize 时红包数量不能超过群成员数量
+	if goods.GroupId != "" {
+		if err := s.checkGroup(goods); err != nil {
+			return nil, err
+		}
+	}
+
 	if goods.EnvelopeType == int(services.GeneralEnvelopeType) {
 		goods.AmountOne = goods.Amount
 		// goods.Amount = decimal.Decimal{}
@@ -48,14 +81,16 @@ func (s *redEnvelopeService) SendOut(dto services.RedEnvelopeSendingDTO) (*servi
 	// 执行发红包的逻辑
 	// 业务领域 goodsDomain 有状态 每次创建新的使用
 	domain := new(goodsDomain)
-	activity, err := domain.SendOut(*goods)
+	activity, err = domain.SendOut(ctx, *goods)
 	if err != nil {
-		logrus.Error(err)
+		base.Logger(ctx).Error(err)
 	}
 	return activity, err
 }
 
-func (s *redEnvelopeService) Receive(dto services.RedEnvelopeReceiveDTO) (item *services.RedEnvelopeItemDTO, err error) {
+func (s *redEnvelopeService) Receive(ctx context.Context, dto services.RedEnvelopeReceiveDTO) (item *services.RedEnvelopeItemDTO, err error) {
+	ctx, span := base.StartSpan(ctx, "RedEnvelopeService.Receive")
+	defer func() { base.EndSpan(span, err) }()
 	// 参数校验
 	if err = base.ValidateStruct(&dto); err != nil {
 		return nil, err
@@ -63,13 +98,58 @@ func (s *redEnvelopeService) Receive(dto services.RedEnvelopeReceiveDTO) (item *
 	// 获取当前收红包用户的账户信息
 	account := services.GetAccountService().GetEnvelopeAccountByUserId(dto.RecvUserId)
 	if account == nil {
-		return nil, errors.New("收红包资金账户不存在:user_id = " + dto.RecvUserId)
+		return nil, accounts.ErrAccountNotFound.With("userId", dto.RecvUserId)
+	}
+	// 没有传入账户编号时使用查询到的红包账户
+	if dto.AccountNo == "" {
+		dto.AccountNo = account.AccountNo
 	}
 	// 进行尝试收红包
-	item, err = new(goodsDomain).Receive(context.Background(), dto)
+	item, err = new(goodsDomain).Receive(ctx, dto)
+	if err != nil {
+		base.Logger(ctx).Error(err)
+	}
 	return item, err
 }
 
+// 群红包发送校验
+func (s *redEnvelopeService) checkGroup(goods *services.RedEnvelopeGoodsDTO) error {
+	gs := services.GetGroupService()
+	group := gs.GetGroup(goods.GroupId)
+	if group == nil || group.Status != services.GroupEnabled {
+		return ErrGroupUnavailable.With("groupId", goods.GroupId)
+	}
+	if !gs.IsMember(goods.GroupId, goods.UserId) {
+		return ErrNotGroupMember.With("userId", goods.UserId)
+	}
+	// 红包数量校验默认关闭 群成员可能在发红包之后加入
+	if base.Props().GetBoolDefault("envelope.group.checkSize", false) && goods.Quantity > group.MemberCount {
+		return ErrQuantityExceedsMembers.With("quantity", goods.Quantity).With("memberCount", group.MemberCount)
+	}
+	return nil
+}
+
+// 取消红包
+// 预约红包在发布前取消 解冻发红包人的资金
+// 已发布的红包在没有人领取时取消 全额退款
+func (s *redEnvelopeService) Cancel(ctx context.Context, dto services.RedEnvelopeCancelDTO) (err error) {
+	ctx, span := base.StartSpan(ctx, "RedEnvelopeService.Cancel")
+	defer func() { base.EndSpan(span, err) }()
+	if err := base.ValidateStruct(&dto); err != nil {
+		return err
+	}
+	domain := new(goodsDomain)
+	goods := domain.Get(dto.EnvelopeNo)
+	if goods == nil {
+		return ErrEnvelopeNotFound.With("envelopeNo", dto.EnvelopeNo)
+	}
+	domain.RedEnvelopeGoods = *goods
+	if domain.IsScheduled() {
+		return domain.CancelScheduled(ctx, dto)
+	}
+	return new(goodsDomain).CancelUnclaimed(ctx, dto)
+}
+
 func (s *redEnvelopeService) Refund(string) *services.RedEnvelopeGoodsDTO {
 	panic("implement me")
 }
@@ -109,9 +189,9 @@ func (s *redEnvelopeService) ListItems(envelopeNo string) (items []*services.Red
 	return domain.FindItems(envelopeNo)
 }
 
-func (s *redEnvelopeService) ListReceivable(offset int, size int) []*services.RedEnvelopeGoodsDTO {
+func (s *redEnvelopeService) ListReceivable(userId string, offset int, size int) []*services.RedEnvelopeGoodsDTO {
 	domain := new(goodsDomain)
-	pos := domain.ListReceivable(offset, size)
+	pos := domain.ListReceivable(userId, offset, size)
 	orders := make([]*services.RedEnvelopeGoodsDTO, 0, len(pos))
 	for _, po := range pos {
 		orders = append(orders, po.ToDTO())
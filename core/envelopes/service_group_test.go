@@ -0,0 +1,145 @@
+package envelopes
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	"github.com/shopspring/decimal"
+	. "github.com/smartystreets/goconvey/convey"
+
+	_ "github.com/solozyx/red-envelope/core/groups"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+	_ "github.com/solozyx/red-envelope/textx"
+)
+
+// memory 后端发群红包和收群红包
+func TestRedEnvelopeService_GroupMemoryBackend(t *testing.T) {
+	restore := base.UseMemoryBackend()
+	defer restore()
+
+	as := services.GetAccountService()
+	gs := services.GetGroupService()
+	rs := services.GetRedEnvelopeService()
+
+	Convey("memory 后端的群红包", t, func() {
+		accounts := make([]*services.AccountDTO, 0)
+		for i := 0; i < 3; i++ {
+			acDto, err := as.CreateAccount(context.Background(), services.AccountCreatedDTO{
+				UserId:       ksuid.New().Next().String(),
+				Username:     "测试用户" + strconv.Itoa(i+1),
+				AccountName:  "测试账户" + strconv.Itoa(i+1),
+				AccountType:  int(services.EnvelopeAccountType),
+				Amount:       "100",
+				CurrencyCode: "CNY",
+			})
+			So(err, ShouldBeNil)
+			accounts = append(accounts, acDto)
+		}
+		owner, member, outsider := accounts[0], accounts[1], accounts[2]
+
+		group, err := gs.CreateGroup(services.GroupCreatedDTO{
+			GroupId:       ksuid.New().Next().String(),
+			GroupName:     "测试群",
+			OwnerUserId:   owner.UserId,
+			OwnerUsername: owner.Username,
+		})
+		So(err, ShouldBeNil)
+		err = gs.Join(services.GroupMemberDTO{
+			GroupId:  group.GroupId,
+			UserId:   member.UserId,
+			Username: member.Username,
+		})
+		So(err, ShouldBeNil)
+
+		activity, err := rs.SendOut(context.Background(), services.RedEnvelopeSendingDTO{
+			UserId:       owner.UserId,
+			Username:     owner.Username,
+			EnvelopeType: int(services.GeneralEnvelopeType),
+			Amount:       "1",
+			Quantity:     2,
+			GroupId:      group.GroupId,
+		})
+		So(err, ShouldBeNil)
+		So(activity.GroupId, ShouldEqual, group.GroupId)
+
+		// 群成员可以看到群红包 群外用户看不到
+		contains := func(userId string) bool {
+			for _, g := range rs.ListReceivable(userId, 0, 100) {
+				if g.EnvelopeNo == activity.EnvelopeNo {
+					return true
+				}
+			}
+			return false
+		}
+		So(contains(member.UserId), ShouldBeTrue)
+		So(contains(outsider.UserId), ShouldBeFalse)
+
+		item, err := rs.Receive(context.Background(), services.RedEnvelopeReceiveDTO{
+			EnvelopeNo:   activity.EnvelopeNo,
+			RecvUserId:   member.UserId,
+			RecvUsername: member.Username,
+		})
+		So(err, ShouldBeNil)
+		So(item.Amount.Equal(decimal.NewFromFloat(1)), ShouldBeTrue)
+
+		_, err = rs.Receive(context.Background(), services.RedEnvelopeReceiveDTO{
+			EnvelopeNo:   activity.EnvelopeNo,
+			RecvUserId:   outsider.UserId,
+			RecvUsername: outsider.Username,
+		})
+		So(errors.Is(err, ErrNotGroupMember), ShouldBeTrue)
+	})
+}
+
+// 红包数量超过群成员数量 envelope.group.checkSize 开启时拒绝 默认允许
+func TestRedEnvelopeService_GroupCheckSize(t *testing.T) {
+	restore := base.UseMemoryBackend()
+	defer restore()
+	props := base.Props()
+	defer props.Set("envelope.group.checkSize", props.GetDefault("envelope.group.checkSize", "false"))
+
+	Convey("群红包数量校验", t, func() {
+		owner, err := services.GetAccountService().CreateAccount(context.Background(), services.AccountCreatedDTO{
+			UserId:       ksuid.New().Next().String(),
+			Username:     "测试群主",
+			AccountName:  "测试账户",
+			AccountType:  int(services.EnvelopeAccountType),
+			Amount:       "100",
+			CurrencyCode: "CNY",
+		})
+		So(err, ShouldBeNil)
+		group, err := services.GetGroupService().CreateGroup(services.GroupCreatedDTO{
+			GroupId:       ksuid.New().Next().String(),
+			GroupName:     "测试群",
+			OwnerUserId:   owner.UserId,
+			OwnerUsername: owner.Username,
+		})
+		So(err, ShouldBeNil)
+		So(group.MemberCount, ShouldEqual, 1)
+		// 只有群主1个成员 红包数量是2
+		sending := services.RedEnvelopeSendingDTO{
+			UserId:       owner.UserId,
+			Username:     owner.Username,
+			EnvelopeType: int(services.GeneralEnvelopeType),
+			Amount:       "1",
+			Quantity:     2,
+			GroupId:      group.GroupId,
+		}
+
+		Convey("默认不校验", func() {
+			props.Set("envelope.group.checkSize", "false")
+			_, err := services.GetRedEnvelopeService().SendOut(context.Background(), sending)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("开启后拒绝", func() {
+			props.Set("envelope.group.checkSize", "true")
+			_, err := services.GetRedEnvelopeService().SendOut(context.Background(), sending)
+			So(errors.Is(err, ErrQuantityExceedsMembers), ShouldBeTrue)
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package envelopes
 
 import (
+	"context"
 	"strconv"
 	"testing"
 
@@ -29,7 +30,7 @@ func TestGoodsDomain_Receive(t *testing.T) {
 				CurrencyCode: "CNY",
 			}
 			// 账户创建
-			acDto, err := as.CreateAccount(account)
+			acDto, err := as.CreateAccount(context.Background(), account)
 			So(err, ShouldBeNil)
 			So(acDto, ShouldNotBeNil)
 			accounts = append(accounts, acDto)
@@ -39,17 +40,18 @@ func TestGoodsDomain_Receive(t *testing.T) {
 		acDTO := accounts[0]
 		rs := services.GetRedEnvelopeService()
 		// 发送普通红包
+		amountOne := decimal.NewFromFloat(1.88)
 		goods := services.RedEnvelopeSendingDTO{
 			UserId:       acDTO.UserId,
 			Username:     acDTO.Username,
 			EnvelopeType: int(services.GeneralEnvelopeType),
 			// 普通红包 Amount 是每个子红包金额 总金额 = 1.88 * 10 = 18.8 元
-			Amount:   decimal.NewFromFloat(1.88),
+			Amount:   amountOne.String(),
 			Quantity: size,
 			Blessing: "发红包",
 		}
 		// 发红包返回红包活动
-		activity, err := rs.SendOut(goods)
+		activity, err := rs.SendOut(context.Background(), goods)
 		So(err, ShouldBeNil)
 		So(activity, ShouldNotBeNil)
 		So(activity.Link, ShouldNotBeEmpty)
@@ -59,11 +61,11 @@ func TestGoodsDomain_Receive(t *testing.T) {
 		So(dto.UserId, ShouldEqual, goods.UserId)
 		So(dto.Username, ShouldEqual, goods.Username)
 		So(dto.Quantity, ShouldEqual, goods.Quantity)
-		So(dto.Amount.String(), ShouldEqual, goods.Amount.Mul(decimal.NewFromFloat(float64(goods.Quantity))).String())
-		So(dto.AmountOne.String(), ShouldEqual, goods.Amount.String())
+		So(dto.Amount, ShouldEqual, amountOne.Mul(decimal.NewFromFloat(float64(goods.Quantity))).String())
+		So(dto.AmountOne, ShouldEqual, goods.Amount)
 
 		// 发红包后 剩余金额 = 总金额
-		remainAmount := activity.Amount
+		remainAmount := activity.RemainAmount
 
 		// 3.使用发送红包数量的人收红包 发红包的人也可以收红包
 		Convey("收普通红包", func() {
@@ -75,20 +77,44 @@ func TestGoodsDomain_Receive(t *testing.T) {
 					AccountNo:    account.AccountNo,
 				}
 				// 收红包 返回红包订单详情
-				item, err := rs.Receive(receiveDTO)
+				item, err := rs.Receive(context.Background(), receiveDTO)
 				So(err, ShouldBeNil)
 				So(item, ShouldNotBeNil)
 				// 收到的红包金额
-				So(item.Amount.String(), ShouldEqual, activity.AmountOne.String())
+				So(item.Amount.String(), ShouldEqual, activity.AmountOne)
 				// 每次收红包后 红包剩余金额
 				remainAmount = remainAmount.Sub(item.Amount)
 				So(item.RemainAmount.String(), ShouldEqual, remainAmount.String())
 			}
 		})
 
+		// 不传账户编号 通过 RecvUserId 查询收红包账户
+		// memory 后端的事务不能嵌套 查询账户时不能开启新的事务
+		Convey("不传账户编号收红包", func() {
+			account := accounts[1]
+			receiveDTO := services.RedEnvelopeReceiveDTO{
+				EnvelopeNo:   activity.EnvelopeNo,
+				RecvUserId:   account.UserId,
+				RecvUsername: account.Username,
+			}
+			item, err := rs.Receive(context.Background(), receiveDTO)
+			So(err, ShouldBeNil)
+			So(item, ShouldNotBeNil)
+			So(item.AccountNo, ShouldEqual, account.AccountNo)
+
+			// 领域对象直接收红包 在收红包的事务中查询账户
+			item, err = new(goodsDomain).Receive(context.Background(), services.RedEnvelopeReceiveDTO{
+				EnvelopeNo:   activity.EnvelopeNo,
+				RecvUserId:   accounts[2].UserId,
+				RecvUsername: accounts[2].Username,
+			})
+			So(err, ShouldBeNil)
+			So(item, ShouldNotBeNil)
+		})
+
 		Convey("收碰运气红包", func() {
 			goods.EnvelopeType = int(services.LuckyEnvelopeType)
-			at, err := rs.SendOut(goods)
+			at, err := rs.SendOut(context.Background(), goods)
 			So(at, ShouldNotBeNil)
 			So(err, ShouldBeNil)
 			remainAmount = at.RemainAmount
@@ -100,7 +126,7 @@ func TestGoodsDomain_Receive(t *testing.T) {
 					RecvUserId:   account.UserId,
 					AccountNo:    account.AccountNo,
 				}
-				item, err := rs.Receive(receiveDTO)
+				item, err := rs.Receive(context.Background(), receiveDTO)
 				So(err, ShouldBeNil)
 				So(item, ShouldNotBeNil)
 				remainAmount = remainAmount.Sub(item.Amount)
@@ -1,6 +1,7 @@
 package envelopes
 
 import (
+	"context"
 	"testing"
 
 	"github.com/segmentio/ksuid"
@@ -24,7 +25,7 @@ func TestRedEnvelopeService_SendOut(t *testing.T) {
 	rs := services.GetRedEnvelopeService()
 
 	Convey("准备账户", t, func() {
-		account, err := as.CreateAccount(accountDTO)
+		account, err := as.CreateAccount(context.Background(), accountDTO)
 		So(err, ShouldBeNil)
 		So(account, ShouldNotBeNil)
 		So(account.Balance.String(), ShouldEqual, accountDTO.Amount)
@@ -42,7 +43,7 @@ func TestRedEnvelopeService_SendOut(t *testing.T) {
 			// 普通红包 用户输入单个红包金额 和 红包数量 数据库返回总金额
 			goodsDTO.Amount = decimal.NewFromFloat(8.88)
 			goodsDTO.Quantity = 10
-			activity, err := rs.SendOut(goodsDTO)
+			activity, err := rs.SendOut(context.Background(), goodsDTO)
 
 			So(err, ShouldBeNil)
 			So(activity, ShouldNotBeNil)
@@ -62,7 +63,7 @@ func TestRedEnvelopeService_SendOut(t *testing.T) {
 			// 碰运气红包 用户输入红包总金额 和 红包数量 数据库返回总金额
 			goodsDTO.Amount = decimal.NewFromFloat(88.8)
 			goodsDTO.Quantity = 10
-			activity, err := rs.SendOut(goodsDTO)
+			activity, err := rs.SendOut(context.Background(), goodsDTO)
 
 			So(activity, ShouldNotBeNil)
 			So(err, ShouldBeNil)
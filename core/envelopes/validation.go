@@ -0,0 +1,108 @@
+package envelopes
+
+import (
+	"strconv"
+
+	"github.com/go-playground/universal-translator"
+	"github.com/shopspring/decimal"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 红包金额校验规则
+const (
+	// 单个红包最小金额 0.01
+	tagMinAmountOne = "min_amount_one"
+	// 单个红包最大金额
+	tagMaxAmountOne = "max_amount_one"
+	// 单个红包活动的总金额上限
+	tagMaxAmount = "max_amount"
+	// 红包最大数量
+	tagMaxQuantity = "max_quantity"
+)
+
+// 单个红包最小金额 1分钱
+var minAmountOne = decimal.New(1, -2)
+
+func init() {
+	base.RegisterValidation(func(validate *validator.Validate, translator ut.Translator) {
+		validate.RegisterStructValidation(validateSendingAmount, services.RedEnvelopeSendingDTO{})
+		base.AddTranslation(validate, translator, tagMinAmountOne, "{0}平均到每个红包不能少于{1}元")
+		base.AddTranslation(validate, translator, tagMaxAmountOne, "{0}单个红包不能超过{1}元")
+		base.AddTranslation(validate, translator, tagMaxAmount, "{0}红包总金额不能超过{1}元")
+		base.AddTranslation(validate, translator, tagMaxQuantity, "{0}不能超过{1}个")
+	})
+}
+
+// 红包金额规则
+type amountLimits struct {
+	// 单个红包最大金额
+	maxAmountOne decimal.Decimal
+	// 单个红包活动总金额上限
+	maxAmount decimal.Decimal
+	// 红包最大数量
+	maxQuantity int
+}
+
+func getAmountLimits() amountLimits {
+	props := base.Props()
+	maxAmountOne, err := decimal.NewFromString(props.GetDefault("envelope.maxAmountOne", "200"))
+	if err != nil {
+		maxAmountOne = decimal.New(200, 0)
+	}
+	maxAmount, err := decimal.NewFromString(props.GetDefault("envelope.maxAmount", "200"))
+	if err != nil {
+		maxAmount = decimal.New(200, 0)
+	}
+	return amountLimits{
+		maxAmountOne: maxAmountOne,
+		maxAmount:    maxAmount,
+		maxQuantity:  props.GetIntDefault("envelope.maxQuantity", 100),
+	}
+}
+
+// 解析精确到分的金额 金额必须大于0
+func parseCentAmount(s string) (decimal.Decimal, bool) {
+	amount, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	if amount.Sign() <= 0 || !amount.Equal(amount.Truncate(2)) {
+		return decimal.Zero, false
+	}
+	return amount, true
+}
+
+// 发红包金额和数量的校验
+// 普通红包 Amount 是单个红包金额 总金额 = Amount * Quantity
+// 碰运气红包 口令红包 Amount 是总金额 单个红包金额由红包算法随机分配 平均每个红包至少0.01元
+func validateSendingAmount(sl validator.StructLevel) {
+	dto := sl.Current().Interface().(services.RedEnvelopeSendingDTO)
+	limits := getAmountLimits()
+	if dto.Quantity > limits.maxQuantity {
+		sl.ReportError(dto.Quantity, "quantity", "Quantity", tagMaxQuantity, strconv.Itoa(limits.maxQuantity))
+	}
+	// 金额格式错误由字段的 amount tag 报告
+	amount, ok := parseCentAmount(dto.Amount)
+	if !ok {
+		return
+	}
+	if dto.Quantity <= 0 {
+		return
+	}
+	quantity := decimal.New(int64(dto.Quantity), 0)
+	total := amount
+	if dto.EnvelopeType == int(services.GeneralEnvelopeType) {
+		if amount.GreaterThan(limits.maxAmountOne) {
+			sl.ReportError(dto.Amount, "amount", "Amount", tagMaxAmountOne, limits.maxAmountOne.String())
+		}
+		total = amount.Mul(quantity)
+	} else if total.LessThan(minAmountOne.Mul(quantity)) {
+		sl.ReportError(dto.Amount, "amount", "Amount", tagMinAmountOne, minAmountOne.String())
+	}
+	if total.GreaterThan(limits.maxAmount) {
+		sl.ReportError(dto.Amount, "amount", "Amount", tagMaxAmount, limits.maxAmount.String())
+	}
+}
@@ -0,0 +1,56 @@
+package envelopes
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+	_ "github.com/solozyx/red-envelope/textx"
+)
+
+func TestParseCentAmount(t *testing.T) {
+	Convey("精确到分的金额解析", t, func() {
+		for _, s := range []string{"1", "0.01", "8.88", "200.00", "1.500"} {
+			_, ok := parseCentAmount(s)
+			So(ok, ShouldBeTrue)
+		}
+		for _, s := range []string{"", "abc", "0", "-1", "0.001", "1.234"} {
+			_, ok := parseCentAmount(s)
+			So(ok, ShouldBeFalse)
+		}
+	})
+}
+
+func TestValidateSendingAmount(t *testing.T) {
+	dto := func(envelopeType services.EnvelopeType, amount string, quantity int) services.RedEnvelopeSendingDTO {
+		return services.RedEnvelopeSendingDTO{
+			EnvelopeType: int(envelopeType),
+			Username:     "测试用户",
+			UserId:       "测试用户编号",
+			Amount:       amount,
+			Quantity:     quantity,
+		}
+	}
+	Convey("发红包金额校验", t, func() {
+		So(base.ValidateStruct(dto(services.LuckyEnvelopeType, "88.8", 10)), ShouldBeNil)
+		So(base.ValidateStruct(dto(services.GeneralEnvelopeType, "8.88", 10)), ShouldBeNil)
+
+		// 平均每个红包不足0.01元
+		err := base.ValidateStruct(dto(services.LuckyEnvelopeType, "0.05", 10))
+		So(err, ShouldNotBeNil)
+		msg, ok := base.TranslateError(err)
+		So(ok, ShouldBeTrue)
+		So(msg, ShouldContainSubstring, "0.01")
+
+		// 金额超过2位小数
+		So(base.ValidateStruct(dto(services.LuckyEnvelopeType, "1.001", 1)), ShouldNotBeNil)
+		// 普通红包总金额超过上限
+		So(base.ValidateStruct(dto(services.GeneralEnvelopeType, "100", 3)), ShouldNotBeNil)
+		// 碰运气红包总金额超过上限
+		So(base.ValidateStruct(dto(services.LuckyEnvelopeType, "200.01", 10)), ShouldNotBeNil)
+		// 红包数量超过上限
+		So(base.ValidateStruct(dto(services.LuckyEnvelopeType, "100", 1000)), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,34 @@
+package groups
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+)
+
+type GroupDao struct {
+	runner *dbx.TxRunner
+}
+
+// 根据群编号查询群
+func (dao *GroupDao) GetOne(groupId string) *Group {
+	out := &Group{GroupId: groupId}
+	ok, err := dao.runner.GetOne(out)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return out
+}
+
+// 插入 返回群id
+func (dao *GroupDao) Insert(po *Group) (int64, error) {
+	rs, err := dao.runner.Insert(po)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.LastInsertId()
+}
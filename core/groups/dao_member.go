@@ -0,0 +1,76 @@
+package groups
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+type GroupMemberDao struct {
+	runner *dbx.TxRunner
+}
+
+// 查询群成员记录 包含已退群的记录
+func (dao *GroupMemberDao) GetOne(groupId, userId string) *GroupMember {
+	out := &GroupMember{}
+	sql := "select * from chat_group_member where group_id=? and user_id=?"
+	ok, err := dao.runner.Get(out, sql, groupId, userId)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return out
+}
+
+// 插入 返回成员记录id
+func (dao *GroupMemberDao) Insert(po *GroupMember) (int64, error) {
+	rs, err := dao.runner.Insert(po)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.LastInsertId()
+}
+
+// 更新成员状态 入群 退群
+func (dao *GroupMemberDao) UpdateStatus(groupId, userId string, status services.MemberStatus) (int64, error) {
+	sql := "update chat_group_member set status=? where group_id=? and user_id=?"
+	rs, err := dao.runner.Exec(sql, status, groupId, userId)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// 群内成员数量
+func (dao *GroupMemberDao) Count(groupId string) int {
+	var count int
+	sql := "select count(1) from chat_group_member where group_id=? and status=?"
+	err := dao.runner.QueryRow(sql, groupId, services.MemberJoined).Scan(&count)
+	if err != nil {
+		logrus.Error(err)
+		return 0
+	}
+	return count
+}
+
+// 用户所在的全部群编号
+func (dao *GroupMemberDao) FindGroupIds(userId string) []string {
+	var members []GroupMember
+	sql := "select * from chat_group_member where user_id=? and status=?"
+	err := dao.runner.Find(&members, sql, userId, services.MemberJoined)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	groupIds := make([]string, 0, len(members))
+	for _, m := range members {
+		groupIds = append(groupIds, m.GroupId)
+	}
+	return groupIds
+}
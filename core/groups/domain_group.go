@@ -0,0 +1,162 @@
+package groups
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 领域对象 有状态的 每次使用时都要实例化
+type groupDomain struct {
+	group Group
+}
+
+// 创建群 群和群主成员记录在同1个事务中写入
+func (domain *groupDomain) Create(dto services.GroupCreatedDTO) (*services.GroupDTO, error) {
+	domain.group = Group{}
+	domain.group.FromDTO(&dto)
+	domain.group.Status = services.GroupEnabled
+	owner := GroupMember{
+		GroupId:  dto.GroupId,
+		UserId:   dto.OwnerUserId,
+		Username: dto.OwnerUsername,
+		Status:   services.MemberJoined,
+	}
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		groupRepo := NewGroupRepository(tx)
+		memberRepo := NewGroupMemberRepository(tx)
+		if groupRepo.GetOne(dto.GroupId) != nil {
+			return ErrGroupExists.With("groupId", dto.GroupId)
+		}
+		id, err := groupRepo.Insert(&domain.group)
+		if err != nil {
+			return err
+		}
+		if id <= 0 {
+			return ErrSaveFailed.With("groupId", dto.GroupId)
+		}
+		id, err = memberRepo.Insert(&owner)
+		if err != nil {
+			return err
+		}
+		if id <= 0 {
+			return ErrSaveFailed.With("groupId", dto.GroupId).With("userId", dto.OwnerUserId)
+		}
+		domain.group = *groupRepo.GetOne(dto.GroupId)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rdto := domain.group.ToDTO()
+	rdto.MemberCount = 1
+	return rdto, nil
+}
+
+// 加入群 已退群的成员恢复为在群状态
+func (domain *groupDomain) Join(dto services.GroupMemberDTO) error {
+	return base.Transact(context.Background(), func(tx base.Transaction) error {
+		groupRepo := NewGroupRepository(tx)
+		memberRepo := NewGroupMemberRepository(tx)
+		group := groupRepo.GetOne(dto.GroupId)
+		if group == nil || group.Status != services.GroupEnabled {
+			return ErrGroupNotFound.With("groupId", dto.GroupId)
+		}
+		member := memberRepo.GetOne(dto.GroupId, dto.UserId)
+		if member != nil {
+			if member.Status == services.MemberJoined {
+				return nil
+			}
+			_, err := memberRepo.UpdateStatus(dto.GroupId, dto.UserId, services.MemberJoined)
+			return err
+		}
+		member = &GroupMember{}
+		member.FromDTO(&dto)
+		member.Status = services.MemberJoined
+		id, err := memberRepo.Insert(member)
+		if err != nil {
+			return err
+		}
+		if id <= 0 {
+			return ErrSaveFailed.With("groupId", dto.GroupId).With("userId", dto.UserId)
+		}
+		return nil
+	})
+}
+
+// 退出群
+func (domain *groupDomain) Leave(dto services.GroupMemberDTO) error {
+	return base.Transact(context.Background(), func(tx base.Transaction) error {
+		memberRepo := NewGroupMemberRepository(tx)
+		member := memberRepo.GetOne(dto.GroupId, dto.UserId)
+		if member == nil || member.Status != services.MemberJoined {
+			return ErrNotMember.With("groupId", dto.GroupId).With("userId", dto.UserId)
+		}
+		_, err := memberRepo.UpdateStatus(dto.GroupId, dto.UserId, services.MemberLeft)
+		return err
+	})
+}
+
+// 查询群信息
+func (domain *groupDomain) Get(groupId string) *services.GroupDTO {
+	var group *Group
+	var count int
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		group = NewGroupRepository(tx).GetOne(groupId)
+		count = NewGroupMemberRepository(tx).Count(groupId)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	if group == nil {
+		return nil
+	}
+	dto := group.ToDTO()
+	dto.MemberCount = count
+	return dto
+}
+
+// 是否是群成员
+func (domain *groupDomain) IsMember(groupId, userId string) (ok bool) {
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		member := NewGroupMemberRepository(tx).GetOne(groupId, userId)
+		ok = member != nil && member.Status == services.MemberJoined
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return false
+	}
+	return ok
+}
+
+// 群成员数量
+func (domain *groupDomain) CountMembers(groupId string) (count int) {
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		count = NewGroupMemberRepository(tx).Count(groupId)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return 0
+	}
+	return count
+}
+
+// 用户所在的全部群编号
+func (domain *groupDomain) ListGroupIds(userId string) (groupIds []string) {
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		groupIds = NewGroupMemberRepository(tx).FindGroupIds(userId)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	return groupIds
+}
@@ -0,0 +1,15 @@
+package groups
+
+import (
+	"github.com/solozyx/red-envelope/infra/errs"
+)
+
+// 聊天群的业务错误 消息key 以 group. 开头
+var (
+	ErrGroupNotFound = errs.New(errs.NotFound, "group.not_found", "群不存在或已解散")
+	ErrGroupExists   = errs.New(errs.AlreadyExists, "group.exists", "群已经存在")
+	ErrNotMember     = errs.New(errs.FailedPrecondition, "group.not_member", "用户不在该群中")
+
+	// 数据库写入没有生效 客户端只会看到通用描述
+	ErrSaveFailed = errs.New(errs.Internal, "group.save_failed", "群数据保存失败")
+)
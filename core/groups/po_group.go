@@ -0,0 +1,35 @@
+package groups
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 聊天群 映射 chat_group 表
+type Group struct {
+	Id          int64                `db:"id,omitempty"`
+	GroupId     string               `db:"group_id,unique"`
+	GroupName   string               `db:"group_name"`
+	OwnerUserId string               `db:"owner_user_id"`
+	Status      services.GroupStatus `db:"status"`
+	CreatedAt   time.Time            `db:"created_at,omitempty"`
+	UpdatedAt   time.Time            `db:"updated_at,omitempty"`
+}
+
+func (po *Group) FromDTO(dto *services.GroupCreatedDTO) {
+	po.GroupId = dto.GroupId
+	po.GroupName = dto.GroupName
+	po.OwnerUserId = dto.OwnerUserId
+}
+
+func (po *Group) ToDTO() *services.GroupDTO {
+	return &services.GroupDTO{
+		GroupId:     po.GroupId,
+		GroupName:   po.GroupName,
+		OwnerUserId: po.OwnerUserId,
+		Status:      po.Status,
+		CreatedAt:   po.CreatedAt,
+		UpdatedAt:   po.UpdatedAt,
+	}
+}
@@ -0,0 +1,25 @@
+package groups
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 聊天群成员 映射 chat_group_member 表
+// 退群不做物理删除 通过 status 标识 再次入群时恢复状态
+type GroupMember struct {
+	Id        int64                 `db:"id,omitempty"`
+	GroupId   string                `db:"group_id"`
+	UserId    string                `db:"user_id"`
+	Username  string                `db:"username"`
+	Status    services.MemberStatus `db:"status"`
+	CreatedAt time.Time             `db:"created_at,omitempty"`
+	UpdatedAt time.Time             `db:"updated_at,omitempty"`
+}
+
+func (po *GroupMember) FromDTO(dto *services.GroupMemberDTO) {
+	po.GroupId = dto.GroupId
+	po.UserId = dto.UserId
+	po.Username = dto.Username
+}
@@ -0,0 +1,51 @@
+package groups
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 仓储接口 领域层只依赖接口 不关心数据保存在 MySQL 还是内存数据库
+// 方法语义和 dbx 的 DAO 相同 更新方法返回受影响行数
+
+// 群仓储
+type GroupRepository interface {
+	GetOne(groupId string) *Group
+	Insert(po *Group) (int64, error)
+}
+
+// 群成员仓储
+type GroupMemberRepository interface {
+	GetOne(groupId, userId string) *GroupMember
+	Insert(po *GroupMember) (int64, error)
+	UpdateStatus(groupId, userId string, status services.MemberStatus) (int64, error)
+	Count(groupId string) int
+	FindGroupIds(userId string) []string
+}
+
+// 根据事务类型创建仓储 tx 来自 base.Transact
+func NewGroupRepository(tx base.Transaction) GroupRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &GroupDao{runner: t}
+	case *memdb.Tx:
+		return &memGroupRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewGroupMemberRepository(tx base.Transaction) GroupMemberRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &GroupMemberDao{runner: t}
+	case *memdb.Tx:
+		return &memGroupMemberRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
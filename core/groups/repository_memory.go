@@ -0,0 +1,122 @@
+package groups
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+// 内存数据库的仓储实现 表中保存持久化对象的值 返回副本
+// 唯一索引和 MySQL 的表结构 SQL 保持一致
+
+const (
+	tableGroup       = "chat_group"
+	tableGroupMember = "chat_group_member"
+)
+
+type memGroupRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memGroupRepository) GetOne(groupId string) *Group {
+	var out *Group
+	r.tx.Scan(tableGroup, func(id int64, row interface{}) bool {
+		g := row.(Group)
+		if g.GroupId == groupId {
+			out = &g
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+// group_id 是唯一索引
+func (r *memGroupRepository) Insert(po *Group) (int64, error) {
+	if r.GetOne(po.GroupId) != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *po
+	row.Id = r.tx.NextId(tableGroup)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableGroup, row.Id, row)
+	return row.Id, nil
+}
+
+type memGroupMemberRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memGroupMemberRepository) filter(match func(m *GroupMember) bool) []GroupMember {
+	var out []GroupMember
+	r.tx.Scan(tableGroupMember, func(id int64, row interface{}) bool {
+		m := row.(GroupMember)
+		if match(&m) {
+			out = append(out, m)
+		}
+		return true
+	})
+	return out
+}
+
+func (r *memGroupMemberRepository) find(groupId, userId string) (int64, *GroupMember) {
+	var id int64
+	var out *GroupMember
+	r.tx.Scan(tableGroupMember, func(rowId int64, row interface{}) bool {
+		m := row.(GroupMember)
+		if m.GroupId == groupId && m.UserId == userId {
+			id, out = rowId, &m
+			return false
+		}
+		return true
+	})
+	return id, out
+}
+
+func (r *memGroupMemberRepository) GetOne(groupId, userId string) *GroupMember {
+	_, m := r.find(groupId, userId)
+	return m
+}
+
+// (group_id, user_id) 是唯一索引
+func (r *memGroupMemberRepository) Insert(po *GroupMember) (int64, error) {
+	if r.GetOne(po.GroupId, po.UserId) != nil {
+		return 0, memdb.ErrDuplicate
+	}
+	row := *po
+	row.Id = r.tx.NextId(tableGroupMember)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableGroupMember, row.Id, row)
+	return row.Id, nil
+}
+
+func (r *memGroupMemberRepository) UpdateStatus(groupId, userId string, status services.MemberStatus) (int64, error) {
+	id, m := r.find(groupId, userId)
+	if m == nil {
+		return 0, nil
+	}
+	m.Status = status
+	m.UpdatedAt = time.Now()
+	r.tx.Put(tableGroupMember, id, *m)
+	return 1, nil
+}
+
+func (r *memGroupMemberRepository) Count(groupId string) int {
+	return len(r.filter(func(m *GroupMember) bool {
+		return m.GroupId == groupId && m.Status == services.MemberJoined
+	}))
+}
+
+func (r *memGroupMemberRepository) FindGroupIds(userId string) []string {
+	members := r.filter(func(m *GroupMember) bool {
+		return m.UserId == userId && m.Status == services.MemberJoined
+	})
+	groupIds := make([]string, 0, len(members))
+	for _, m := range members {
+		groupIds = append(groupIds, m.GroupId)
+	}
+	return groupIds
+}
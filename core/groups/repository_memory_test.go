@@ -0,0 +1,46 @@
+package groups
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+	"github.com/solozyx/red-envelope/services"
+)
+
+func TestMemGroupRepository(t *testing.T) {
+	Convey("内存群仓储", t, func() {
+		db := memdb.New()
+		err := db.Tx(func(tx *memdb.Tx) error {
+			groupRepo := NewGroupRepository(tx)
+			id, err := groupRepo.Insert(&Group{GroupId: "g1", OwnerUserId: "u1", Status: services.GroupEnabled})
+			So(err, ShouldBeNil)
+			So(id, ShouldEqual, 1)
+			_, err = groupRepo.Insert(&Group{GroupId: "g1"})
+			So(err, ShouldEqual, memdb.ErrDuplicate)
+			So(groupRepo.GetOne("g1").OwnerUserId, ShouldEqual, "u1")
+			So(groupRepo.GetOne("g2"), ShouldBeNil)
+
+			memberRepo := NewGroupMemberRepository(tx)
+			_, err = memberRepo.Insert(&GroupMember{GroupId: "g1", UserId: "u1", Status: services.MemberJoined})
+			So(err, ShouldBeNil)
+			_, err = memberRepo.Insert(&GroupMember{GroupId: "g1", UserId: "u2", Status: services.MemberJoined})
+			So(err, ShouldBeNil)
+			_, err = memberRepo.Insert(&GroupMember{GroupId: "g1", UserId: "u2"})
+			So(err, ShouldEqual, memdb.ErrDuplicate)
+			So(memberRepo.Count("g1"), ShouldEqual, 2)
+			So(memberRepo.FindGroupIds("u2"), ShouldResemble, []string{"g1"})
+
+			// 退群的成员不计数 记录保留
+			rows, err := memberRepo.UpdateStatus("g1", "u2", services.MemberLeft)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			So(memberRepo.Count("g1"), ShouldEqual, 1)
+			So(memberRepo.FindGroupIds("u2"), ShouldBeEmpty)
+			So(memberRepo.GetOne("g1", "u2").Status, ShouldEqual, services.MemberLeft)
+			return nil
+		})
+		So(err, ShouldBeNil)
+	})
+}
@@ -0,0 +1,67 @@
+package groups
+
+import (
+	"sync"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/services"
+)
+
+var _ services.GroupService = new(groupService)
+
+var once sync.Once
+
+func init() {
+	once.Do(func() {
+		services.IGroupService = new(groupService)
+	})
+}
+
+type groupService struct{}
+
+// 创建群
+func (s *groupService) CreateGroup(dto services.GroupCreatedDTO) (*services.GroupDTO, error) {
+	if err := base.ValidateStruct(&dto); err != nil {
+		return nil, err
+	}
+	domain := new(groupDomain)
+	return domain.Create(dto)
+}
+
+// 加入群
+func (s *groupService) Join(dto services.GroupMemberDTO) error {
+	if err := base.ValidateStruct(&dto); err != nil {
+		return err
+	}
+	domain := new(groupDomain)
+	return domain.Join(dto)
+}
+
+// 退出群
+func (s *groupService) Leave(dto services.GroupMemberDTO) error {
+	if err := base.ValidateStruct(&dto); err != nil {
+		return err
+	}
+	domain := new(groupDomain)
+	return domain.Leave(dto)
+}
+
+func (s *groupService) GetGroup(groupId string) *services.GroupDTO {
+	domain := new(groupDomain)
+	return domain.Get(groupId)
+}
+
+func (s *groupService) IsMember(groupId, userId string) bool {
+	domain := new(groupDomain)
+	return domain.IsMember(groupId, userId)
+}
+
+func (s *groupService) CountMembers(groupId string) int {
+	domain := new(groupDomain)
+	return domain.CountMembers(groupId)
+}
+
+func (s *groupService) ListGroupIds(userId string) []string {
+	domain := new(groupDomain)
+	return domain.ListGroupIds(userId)
+}
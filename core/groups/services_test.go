@@ -0,0 +1,59 @@
+package groups
+
+import (
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/services"
+	_ "github.com/solozyx/red-envelope/textx"
+)
+
+func TestGroupService_JoinAndLeave(t *testing.T) {
+	s := new(groupService)
+	dto := services.GroupCreatedDTO{
+		GroupId:       ksuid.New().Next().String(),
+		GroupName:     "测试群",
+		OwnerUserId:   ksuid.New().Next().String(),
+		OwnerUsername: "测试群主",
+	}
+	member := services.GroupMemberDTO{
+		GroupId:  dto.GroupId,
+		UserId:   ksuid.New().Next().String(),
+		Username: "测试群成员",
+	}
+
+	Convey("群成员加入和退出", t, func() {
+		group, err := s.CreateGroup(dto)
+		So(err, ShouldBeNil)
+		So(group, ShouldNotBeNil)
+		So(group.MemberCount, ShouldEqual, 1)
+		So(s.IsMember(dto.GroupId, dto.OwnerUserId), ShouldBeTrue)
+
+		// 重复创建
+		_, err = s.CreateGroup(dto)
+		So(err, ShouldNotBeNil)
+
+		// 加入
+		So(s.IsMember(dto.GroupId, member.UserId), ShouldBeFalse)
+		err = s.Join(member)
+		So(err, ShouldBeNil)
+		So(s.IsMember(dto.GroupId, member.UserId), ShouldBeTrue)
+		So(s.CountMembers(dto.GroupId), ShouldEqual, 2)
+		So(s.ListGroupIds(member.UserId), ShouldContain, dto.GroupId)
+
+		// 退出
+		err = s.Leave(member)
+		So(err, ShouldBeNil)
+		So(s.IsMember(dto.GroupId, member.UserId), ShouldBeFalse)
+		So(s.CountMembers(dto.GroupId), ShouldEqual, 1)
+		err = s.Leave(member)
+		So(err, ShouldNotBeNil)
+
+		// 再次加入
+		err = s.Join(member)
+		So(err, ShouldBeNil)
+		So(s.CountMembers(dto.GroupId), ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,23 @@
+package base
+
+import (
+	"github.com/kataras/iris"
+)
+
+// 管理接口校验请求头 X-Admin-Token 没有配置 admin.token 时禁止访问
+// 也可以使用 Authorization: Bearer <token> 方便 Prometheus 抓取 /metrics
+func AdminAuthHandler() iris.Handler {
+	token := Props().GetDefault("admin.token", "")
+	return func(ctx iris.Context) {
+		if token == "" || (ctx.GetHeader("X-Admin-Token") != token &&
+			ctx.GetHeader("Authorization") != "Bearer "+token) {
+			ctx.StatusCode(iris.StatusForbidden)
+			ctx.JSON(&Res{
+				Code:    ResCodeRequestParamsErr,
+				Message: "没有访问管理接口的权限",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -1,6 +1,11 @@
 package base
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
 	// MySQL驱动
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
@@ -17,6 +22,21 @@ func DbxDatabase() *dbx.Database {
 	return database
 }
 
+// 数据库连接池状态 主库名称是 primary 从库使用配置中的名称 memory 后端没有连接池
+func DatabaseStats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats)
+	if database == nil {
+		return stats
+	}
+	stats["primary"] = database.Stats()
+	if replicas != nil {
+		for _, r := range replicas.replicas {
+			stats[r.name] = r.db.Stats()
+		}
+	}
+	return stats
+}
+
 // dbx数据库starter 设置为全局
 type DbxDatabaseStarter struct {
 	// 继承 BaseStarter 免去实现一系列的 start方法
@@ -28,6 +48,26 @@ func (s *DbxDatabaseStarter) Setup(ctx infra.StarterContext) {
 	logrus.Info("DbxDatabaseStarter Setup()")
 	// 读取配置文件
 	conf := ctx.Props()
+	// 仓储后端 memory 后端不连接数据库
+	backend = conf.GetDefault("repository.backend", BackendMysql)
+	switch backend {
+	case BackendMysql:
+	case BackendSqlite:
+		db, err := OpenSqlite(conf)
+		if err != nil {
+			logrus.Panic(err)
+		}
+		// SQLite 只有1个数据库文件 不使用从库
+		database = &dbx.Database{DB: db}
+		logrus.Info("SQLite 数据库已打开: ", conf.GetDefault("sqlite.path", defaultSqlitePath))
+		return
+	case BackendMemory:
+		logrus.Warn("使用内存数据库 repository.backend=memory 数据不会持久化")
+		setupMemDatabase()
+		return
+	default:
+		logrus.Panic("不支持的仓储后端 repository.backend=", backend)
+	}
 	// 数据库配置
 	settings := dbx.Settings{}
 	// kvs.Unmarshal 把配置文件内容解析到结构体
@@ -36,6 +76,8 @@ func (s *DbxDatabaseStarter) Setup(ctx infra.StarterContext) {
 		// 数据库配置错误 是严重错误 禁止启动成功
 		panic(err)
 	}
+	// 启用链路追踪时使用包装后的驱动 每条 SQL 1个 span
+	settings.DriverName = tracedDriverName(conf, settings.DriverName)
 	logrus.Infof("%+v", settings)
 	// 记录连接字符串
 	logrus.Info("mysql.conn url:", settings.ShortDataSourceName())
@@ -47,4 +89,41 @@ func (s *DbxDatabaseStarter) Setup(ctx infra.StarterContext) {
 	}
 	logrus.Info(dbConn.Ping())
 	database = dbConn
+	// 从库 只读查询通过 ReadTx 在从库执行
+	replicas = newReplicaSet(conf)
+	if replicas != nil {
+		go replicas.watch(conf.GetDurationDefault("mysql.replica.checkInterval", 10*time.Second))
+	}
+}
+
+func (s *DbxDatabaseStarter) DependsOn() []string {
+	return []string{"props"}
+}
+
+// 健康检查 ping 主库 主库不可用时不是就绪状态
+// 从库全部不可用时读操作回退到主库 只报告为 DEGRADED 不影响就绪状态
+func (s *DbxDatabaseStarter) Check(ctx context.Context) error {
+	if database == nil {
+		return nil
+	}
+	if err := database.PingContext(ctx); err != nil {
+		return err
+	}
+	if replicas != nil && replicas.healthy() == 0 {
+		return infra.Degraded(errors.New("所有从库都不可用 读操作回退到主库"))
+	}
+	return nil
+}
+
+// 数据库最后关闭 其他starter停止时可能还需要访问数据库
+func (s *DbxDatabaseStarter) Stop(ctx infra.StarterContext) {
+	if replicas != nil {
+		replicas.close()
+	}
+	if database == nil {
+		return
+	}
+	if err := database.Close(); err != nil {
+		logrus.Error("dbx close error: ", err)
+	}
 }
@@ -3,9 +3,16 @@ package base
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 	"github.com/tietang/dbx"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const TX = "tx"
@@ -25,15 +32,152 @@ func Tx(fn TxFunc) error {
 	return TxContext(context.Background(), fn)
 }
 
+// MySQL 可以重试的错误
+const (
+	// 等待行锁超时 Lock wait timeout exceeded
+	mysqlErrLockWaitTimeout = 1205
+	// 死锁 Deadlock found when trying to get lock
+	mysqlErrDeadlock = 1213
+)
+
+// 保存点序号 保证同1个事务中的保存点名称不重复
+var savepointSeq int64
+
+// 事务配置 [mysql] tx.timeout tx.retries tx.retryBackoff
+type txSettings struct {
+	// ctx 没有截止时间时使用的事务超时时间 0表示不限制
+	timeout time.Duration
+	// 死锁和等待行锁超时的重试次数
+	retries int
+	// 重试间隔 第n次重试等待 n*retryBackoff
+	retryBackoff time.Duration
+}
+
+func getTxSettings() txSettings {
+	settings := txSettings{retries: 3, retryBackoff: 50 * time.Millisecond}
+	if props == nil {
+		return settings
+	}
+	settings.timeout = props.GetDurationDefault("mysql.tx.timeout", 0)
+	settings.retries = props.GetIntDefault("mysql.tx.retries", settings.retries)
+	settings.retryBackoff = props.GetDurationDefault("mysql.tx.retryBackoff", settings.retryBackoff)
+	return settings
+}
+
 // 事务执行
-func TxContext(ctx context.Context, fn TxFunc) error {
-	return DbxDatabase().Tx(fn)
+// 1.ctx 中已经有事务时 在该事务中创建保存点执行 fn 返回错误时只回滚到保存点 由外层事务提交或回滚
+// 2.否则使用 ctx 开启新的事务 ctx 取消或超过截止时间时事务回滚
+// 3.新开启的事务遇到死锁或等待行锁超时 整个事务重试 fn 可能被执行多次
+// ctx 中有 span 时新开启的事务创建子 span 包含所有重试
+// memory 后端没有 dbx 数据库 返回 ErrDbxUnsupported 需要使用 Transact
+func TxContext(ctx context.Context, fn TxFunc) (err error) {
+	if runner, ok := ctx.Value(TX).(*dbx.TxRunner); ok && runner != nil {
+		return savepoint(runner, fn)
+	}
+	if backend == BackendMemory || database == nil {
+		return ErrDbxUnsupported
+	}
+	settings := getTxSettings()
+	if _, ok := ctx.Deadline(); !ok && settings.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+		defer cancel()
+	}
+	ctx, span := startChildSpan(ctx, "db.transaction", attribute.String("db.system", backend))
+	attempts := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("db.transaction.attempts", attempts))
+		EndSpan(span, err)
+	}()
+	attempts, err = retryTx(ctx, settings, func() error {
+		return runTx(ctx, fn)
+	})
+	return err
+}
+
+// 执行事务 遇到可以重试的错误时重试 返回执行次数
+func retryTx(ctx context.Context, settings txSettings, run func() error) (attempt int, err error) {
+	for attempt = 1; ; attempt++ {
+		err = run()
+		if err == nil || attempt > settings.retries || !isRetryable(err) {
+			return attempt, err
+		}
+		logrus.Warnf("事务第%d次执行失败 准备重试: %s", attempt, err)
+		select {
+		case <-time.After(time.Duration(attempt) * settings.retryBackoff):
+		case <-ctx.Done():
+			return attempt, err
+		}
+	}
+}
+
+// 嵌套事务 MySQL 和 SQLite 的保存点语法相同
+// 外层事务遇到死锁时整个事务已经回滚 回滚到保存点会失败 错误返回给外层事务重试
+func savepoint(runner *dbx.TxRunner, fn TxFunc) (err error) {
+	name := fmt.Sprintf("sp_%d", atomic.AddInt64(&savepointSeq, 1))
+	if _, err = runner.Exec("savepoint " + name); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			runner.Exec("rollback to savepoint " + name)
+			panic(r)
+		}
+	}()
+	if err = fn(runner); err != nil {
+		if _, rerr := runner.Exec("rollback to savepoint " + name); rerr != nil {
+			logrus.Error("回滚到保存点失败: ", name, rerr)
+		}
+		return err
+	}
+	_, err = runner.Exec("release savepoint " + name)
+	return err
+}
+
+func runTx(ctx context.Context, fn TxFunc) error {
+	return runTxOn(ctx, DbxDatabase(), nil, fn)
+}
+
+func runTxOn(ctx context.Context, db *dbx.Database, opts *sql.TxOptions, fn TxFunc) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err = fn(dbx.NewTxRunner(tx)); err != nil {
+		if rerr := tx.Rollback(); rerr != nil && rerr != sql.ErrTxDone {
+			logrus.Error("事务回滚失败: ", rerr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// 死锁和等待行锁超时 MySQL 已经回滚了事务或语句 整个事务可以重试
+// SQLite 等待写锁超时 或读取的快照已经被其他事务修改 整个事务可以重试
+// 领域代码会把驱动错误包装为业务错误 通过 errors.As 判断原因
+func isRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
 }
 
 // 在context上下文对象中传递 *TxRunner对象
 // 实现跨方法执行事务 在不同方法使用同1个 *TxRunner事务对象 在同1个*TxRunner事务对象执行不同逻辑
 // 和 ExecuteContext 配合
-func WithValueContext(parent context.Context, runner *dbx.TxRunner) context.Context {
+// runner 也可以是 memory 后端的事务 参考 Transaction
+func WithValueContext(parent context.Context, runner Transaction) context.Context {
 	return context.WithValue(parent, TX, runner)
 }
 
@@ -0,0 +1,123 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tietang/dbx"
+)
+
+func TestIsRetryable(t *testing.T) {
+	Convey("死锁和等待行锁超时可以重试", t, func() {
+		So(isRetryable(&mysql.MySQLError{Number: mysqlErrDeadlock}), ShouldBeTrue)
+		So(isRetryable(&mysql.MySQLError{Number: mysqlErrLockWaitTimeout}), ShouldBeTrue)
+		// Duplicate entry
+		So(isRetryable(&mysql.MySQLError{Number: 1062}), ShouldBeFalse)
+		So(isRetryable(errors.New("余额不足")), ShouldBeFalse)
+		// 领域代码包装后的驱动错误
+		So(isRetryable(fmt.Errorf("收红包失败: %w", &mysql.MySQLError{Number: mysqlErrDeadlock})), ShouldBeTrue)
+	})
+}
+
+func TestRetryTx(t *testing.T) {
+	settings := txSettings{retries: 3, retryBackoff: time.Millisecond}
+	Convey("包装后的死锁错误整个事务重试", t, func() {
+		calls := 0
+		attempts, err := retryTx(context.Background(), settings, func() error {
+			calls++
+			if calls < 3 {
+				return fmt.Errorf("更新失败: %w", &mysql.MySQLError{Number: mysqlErrDeadlock})
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(attempts, ShouldEqual, 3)
+	})
+
+	Convey("超过重试次数或不能重试的错误直接返回", t, func() {
+		deadlock := &mysql.MySQLError{Number: mysqlErrDeadlock}
+		attempts, err := retryTx(context.Background(), settings, func() error {
+			return deadlock
+		})
+		So(err, ShouldEqual, deadlock)
+		So(attempts, ShouldEqual, settings.retries+1)
+
+		attempts, err = retryTx(context.Background(), settings, func() error {
+			return errors.New("余额不足")
+		})
+		So(err, ShouldNotBeNil)
+		So(attempts, ShouldEqual, 1)
+	})
+}
+
+func TestTxContextMemoryBackend(t *testing.T) {
+	Convey("memory 后端的 dbx 事务返回错误", t, func() {
+		old := backend
+		backend = BackendMemory
+		defer func() { backend = old }()
+		called := false
+		err := TxContext(context.Background(), func(runner *dbx.TxRunner) error {
+			called = true
+			return nil
+		})
+		So(err, ShouldEqual, ErrDbxUnsupported)
+		So(called, ShouldBeFalse)
+	})
+}
+
+func TestTxContextSavepoint(t *testing.T) {
+	Convey("嵌套事务回滚到保存点", t, func() {
+		db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "tx.db"))
+		So(err, ShouldBeNil)
+		defer db.Close()
+		_, err = db.Exec("create table t (id integer primary key)")
+		So(err, ShouldBeNil)
+
+		oldBackend, oldDatabase := backend, database
+		backend, database = BackendSqlite, &dbx.Database{DB: db}
+		defer func() { backend, database = oldBackend, oldDatabase }()
+
+		insert := func(id int) TxFunc {
+			return func(runner *dbx.TxRunner) error {
+				_, err := runner.Exec("insert into t(id) values(?)", id)
+				return err
+			}
+		}
+		nestedErr := errors.New("嵌套事务失败")
+		err = TxContext(context.Background(), func(runner *dbx.TxRunner) error {
+			ctx := WithValueContext(context.Background(), runner)
+			if err := insert(1)(runner); err != nil {
+				return err
+			}
+			// 失败的嵌套事务只回滚自己的修改
+			err := TxContext(ctx, func(runner *dbx.TxRunner) error {
+				if err := insert(2)(runner); err != nil {
+					return err
+				}
+				return nestedErr
+			})
+			So(err, ShouldEqual, nestedErr)
+			// 成功的嵌套事务由外层事务提交
+			return TxContext(ctx, insert(3))
+		})
+		So(err, ShouldBeNil)
+
+		var ids []int
+		rows, err := db.Query("select id from t order by id")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			So(rows.Scan(&id), ShouldBeNil)
+			ids = append(ids, id)
+		}
+		So(ids, ShouldResemble, []int{1, 3})
+	})
+}
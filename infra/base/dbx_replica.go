@@ -0,0 +1,193 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+	"github.com/tietang/props/kvs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 从库负载均衡策略
+const (
+	// 轮询
+	ReplicaRoundRobin = "round_robin"
+	// 随机
+	ReplicaRandom = "random"
+	// 使用中的连接数最少
+	ReplicaLeastConn = "least_conn"
+)
+
+// 读主库的标记
+const readPrimaryKey = "read_primary"
+
+// 从库集合 健康检查失败的从库不参与负载均衡
+var replicas *replicaSet
+
+type replica struct {
+	name    string
+	db      *dbx.Database
+	healthy int32
+}
+
+type replicaSet struct {
+	replicas []*replica
+	policy   string
+	next     uint64
+	stop     chan struct{}
+}
+
+// 读取从库配置 [mysql.replica] names 是从库名称列表 每个从库的配置和主库 [mysql] 相同
+//  [mysql.replica] names = r1,r2 policy = round_robin checkInterval = 10s
+//  [mysql.replica.r1] driverName host database user password ...
+func newReplicaSet(conf kvs.ConfigSource) *replicaSet {
+	names := conf.GetDefault("mysql.replica.names", "")
+	if strings.TrimSpace(names) == "" {
+		return nil
+	}
+	set := &replicaSet{
+		policy: conf.GetDefault("mysql.replica.policy", ReplicaRoundRobin),
+		stop:   make(chan struct{}),
+	}
+	switch set.policy {
+	case ReplicaRoundRobin, ReplicaRandom, ReplicaLeastConn:
+	default:
+		logrus.Panic("不支持的从库负载均衡策略 mysql.replica.policy=", set.policy)
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		settings := dbx.Settings{}
+		if err := kvs.Unmarshal(conf, &settings, "mysql.replica."+name); err != nil {
+			panic(err)
+		}
+		settings.DriverName = tracedDriverName(conf, settings.DriverName)
+		logrus.Infof("mysql.replica.%s conn url: %s", name, settings.ShortDataSourceName())
+		db, err := dbx.Open(settings)
+		if err != nil {
+			logrus.Panic("dbx.Open replica error:", name, err)
+		}
+		set.replicas = append(set.replicas, &replica{name: name, db: db})
+	}
+	set.check()
+	return set
+}
+
+// 选择1个健康的从库 没有健康的从库时返回 nil
+func (s *replicaSet) pick() *dbx.Database {
+	healthy := make([]*replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	switch s.policy {
+	case ReplicaRandom:
+		return healthy[rand.Intn(len(healthy))].db
+	case ReplicaLeastConn:
+		least := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.db.Stats().InUse < least.db.Stats().InUse {
+				least = r
+			}
+		}
+		return least.db
+	default:
+		n := atomic.AddUint64(&s.next, 1)
+		return healthy[n%uint64(len(healthy))].db
+	}
+}
+
+// ping 所有从库 ping 失败的从库移出负载均衡 恢复后重新加入
+func (s *replicaSet) check() {
+	for _, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := r.db.PingContext(ctx)
+		cancel()
+		var healthy int32 = 1
+		if err != nil {
+			healthy = 0
+		}
+		old := atomic.SwapInt32(&r.healthy, healthy)
+		if old != healthy {
+			if err != nil {
+				logrus.Errorf("从库%s不可用 移出负载均衡: %s", r.name, err)
+			} else {
+				logrus.Infof("从库%s可用 加入负载均衡", r.name)
+			}
+		}
+	}
+}
+
+func (s *replicaSet) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+func (s *replicaSet) close() {
+	close(s.stop)
+	for _, r := range s.replicas {
+		if err := r.db.Close(); err != nil {
+			logrus.Error("dbx close replica error: ", r.name, err)
+		}
+	}
+}
+
+// 健康的从库数量
+func (s *replicaSet) healthy() (n int) {
+	for _, r := range s.replicas {
+		n += int(atomic.LoadInt32(&r.healthy))
+	}
+	return n
+}
+
+// 标记 ctx 中的读操作在主库执行 用于读取同1个请求中刚写入的数据
+func WithPrimary(parent context.Context) context.Context {
+	return context.WithValue(parent, readPrimaryKey, true)
+}
+
+// 只读事务执行
+func ReadTx(fn TxFunc) error {
+	return ReadTxContext(context.Background(), fn)
+}
+
+// 只读事务执行 在从库执行 以下情况在主库执行
+// 1.ctx 中已经有事务 加入该事务
+// 2.ctx 被 WithPrimary 标记为读主库
+// 3.没有配置从库 或者所有从库都不可用
+func ReadTxContext(ctx context.Context, fn TxFunc) error {
+	if pinned, _ := ctx.Value(readPrimaryKey).(bool); pinned || replicas == nil {
+		return TxContext(ctx, fn)
+	}
+	if runner, ok := ctx.Value(TX).(*dbx.TxRunner); ok && runner != nil {
+		return fn(runner)
+	}
+	db := replicas.pick()
+	if db == nil {
+		return TxContext(ctx, fn)
+	}
+	ctx, span := startChildSpan(ctx, "db.transaction",
+		attribute.String("db.system", backend), attribute.Bool("db.replica", true))
+	err := runTxOn(ctx, db, &sql.TxOptions{ReadOnly: true}, fn)
+	EndSpan(span, err)
+	return err
+}
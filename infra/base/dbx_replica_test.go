@@ -0,0 +1,31 @@
+package base
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tietang/dbx"
+)
+
+func TestReplicaSet_Pick(t *testing.T) {
+	Convey("从库负载均衡", t, func() {
+		r1 := &replica{name: "r1", db: &dbx.Database{}, healthy: 1}
+		r2 := &replica{name: "r2", db: &dbx.Database{}, healthy: 1}
+		set := &replicaSet{replicas: []*replica{r1, r2}, policy: ReplicaRoundRobin}
+
+		Convey("轮询", func() {
+			So(set.pick(), ShouldNotEqual, set.pick())
+		})
+		Convey("不可用的从库移出负载均衡", func() {
+			r1.healthy = 0
+			for i := 0; i < 4; i++ {
+				So(set.pick(), ShouldEqual, r2.db)
+			}
+			So(set.healthy(), ShouldEqual, 1)
+		})
+		Convey("没有可用的从库", func() {
+			r1.healthy, r2.healthy = 0, 0
+			So(set.pick(), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package base
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tietang/props/kvs"
+)
+
+const defaultSqlitePath = "red_envelope.db"
+
+// 打开 SQLite 数据库 配置 [sqlite]
+//  path: 数据库文件路径
+//  busyTimeout: 等待写锁的时间 超时返回 SQLITE_BUSY 由事务重试
+//  maxOpenConns: 最大连接数
+// 使用 WAL 模式 读事务不阻塞写事务 写事务串行执行
+// 时间按本地时区保存和解析 和程序写入的时间参数可以直接比较
+func OpenSqlite(conf kvs.ConfigSource) (*sql.DB, error) {
+	path := conf.GetDefault("sqlite.path", defaultSqlitePath)
+	busyTimeout := conf.GetDurationDefault("sqlite.busyTimeout", 5*time.Second)
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d&_loc=auto",
+		path, int64(busyTimeout/time.Millisecond))
+	db, err := sql.Open(tracedDriverName(conf, "sqlite3"), dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(conf.GetIntDefault("sqlite.maxOpenConns", 8))
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// insert ignore 语句 唯一索引冲突时不插入
+func InsertIgnore() string {
+	if backend == BackendSqlite {
+		return "insert or ignore"
+	}
+	return "insert ignore"
+}
@@ -0,0 +1,264 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/tietang/props/kvs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 数据库驱动包装 每条 SQL 语句1个 span 只记录脱敏后的 SQL 不记录参数
+// dbx 的 TxRunner 执行 SQL 时没有传入 ctx 语句 span 的父 span 使用开启事务时的 ctx
+// 没有父 span 的语句不记录 比如事务之外执行的 SQL 和健康检查
+
+var (
+	tracedDriversMu sync.Mutex
+	// 原驱动名称 -> 包装后注册的驱动名称
+	tracedDrivers = map[string]string{}
+)
+
+// 包装了链路追踪的驱动名称 tracing.exporter=none 或不支持的驱动返回原驱动名称
+func tracedDriverName(conf kvs.ConfigSource, name string) string {
+	if conf.GetDefault("tracing.exporter", "none") == "none" {
+		return name
+	}
+	tracedDriversMu.Lock()
+	defer tracedDriversMu.Unlock()
+	if traced, ok := tracedDrivers[name]; ok {
+		return traced
+	}
+	var d driver.Driver
+	var system string
+	switch name {
+	case "mysql":
+		d, system = &mysql.MySQLDriver{}, BackendMysql
+	case "sqlite3":
+		d, system = &sqlite3.SQLiteDriver{}, BackendSqlite
+	default:
+		return name
+	}
+	traced := name + "-traced"
+	sql.Register(traced, &tracedDriver{Driver: d, system: system})
+	tracedDrivers[name] = traced
+	return traced
+}
+
+type tracedDriver struct {
+	driver.Driver
+	system string
+}
+
+func (d *tracedDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, system: d.system}, nil
+}
+
+// database/sql 保证同1个连接不会被并发使用
+type tracedConn struct {
+	driver.Conn
+	system string
+	// 开启事务时的 ctx 事务中执行的语句没有传入 ctx 时作为父 span
+	txCtx context.Context
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.txCtx = ctx
+	return &tracedTx{Tx: tx, conn: c}, nil
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// 驱动不支持或返回 driver.ErrSkip 时 database/sql 改为预编译语句执行 由 tracedStmt 记录
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.record(ctx, start, query, err)
+	}
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.record(ctx, start, query, err)
+	}
+	return rows, err
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// 参数类型转换使用原驱动的规则
+func (c *tracedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func (c *tracedConn) record(ctx context.Context, start time.Time, query string, err error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() && c.txCtx != nil {
+		ctx = c.txCtx
+	}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	statement := sanitizeSQL(query)
+	_, span := Tracer().Start(ctx, sqlOperation(statement),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			attribute.String("db.system", c.system),
+			attribute.String("db.statement", statement)))
+	EndSpan(span, err)
+}
+
+type tracedTx struct {
+	driver.Tx
+	conn *tracedConn
+}
+
+func (t *tracedTx) Commit() error {
+	t.conn.txCtx = nil
+	return t.Tx.Commit()
+}
+
+func (t *tracedTx) Rollback() error {
+	t.conn.txCtx = nil
+	return t.Tx.Rollback()
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	conn  *tracedConn
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (res driver.Result, err error) {
+	start := time.Now()
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.conn.record(ctx, start, s.query, err)
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	start := time.Now()
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	s.conn.record(ctx, start, s.query, err)
+	return rows, err
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("驱动不支持命名参数")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	sqlNumberLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlSpaces        = regexp.MustCompile(`\s+`)
+)
+
+// SQL 脱敏 字符串和数字常量替换为 ? 合并空白字符
+// DAO 使用占位符传参 常量只出现在少数语句中 也可能包含用户数据
+func sanitizeSQL(query string) string {
+	s := sqlStringLiteral.ReplaceAllString(query, "?")
+	s = sqlNumberLiteral.ReplaceAllString(s, "?")
+	return strings.TrimSpace(sqlSpaces.ReplaceAllString(s, " "))
+}
+
+// SQL 语句的操作 作为 span 名称 比如 SELECT UPDATE
+func sqlOperation(statement string) string {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
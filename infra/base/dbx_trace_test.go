@@ -0,0 +1,22 @@
+package base
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	Convey("SQL 脱敏", t, func() {
+		So(sanitizeSQL("select * from account\n  where user_id=? and account_type=?"),
+			ShouldEqual, "select * from account where user_id=? and account_type=?")
+		So(sanitizeSQL("update account set status=2, username='张三' where id = 10 and balance>=1.50"),
+			ShouldEqual, "update account set status=?, username=? where id = ? and balance>=?")
+		So(sanitizeSQL("insert into t(a) values('it''s', 'a\\'b')"), ShouldEqual, "insert into t(a) values(?, ?)")
+	})
+
+	Convey("SQL 操作", t, func() {
+		So(sqlOperation("select * from account"), ShouldEqual, "SELECT")
+		So(sqlOperation(""), ShouldEqual, "SQL")
+	})
+}
@@ -0,0 +1,89 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+)
+
+var rpcServer *rpc.Server
+
+func RpcServer() *rpc.Server {
+	Check(rpcServer)
+	return rpcServer
+}
+
+// 注册 RPC 服务 在 GoRPCStarter Init 之后 Start 之前调用
+func RpcRegister(ri interface{}) {
+	typ := reflect.TypeOf(ri)
+	logrus.Infof("goRPC Register: %s", typ.String())
+	if err := RpcServer().Register(ri); err != nil {
+		logrus.Panic("RPC 服务注册失败: ", err)
+	}
+}
+
+// net/rpc server starter 监听 app.rpc.port
+type GoRPCStarter struct {
+	infra.BaseStarter
+	listener net.Listener
+	// 是否在接收连接 1接收 0已停止
+	accepting int32
+}
+
+func (s *GoRPCStarter) DependsOn() []string {
+	return []string{"props"}
+}
+
+func (s *GoRPCStarter) Init(ctx infra.StarterContext) {
+	rpcServer = rpc.NewServer()
+}
+
+func (s *GoRPCStarter) Start(ctx infra.StarterContext) {
+	port := ctx.Props().GetDefault("app.rpc.port", "18082")
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		logrus.Panic("RPC 端口监听失败: ", err)
+	}
+	s.listener = listener
+	atomic.StoreInt32(&s.accepting, 1)
+	logrus.Info("tcp port listened for rpc: ", port)
+	go func() {
+		// 监听关闭后 Accept 返回
+		rpcServer.Accept(listener)
+		atomic.StoreInt32(&s.accepting, 0)
+	}()
+}
+
+// 停止接收新的连接 已经建立的连接由调用方关闭
+func (s *GoRPCStarter) Stop(ctx infra.StarterContext) {
+	if s.listener == nil {
+		return
+	}
+	if err := s.listener.Close(); err != nil {
+		logrus.Error("RPC 监听关闭失败: ", err)
+	}
+}
+
+// 健康检查 监听没有关闭并且可以建立连接
+func (s *GoRPCStarter) Check(ctx context.Context) error {
+	if atomic.LoadInt32(&s.accepting) == 0 {
+		return errors.New("RPC 端口没有在监听")
+	}
+	_, port, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
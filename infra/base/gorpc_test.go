@@ -0,0 +1,34 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tietang/props/kvs"
+
+	"github.com/solozyx/red-envelope/infra"
+)
+
+func TestGoRPCStarter_Check(t *testing.T) {
+	Convey("RPC 监听的健康检查", t, func() {
+		ctx := infra.StarterContext{}
+		ctx[infra.KeyProps] = kvs.NewMapConfigSource("test", map[string]string{"app.rpc.port": "0"})
+		s := &GoRPCStarter{}
+		s.Init(ctx)
+		So(s.Check(context.Background()), ShouldNotBeNil)
+
+		s.Start(ctx)
+		So(s.Check(context.Background()), ShouldBeNil)
+
+		s.Stop(ctx)
+		deadline := time.Now().Add(time.Second)
+		err := s.Check(context.Background())
+		for err == nil && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			err = s.Check(context.Background())
+		}
+		So(err, ShouldNotBeNil)
+	})
+}
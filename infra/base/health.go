@@ -0,0 +1,26 @@
+package base
+
+import (
+	"time"
+
+	"github.com/kataras/iris"
+
+	"github.com/solozyx/red-envelope/infra"
+)
+
+// 健康检查接口 供负载均衡和容器编排探测
+//  /health/live 存活检查 进程能响应请求就是存活的
+//  /health/ready 就绪检查 汇总所有 starter 的健康检查 启动完成前和停止过程中返回 503
+func registerHealthRoutes(app *iris.Application, boot *infra.BootApplication, timeout time.Duration) {
+	health := app.Party("/health")
+	health.Get("/live", func(ctx iris.Context) {
+		ctx.JSON(&infra.Health{Status: infra.HealthUp})
+	})
+	health.Get("/ready", func(ctx iris.Context) {
+		h := boot.Health(timeout)
+		if h.Status != infra.HealthUp {
+			ctx.StatusCode(iris.StatusServiceUnavailable)
+		}
+		ctx.JSON(&h)
+	})
+}
@@ -0,0 +1,37 @@
+package base
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+)
+
+// 监听系统信号 SIGINT SIGTERM 收到信号后停止应用
+type HookStarter struct {
+	infra.BaseStarter
+	signals chan os.Signal
+}
+
+func (s *HookStarter) Init(ctx infra.StarterContext) {
+	s.signals = make(chan os.Signal, 1)
+	signal.Notify(s.signals, syscall.SIGINT, syscall.SIGTERM)
+}
+
+func (s *HookStarter) Start(ctx infra.StarterContext) {
+	go func() {
+		sig, ok := <-s.signals
+		if !ok {
+			return
+		}
+		logrus.Info("收到信号 ", sig, " 开始停止应用")
+		ctx.Application().Stop()
+	}()
+}
+
+func (s *HookStarter) Stop(ctx infra.StarterContext) {
+	signal.Stop(s.signals)
+}
@@ -1,6 +1,7 @@
 package base
 
 import (
+	"context"
 	"time"
 
 	"github.com/kataras/iris"
@@ -33,6 +34,9 @@ func (i *IrisServerStarter) Init(ctx infra.StarterContext) {
 	irisLogger := irisApplication.Logger()
 	// Install方法扩展 golog 日志组件与logrus 统一 golog和logrus都实现了这套接口 所以可以适配
 	irisLogger.Install(logrus.StandardLogger())
+	// 健康检查接口 单个组件检查的超时时间 app.health.timeout
+	timeout := ctx.Props().GetDurationDefault("app.health.timeout", 2*time.Second)
+	registerHealthRoutes(irisApplication, ctx.Application(), timeout)
 }
 
 func (i *IrisServerStarter) Start(ctx infra.StarterContext) {
@@ -46,7 +50,28 @@ func (i *IrisServerStarter) Start(ctx infra.StarterContext) {
 	// 配置文件读取启动端口
 	port := ctx.Props().GetDefault("app.server.port", "18080")
 	// 监听所有网卡
-	Iris().Run(iris.Addr(":" + port))
+	// 关闭 iris 内置的中断信号处理 由 HookStarter 统一处理信号 按顺序停止所有starter
+	// 正常关闭时 Run 返回 iris.ErrServerClosed 不是错误
+	err := Iris().Run(iris.Addr(":"+port),
+		iris.WithoutInterruptHandler,
+		iris.WithoutServerError(iris.ErrServerClosed))
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+// 停止接收新的请求 等待正在处理的请求完成 最长等待 app.server.shutdownTimeout
+func (i *IrisServerStarter) Stop(ctx infra.StarterContext) {
+	timeout := ctx.Props().GetDurationDefault("app.server.shutdownTimeout", 10*time.Second)
+	c, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := Iris().Shutdown(c); err != nil {
+		logrus.Error("iris shutdown error: ", err)
+	}
+}
+
+func (i *IrisServerStarter) DependsOn() []string {
+	return []string{"props"}
 }
 
 // IrisServerStarter 是阻塞式的 需要实现阻塞接口
@@ -59,6 +84,8 @@ func initIris() *iris.Application {
 	// 主要中间件的配置 recover 日志输出中间件的自定义
 	// iris 内置 recover 中间件
 	app.Use(irisrecover.New())
+	// 请求ID 中间件 在日志中间件之前 访问日志输出请求ID
+	app.Use(requestIdHandler)
 	// iris 内置 日志中间件
 	cfg := logger.Config{
 		// 状态
@@ -70,9 +97,10 @@ func initIris() *iris.Application {
 		// http请求path
 		Path: true,
 		// http请求query参数
-		Query:              true,
-		Columns:            false,
-		MessageContextKeys: nil,
+		Query:   true,
+		Columns: false,
+		// 访问日志的消息输出请求ID
+		MessageContextKeys: []string{RequestIdField},
 		MessageHeaderKeys:  nil,
 		// 日志格式化输出函数
 		LogFunc: func(
@@ -1,11 +1,13 @@
 package base
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lestrrat-go/file-rotatelogs"
@@ -13,13 +15,96 @@ import (
 	log "github.com/sirupsen/logrus"
 	// 第三方 logrus 日志格式 兼容 logrus 格式
 	"github.com/x-cray/logrus-prefixed-formatter"
+
+	"github.com/solozyx/red-envelope/infra"
 )
 
+// 项目的包路径前缀 按包设置日志级别时包名相对于项目根目录
+const modulePath = "github.com/solozyx/red-envelope/"
+
 // 日志输出贯穿应用整个生命周期
+// 在 LogStarter 读取配置之前 使用控制台文本格式输出
 func init() {
-	// 定义日志格式
-	// logrus原生日志格式
-	// formatter := &log.TextFormatter{}
+	log.SetFormatter(newTextFormatter(true))
+	// 显示文件名代码行数
+	log.SetReportCaller(true)
+}
+
+// 日志 starter 读取配置文件 [log]
+//  dir file.name: 日志文件目录和名称 按 rotation.time 切割 保存 max.age
+//  format: text | json
+//  console: 是否同时输出到控制台
+//  level: 默认日志级别
+//  levels: 按包设置日志级别 比如 core/envelopes:debug,infra/scheduler:warn 最长匹配优先
+//  enableLineLog: 是否输出文件名代码行数 按包设置日志级别时总是开启
+type LogStarter struct {
+	infra.BaseStarter
+	file *rotatelogs.RotateLogs
+}
+
+func (s *LogStarter) Name() string {
+	return "log"
+}
+
+// 日志最先初始化 其他 starter 的日志按配置输出
+func (s *LogStarter) Priority() int {
+	return infra.HighestPriority
+}
+
+func (s *LogStarter) Init(ctx infra.StarterContext) {
+	conf := ctx.Props()
+	console := conf.GetBoolDefault("log.console", true)
+	format := conf.GetDefault("log.format", "text")
+	switch format {
+	case "text":
+		log.SetFormatter(newTextFormatter(console))
+	case "json":
+		log.SetFormatter(&levelFilterFormatter{
+			Formatter: &log.JSONFormatter{TimestampFormat: "2006-01-02 15:04:05.000000"},
+		})
+	default:
+		log.Panic("不支持的日志格式 log.format=", format)
+	}
+
+	root, err := log.ParseLevel(conf.GetDefault("log.level", "info"))
+	if err != nil {
+		log.Panic(err)
+	}
+	packages, err := parsePackageLevels(conf.GetDefault("log.levels", ""))
+	if err != nil {
+		log.Panic(err)
+	}
+	levels.set(root, packages)
+	log.SetReportCaller(conf.GetBoolDefault("log.enableLineLog", true) || len(packages) > 0)
+
+	writers := make([]io.Writer, 0, 2)
+	if dir := conf.GetDefault("log.dir", ""); dir != "" {
+		s.file = newFileWriter(dir,
+			conf.GetDefault("log.file.name", "resk"),
+			conf.GetDurationDefault("log.max.age", 24*time.Hour),
+			conf.GetDurationDefault("log.rotation.time", time.Hour))
+		if s.file != nil {
+			writers = append(writers, s.file)
+		}
+	}
+	if console || len(writers) == 0 {
+		writers = append(writers, os.Stdout)
+	}
+	log.SetOutput(io.MultiWriter(writers...))
+	log.Infof("日志 format=%s level=%s levels=%v", format, root, LogLevels())
+}
+
+func (s *LogStarter) Stop(ctx infra.StarterContext) {
+	if s.file == nil {
+		return
+	}
+	log.SetOutput(os.Stdout)
+	if err := s.file.Close(); err != nil {
+		log.Error("关闭日志文件失败: ", err)
+	}
+}
+
+func newTextFormatter(colors bool) log.Formatter {
 	// logrus 自定义日志格式
 	formatter := &prefixed.TextFormatter{}
 	// 开启时间戳 设置时间戳输出格式
@@ -27,9 +112,9 @@ func init() {
 	formatter.TimestampFormat = "2006-01-02 15:04:05.000000"
 	// prefixed 强制日志格式化
 	formatter.ForceFormatting = true
-	// 控制台高亮显示
-	formatter.ForceColors = true
-	formatter.DisableColors = false
+	// 控制台高亮显示 只输出到文件时不需要颜色
+	formatter.ForceColors = colors
+	formatter.DisableColors = !colors
 	// prefixed 自定义高亮显示颜色
 	formatter.SetColorScheme(&prefixed.ColorScheme{
 		InfoLevelStyle:  "green",
@@ -41,51 +126,156 @@ func init() {
 		PrefixStyle:     "cyan",
 		TimestampStyle:  "black+h",
 	})
-	log.SetFormatter(formatter)
-
-	// 日志级别 默认 info
-	// 通过系统环境变量控制日志级别
-	level := os.Getenv("log.debug")
-	if level == "true" {
-		log.SetLevel(log.DebugLevel)
-	}
-
-	// 显示文件名代码行数
-	log.SetReportCaller(true)
-
-	// 日志文件 和 滚动存储
-	logFileSettings()
-
-	// log.Info("测试")
-	// log.Debug("debug")
+	return &levelFilterFormatter{Formatter: formatter}
 }
 
 // logrus 默认没有日志文件输出功能 通过三方hook支持
 // github.com/lestrrat/go-file-rotatelogs
-func logFileSettings() {
-	// 配置日志输入目录
-	logPath, _ := filepath.Abs("./logs")
+func newFileWriter(dir, fileName string, maxAge, rotationTime time.Duration) *rotatelogs.RotateLogs {
+	logPath, _ := filepath.Abs(dir)
 	log.Infof("log dir: %s", logPath)
-	logFileName := "resk"
-	// 日志文件最大保存时间 24h
-	maxAge := 24 * time.Hour
-	// 日志切割时间间隔 1h
-	rotationTime := time.Hour * 1
-
 	os.MkdirAll(logPath, os.ModePerm)
 
-	baseLogPath := path.Join(logPath, logFileName)
+	baseLogPath := path.Join(logPath, fileName)
 	// 设置滚动日志输出
 	writer, err := rotatelogs.New(
 		strings.TrimSuffix(baseLogPath, ".log")+".%Y%m%d%H.log",
 		rotatelogs.WithLinkName(baseLogPath),      // 生成软链，指向最新日志文件
 		rotatelogs.WithMaxAge(maxAge),             // 文件最大保存时间
 		rotatelogs.WithRotationTime(rotationTime)) // 日志切割时间间隔
-
 	if err != nil {
 		log.Errorf("config local file system logger error = %+v", err)
+		return nil
+	}
+	return writer
+}
+
+// 解析按包设置的日志级别 core/envelopes:debug,infra/scheduler:warn
+func parsePackageLevels(s string) (map[string]log.Level, error) {
+	packages := make(map[string]log.Level)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("日志级别格式错误 应该是 包名:级别 %s", item)
+		}
+		level, err := log.ParseLevel(item[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		packages[strings.Trim(item[:i], "/")] = level
+	}
+	return packages, nil
+}
+
+// 日志级别 logrus 只有1个全局级别 全局级别设置为所有级别中最详细的
+// 每条日志按调用方所在的包再过滤1次
+type logLevels struct {
+	mu       sync.RWMutex
+	root     log.Level
+	packages map[string]log.Level
+}
+
+var levels = &logLevels{root: log.InfoLevel, packages: map[string]log.Level{}}
+
+func (l *logLevels) set(root log.Level, packages map[string]log.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.root = root
+	l.packages = packages
+	l.apply()
+}
+
+// 调用时持有写锁
+func (l *logLevels) apply() {
+	max := l.root
+	for _, level := range l.packages {
+		if level > max {
+			max = level
+		}
+	}
+	log.SetLevel(max)
+}
+
+// 包的日志级别 最长匹配优先 没有匹配时使用默认级别
+func (l *logLevels) levelOf(pkg string) log.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	level, matched := l.root, ""
+	for p, lv := range l.packages {
+		if (pkg == p || strings.HasPrefix(pkg, p+"/")) && len(p) > len(matched) {
+			level, matched = lv, p
+		}
+	}
+	return level
+}
+
+// 按调用方所在包的日志级别过滤 丢弃的日志格式化为空
+type levelFilterFormatter struct {
+	log.Formatter
+}
+
+func (f *levelFilterFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if entry.Level > levels.levelOf(callerPackage(entry)) {
+		return nil, nil
 	}
+	return f.Formatter.Format(entry)
+}
 
-	writers := io.MultiWriter(writer, os.Stdout)
-	log.SetOutput(writers)
+// 调用方所在的包 相对于项目根目录 比如 core/envelopes
+// 函数名 github.com/solozyx/red-envelope/core/envelopes.(*goodsDomain).Receive
+func callerPackage(entry *log.Entry) string {
+	if entry.Caller == nil {
+		return ""
+	}
+	fn := entry.Caller.Function
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		fn = fn[:slash+1+dot]
+	}
+	return strings.TrimPrefix(fn, modulePath)
+}
+
+// 运行时修改日志级别 pkg 为空时修改默认级别
+func SetLogLevel(pkg, level string) error {
+	lv, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	pkg = strings.Trim(pkg, "/")
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if pkg == "" {
+		levels.root = lv
+	} else {
+		levels.packages[pkg] = lv
+	}
+	levels.apply()
+	// 按包过滤需要调用方信息
+	if len(levels.packages) > 0 {
+		log.SetReportCaller(true)
+	}
+	return nil
+}
+
+// 恢复包的日志级别为默认级别
+func ResetLogLevel(pkg string) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	delete(levels.packages, strings.Trim(pkg, "/"))
+	levels.apply()
+}
+
+// 当前的日志级别 默认级别的包名为空
+func LogLevels() map[string]string {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	out := map[string]string{"": levels.root.String()}
+	for p, lv := range levels.packages {
+		out[p] = lv.String()
+	}
+	return out
 }
@@ -0,0 +1,49 @@
+package base
+
+import (
+	"runtime"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogLevels(t *testing.T) {
+	saved := levels
+	defer func() { levels = saved }()
+
+	Convey("按包设置日志级别", t, func() {
+		packages, err := parsePackageLevels(" core/envelopes:debug, infra/scheduler:warn ,core:error")
+		So(err, ShouldBeNil)
+		So(len(packages), ShouldEqual, 3)
+		_, err = parsePackageLevels("core/envelopes")
+		So(err, ShouldNotBeNil)
+		_, err = parsePackageLevels("core/envelopes:verbose")
+		So(err, ShouldNotBeNil)
+
+		levels = &logLevels{}
+		levels.set(log.InfoLevel, packages)
+		So(log.GetLevel(), ShouldEqual, log.DebugLevel)
+		So(levels.levelOf("core/envelopes"), ShouldEqual, log.DebugLevel)
+		So(levels.levelOf("core/accounts"), ShouldEqual, log.ErrorLevel)
+		So(levels.levelOf("core/envelopes/sub"), ShouldEqual, log.DebugLevel)
+		So(levels.levelOf("core/envelopesx"), ShouldEqual, log.ErrorLevel)
+		So(levels.levelOf("jobs"), ShouldEqual, log.InfoLevel)
+
+		So(SetLogLevel("jobs", "trace"), ShouldBeNil)
+		So(log.GetLevel(), ShouldEqual, log.TraceLevel)
+		ResetLogLevel("jobs")
+		So(log.GetLevel(), ShouldEqual, log.DebugLevel)
+		So(LogLevels()["infra/scheduler"], ShouldEqual, "warning")
+	})
+
+	Convey("调用方所在的包", t, func() {
+		entry := &log.Entry{Caller: &runtime.Frame{
+			Function: "github.com/solozyx/red-envelope/core/envelopes.(*goodsDomain).Receive",
+		}}
+		So(callerPackage(entry), ShouldEqual, "core/envelopes")
+		entry.Caller.Function = "github.com/solozyx/red-envelope/infra/base.init.0"
+		So(callerPackage(entry), ShouldEqual, "infra/base")
+		So(callerPackage(&log.Entry{}), ShouldEqual, "")
+	})
+}
@@ -33,6 +33,11 @@ func (p *PropsStarter) Init(ctx infra.StarterContext) {
 	fmt.Println("初始化配置。")
 }
 
+// 配置最先初始化
+func (p *PropsStarter) Priority() int {
+	return infra.HighestPriority
+}
+
 // 系统红包账户
 type SystemAccount struct {
 	AccountNo   string
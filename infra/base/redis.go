@@ -0,0 +1,24 @@
+package base
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/tietang/props/kvs"
+)
+
+// 根据配置 [redis] 创建 redis 连接池
+func NewRedisPool(conf kvs.ConfigSource) *redis.Pool {
+	maxIdle := conf.GetIntDefault("redis.maxIdle", 2)
+	maxActive := conf.GetIntDefault("redis.maxActive", 5)
+	idleTimeout := conf.GetDurationDefault("redis.idleTimeout", 20*time.Second)
+	addr := conf.GetDefault("redis.addr", "127.0.0.1:6379")
+	return &redis.Pool{
+		Dial: func() (conn redis.Conn, e error) {
+			return redis.Dial("tcp", addr)
+		},
+		MaxIdle:     maxIdle,
+		MaxActive:   maxActive,
+		IdleTimeout: idleTimeout,
+	}
+}
@@ -0,0 +1,116 @@
+package base
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+)
+
+// 仓储后端 配置 repository.backend
+const (
+	// MySQL 使用 dbx
+	BackendMysql = "mysql"
+	// SQLite 单文件数据库 使用 dbx 和 MySQL 共用 DAO 用于本地开发和 CI
+	BackendSqlite = "sqlite"
+	// 进程内的内存数据库 数据不持久化 用于没有数据库的测试环境
+	BackendMemory = "memory"
+)
+
+var (
+	backend     = BackendMysql
+	memDatabase *memdb.Database
+	memSeeds    []func(tx *memdb.Tx) error
+)
+
+// memory 后端没有 dbx 数据库 Tx TxContext 等 dbx 事务返回该错误
+// 业务代码需要使用 Transact 和仓储 才能在 memory 后端运行
+var ErrDbxUnsupported = errors.New("memory 后端不支持 dbx 事务 需要使用 mysql 或 sqlite 后端")
+
+func Backend() string {
+	return backend
+}
+
+func MemDatabase() *memdb.Database {
+	return memDatabase
+}
+
+// 注册内存数据库的初始数据 相当于 mysql 后端迁移脚本中的初始化数据
+// 在包的 init 函数中调用
+func RegisterMemorySeed(seed func(tx *memdb.Tx) error) {
+	memSeeds = append(memSeeds, seed)
+}
+
+// 切换到新建的内存数据库 返回恢复原后端的函数
+// 用于在 mysql 环境中测试 memory 后端的业务流程 不能和其他测试并发执行
+func UseMemoryBackend() (restore func()) {
+	oldBackend, oldDatabase := backend, memDatabase
+	backend = BackendMemory
+	setupMemDatabase()
+	return func() {
+		backend, memDatabase = oldBackend, oldDatabase
+	}
+}
+
+func setupMemDatabase() {
+	memDatabase = memdb.New()
+	err := memDatabase.Tx(func(tx *memdb.Tx) error {
+		for _, seed := range memSeeds {
+			if err := seed(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Panic("内存数据库初始化数据失败: ", err)
+	}
+}
+
+// 和后端无关的事务 mysql sqlite 后端是 *dbx.TxRunner memory 后端是 *memdb.Tx
+// 仓储的工厂方法根据事务的具体类型创建对应的仓储实现
+type Transaction interface{}
+
+type TransactionFunc func(tx Transaction) error
+
+// 在事务中执行 ctx 中已经有事务时加入该事务
+// mysql 后端的超时 重试等行为和 TxContext 相同
+func Transact(ctx context.Context, fn TransactionFunc) error {
+	if tx := ctx.Value(TX); tx != nil {
+		return fn(tx)
+	}
+	if backend == BackendMemory {
+		_, span := startChildSpan(ctx, "db.transaction", attribute.String("db.system", backend))
+		err := memDatabase.Tx(func(tx *memdb.Tx) error {
+			return fn(tx)
+		})
+		EndSpan(span, err)
+		return err
+	}
+	return TxContext(ctx, func(runner *dbx.TxRunner) error {
+		return fn(runner)
+	})
+}
+
+// 只读事务执行 mysql 后端和 ReadTxContext 相同 在从库执行
+func ReadTransact(ctx context.Context, fn TransactionFunc) error {
+	if backend == BackendMemory {
+		return Transact(ctx, fn)
+	}
+	return ReadTxContext(ctx, func(runner *dbx.TxRunner) error {
+		return fn(runner)
+	})
+}
+
+// 从 ctx 获取事务执行 和 WithValueContext 配合 没有真正开启事务
+func ExecuteTransaction(ctx context.Context, fn TransactionFunc) error {
+	tx := ctx.Value(TX)
+	if tx == nil {
+		logrus.Panic("是否在事务函数块中使用?")
+	}
+	return fn(tx)
+}
@@ -0,0 +1,96 @@
+package base
+
+import (
+	"context"
+
+	"github.com/kataras/iris"
+	"github.com/segmentio/ksuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// 请求ID的 http 请求头 调用方传入时沿用 否则生成新的 并在响应头中返回
+	RequestIdHeader = "X-Request-Id"
+	// 日志字段名 也是 iris Context Values 的 key 访问日志通过它输出请求ID
+	RequestIdField = "request_id"
+	// 链路追踪的 trace id 日志字段名
+	TraceIdField = "trace_id"
+	// 请求ID最大长度 和数据库 request_id 字段长度一致
+	maxRequestIdLength = 64
+)
+
+type requestIdKey struct{}
+
+// 生成新的请求ID
+func NewRequestId() string {
+	return ksuid.New().String()
+}
+
+// 在 ctx 中传递请求ID 服务 领域对象通过 ctx 获取
+func WithRequestId(parent context.Context, requestId string) context.Context {
+	return context.WithValue(parent, requestIdKey{}, requestId)
+}
+
+// ctx 中的请求ID 没有时返回空字符串
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// 带请求ID和 trace id 字段的日志 ctx 中都没有时和 logrus 标准日志相同
+func Logger(ctx context.Context) *logrus.Entry {
+	fields := logrus.Fields{}
+	if id := RequestId(ctx); id != "" {
+		fields[RequestIdField] = id
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields[TraceIdField] = sc.TraceID().String()
+	}
+	if len(fields) == 0 {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+	return logrus.WithFields(fields)
+}
+
+// iris 中间件 读取或生成请求ID 处理函数通过 RequestContext 获取后传给服务
+func requestIdHandler(ctx iris.Context) {
+	id := NormalizeRequestId(ctx.GetHeader(RequestIdHeader))
+	ctx.Header(RequestIdHeader, id)
+	ctx.Values().Set(RequestIdField, id)
+	ctx.Next()
+}
+
+// 调用方传入的请求ID 不合法或为空时生成新的
+func NormalizeRequestId(id string) string {
+	if validRequestId(id) {
+		return id
+	}
+	return NewRequestId()
+}
+
+// 只接受可见的 ASCII 字符 避免日志注入和超出字段长度
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// 处理函数传给服务的上下文 包含请求ID 和中间件通过 SetRequestContext 设置的 span
+// 不使用 http.Request 的 Context 客户端断开连接不应该中断正在执行的转账事务
+func RequestContext(ctx iris.Context) context.Context {
+	parent, ok := ctx.Values().Get(requestContextKey).(context.Context)
+	if !ok {
+		parent = context.Background()
+	}
+	return WithRequestId(parent, ctx.Values().GetString(RequestIdField))
+}
@@ -0,0 +1,31 @@
+package base
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestId(t *testing.T) {
+	Convey("在 ctx 中传递请求ID", t, func() {
+		So(RequestId(context.Background()), ShouldEqual, "")
+		So(Logger(context.Background()).Data, ShouldBeEmpty)
+
+		ctx := WithRequestId(context.Background(), "req-1")
+		So(RequestId(ctx), ShouldEqual, "req-1")
+		So(Logger(ctx).Data[RequestIdField], ShouldEqual, "req-1")
+		// 绑定事务后请求ID 仍然可以获取
+		So(RequestId(WithValueContext(ctx, nil)), ShouldEqual, "req-1")
+	})
+
+	Convey("调用方传入的请求ID", t, func() {
+		So(NormalizeRequestId("abc-123_XYZ"), ShouldEqual, "abc-123_XYZ")
+		So(NormalizeRequestId(""), ShouldNotBeEmpty)
+		So(NormalizeRequestId("a b"), ShouldNotEqual, "a b")
+		So(NormalizeRequestId("a\nb"), ShouldNotEqual, "a\nb")
+		long := strings.Repeat("a", maxRequestIdLength+1)
+		So(NormalizeRequestId(long), ShouldNotEqual, long)
+	})
+}
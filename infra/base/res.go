@@ -3,8 +3,10 @@ package base
 type ResCode int
 
 const (
-	ResCodeOk                ResCode = 1000
-	ResCodeValidationErr     ResCode = 2000
+	ResCodeOk            ResCode = 1000
+	ResCodeValidationErr ResCode = 2000
+	// 内容违规 敏感词 长度 表情等
+	ResCodeContentViolation  ResCode = 2010
 	ResCodeRequestParamsErr  ResCode = 2100
 	ResCodeInternalServerErr ResCode = 5000
 	// 业务异常
@@ -0,0 +1,52 @@
+package base
+
+import (
+	"context"
+
+	"github.com/kataras/iris"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 链路追踪 span 的 instrumentation 名称
+// TracingStarter 没有启用时 otel 全局的 TracerProvider 不记录任何 span
+const tracerName = "github.com/solozyx/red-envelope"
+
+// iris Context Values 中保存请求上下文的 key 参考 SetRequestContext
+const requestContextKey = "request_context"
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// 开始1个 span 和 EndSpan 配合使用
+//  ctx, span := base.StartSpan(ctx, "RedEnvelopeService.SendOut")
+//  defer func() { base.EndSpan(span, err) }()
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// 只在 ctx 中已经有 span 时开始子 span 否则返回不记录的 span
+// 用于事务这类到处都会调用的操作 避免定时任务轮询等产生大量单独的 trace
+func startChildSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return StartSpan(ctx, name, attrs...)
+}
+
+// 结束 span err 不为 nil 时记录错误
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// 中间件设置请求的上下文 比如包含 http 请求的 span RequestContext 在此基础上加入请求ID
+func SetRequestContext(ctx iris.Context, c context.Context) {
+	ctx.Values().Set(requestContextKey, c)
+}
@@ -1,6 +1,9 @@
 package base
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/locales/zh"
 	"github.com/go-playground/universal-translator"
 	"github.com/sirupsen/logrus"
@@ -17,6 +20,34 @@ var (
 	translator ut.Translator
 )
 
+// 业务模块自定义验证规则的注册函数 在验证器和翻译器创建后执行
+type ValidationRegister func(validate *validator.Validate, translator ut.Translator)
+
+var validationRegisters []ValidationRegister
+
+// 注册业务模块自定义验证规则 业务模块在 init 中调用
+func RegisterValidation(r ValidationRegister) {
+	validationRegisters = append(validationRegisters, r)
+}
+
+// 为自定义验证tag添加中文翻译 text 中 {0} 为字段名 {1} 为参数
+func AddTranslation(validate *validator.Validate, translator ut.Translator, tag, text string) {
+	err := validate.RegisterTranslation(tag, translator,
+		func(ut ut.Translator) error {
+			return ut.Add(tag, text, true)
+		},
+		func(ut ut.Translator, fe validator.FieldError) string {
+			t, err := ut.T(tag, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.(error).Error()
+			}
+			return t
+		})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
 func Validate() *validator.Validate {
 	return validate
 }
@@ -34,6 +65,8 @@ type ValidatorStarter struct {
 func (v *ValidatorStarter) Init(ctx infra.StarterContext) {
 	logrus.Info("ValidatorStarter Init()")
 	validate = validator.New()
+	// 字段名使用 json tag 错误信息和客户端提交的字段名一致
+	validate.RegisterTagNameFunc(jsonFieldName)
 	// 创建消息国际化通用翻译器
 	cn := zh.New()
 	uni := ut.New(cn, cn)
@@ -47,20 +80,102 @@ func (v *ValidatorStarter) Init(ctx infra.StarterContext) {
 	} else {
 		logrus.Error("Not found translator: zh")
 	}
+	registerDefaultValidations(validate, translator)
+	for _, r := range validationRegisters {
+		r(validate, translator)
+	}
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// 单个字段的验证错误 web 接口在 Res.Data 中返回给客户端
+type FieldError struct {
+	// 字段的 json 路径 嵌套字段用.连接 比如 tradeBody.accountNo
+	Field string `json:"field"`
+	// 没有通过的验证tag 比如 required amount
+	Tag string `json:"tag"`
+	// 验证tag 的参数 比如 amount=2 的 2
+	Param string `json:"param,omitempty"`
+	// 翻译后的错误描述
+	Message string `json:"message"`
+}
+
+// ValidateStruct 返回的参数验证错误 包含每个没有通过验证的字段
+type ValidationErrors []FieldError
+
+// 多个字段错误用分号连接
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Message)
+	}
+	return strings.Join(msgs, ";")
+}
+
+func newValidationErrors(errs validator.ValidationErrors) ValidationErrors {
+	fields := make(ValidationErrors, 0, len(errs))
+	for _, e := range errs {
+		// Namespace 以结构体类型名开头 比如 AccountTransferDTO.tradeBody.accountNo
+		field := e.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		fields = append(fields, FieldError{
+			Field:   field,
+			Tag:     e.Tag(),
+			Param:   e.Param(),
+			Message: e.Translate(Translate()),
+		})
+	}
+	return fields
+}
+
+// 是否是参数验证错误
+func AsValidationErrors(err error) (ValidationErrors, bool) {
+	switch e := err.(type) {
+	case ValidationErrors:
+		return e, true
+	case validator.ValidationErrors:
+		return newValidationErrors(e), true
+	}
+	return nil, false
+}
+
+// 把字段验证错误翻译为中文描述 多个字段错误用分号连接
+// 不是字段验证错误时返回 false
+func TranslateError(err error) (string, bool) {
+	errs, ok := AsValidationErrors(err)
+	if !ok {
+		return "", false
+	}
+	return errs.Error(), true
 }
 
+// 验证结构体 字段验证没有通过时返回 ValidationErrors
 func ValidateStruct(s interface{}) error {
 	err := Validate().Struct(s)
 	if err != nil {
 		_, ok := err.(*validator.InvalidValidationError)
 		if ok {
 			logrus.Error("用户输入参数验证错误", err)
+			return err
 		}
 		errs, ok := err.(validator.ValidationErrors)
 		if ok {
-			for _, e := range errs {
-				logrus.Error(e.Translate(Translate()))
+			fields := newValidationErrors(errs)
+			for _, e := range fields {
+				logrus.Error(e.Message)
 			}
+			return fields
 		}
 		return err
 	}
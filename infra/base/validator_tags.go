@@ -0,0 +1,95 @@
+package base
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/go-playground/universal-translator"
+	"github.com/segmentio/ksuid"
+	"github.com/shopspring/decimal"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// 通用的自定义验证tag 金额和编号在 DTO 中都是字符串
+//  amount=N: 大于0且最多N位小数的金额
+//  decimal=N: 不小于0且最多N位小数的金额 比如账户初始余额
+//  currency: 货币代码 3位大写字母 比如 CNY
+//  ksuid: ksuid 格式的编号 比如红包编号
+//  account_no: 账户编号 ksuid 格式或配置的系统账户编号
+const (
+	tagAmount    = "amount"
+	tagDecimal   = "decimal"
+	tagCurrency  = "currency"
+	tagKsuid     = "ksuid"
+	tagAccountNo = "account_no"
+)
+
+var currencyCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func registerDefaultValidations(validate *validator.Validate, translator ut.Translator) {
+	validations := []struct {
+		tag  string
+		fn   validator.Func
+		text string
+	}{
+		{tagAmount, isAmount, "{0}必须是大于0且最多{1}位小数的金额"},
+		{tagDecimal, isDecimal, "{0}必须是不小于0且最多{1}位小数的金额"},
+		{tagCurrency, isCurrencyCode, "{0}必须是3位大写字母的货币代码"},
+		{tagKsuid, isKsuid, "{0}格式不正确"},
+		{tagAccountNo, isAccountNo, "{0}不是有效的账户编号"},
+	}
+	for _, v := range validations {
+		if err := validate.RegisterValidation(v.tag, v.fn); err != nil {
+			panic(err)
+		}
+		if translator != nil {
+			AddTranslation(validate, translator, v.tag, v.text)
+		}
+	}
+}
+
+// 解析金额字符串 小数位数不能超过 tag 参数 末尾的0不计入小数位数
+func parseDecimalField(fl validator.FieldLevel) (decimal.Decimal, bool) {
+	if fl.Field().Kind() != reflect.String {
+		return decimal.Zero, false
+	}
+	places, err := strconv.Atoi(fl.Param())
+	if err != nil || places < 0 {
+		// 和 validator 内置 tag 一致 参数错误是编码错误
+		panic("验证tag " + fl.GetTag() + " 的参数必须是小数位数: " + fl.Param())
+	}
+	d, err := decimal.NewFromString(fl.Field().String())
+	if err != nil || !d.Equal(d.Truncate(int32(places))) {
+		return decimal.Zero, false
+	}
+	return d, true
+}
+
+func isAmount(fl validator.FieldLevel) bool {
+	d, ok := parseDecimalField(fl)
+	return ok && d.Sign() > 0
+}
+
+func isDecimal(fl validator.FieldLevel) bool {
+	d, ok := parseDecimalField(fl)
+	return ok && d.Sign() >= 0
+}
+
+func isCurrencyCode(fl validator.FieldLevel) bool {
+	return currencyCodeRegexp.MatchString(fl.Field().String())
+}
+
+func isKsuid(fl validator.FieldLevel) bool {
+	_, err := ksuid.Parse(fl.Field().String())
+	return err == nil
+}
+
+// 系统账户编号由配置指定 不是 ksuid 格式
+func isAccountNo(fl validator.FieldLevel) bool {
+	if isKsuid(fl) {
+		return true
+	}
+	accountNo := fl.Field().String()
+	return accountNo != "" && accountNo == GetSystemAccount().AccountNo
+}
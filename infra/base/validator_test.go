@@ -0,0 +1,82 @@
+package base
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/ksuid"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type validationDTO struct {
+	Amount       string           `json:"amount" validate:"required,amount=2"`
+	Balance      string           `json:"balance" validate:"omitempty,decimal=2"`
+	CurrencyCode string           `json:"currencyCode" validate:"omitempty,currency"`
+	EnvelopeNo   string           `json:"envelopeNo" validate:"omitempty,ksuid"`
+	Target       validationTarget `json:"target"`
+}
+
+type validationTarget struct {
+	AccountNo string `json:"accountNo" validate:"required,account_no"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	new(ValidatorStarter).Init(nil)
+	valid := func() validationDTO {
+		return validationDTO{
+			Amount:       "8.8",
+			Balance:      "0",
+			CurrencyCode: "CNY",
+			EnvelopeNo:   ksuid.New().String(),
+			Target:       validationTarget{AccountNo: ksuid.New().String()},
+		}
+	}
+
+	Convey("自定义验证tag", t, func() {
+		So(ValidateStruct(valid()), ShouldBeNil)
+
+		for _, amount := range []string{"0", "-1", "1.001", "abc", "1e"} {
+			dto := valid()
+			dto.Amount = amount
+			So(ValidateStruct(dto), ShouldNotBeNil)
+		}
+		dto := valid()
+		dto.Amount = "1.500"
+		So(ValidateStruct(dto), ShouldBeNil)
+
+		dto = valid()
+		dto.Balance = "-0.01"
+		So(ValidateStruct(dto), ShouldNotBeNil)
+
+		dto = valid()
+		dto.CurrencyCode = "cny"
+		So(ValidateStruct(dto), ShouldNotBeNil)
+
+		dto = valid()
+		dto.EnvelopeNo = "not-a-ksuid"
+		So(ValidateStruct(dto), ShouldNotBeNil)
+	})
+
+	Convey("返回字段级别的验证错误", t, func() {
+		dto := valid()
+		dto.Amount = "1.001"
+		dto.Target.AccountNo = ""
+		err := ValidateStruct(dto)
+		errs, ok := err.(ValidationErrors)
+		So(ok, ShouldBeTrue)
+		So(errs, ShouldHaveLength, 2)
+		So(errs[0].Field, ShouldEqual, "amount")
+		So(errs[0].Tag, ShouldEqual, "amount")
+		So(errs[0].Param, ShouldEqual, "2")
+		So(errs[0].Message, ShouldContainSubstring, "2位小数")
+		So(errs[1].Field, ShouldEqual, "target.accountNo")
+		So(errs[1].Tag, ShouldEqual, "required")
+
+		msg, ok := TranslateError(err)
+		So(ok, ShouldBeTrue)
+		So(msg, ShouldEqual, errs[0].Message+";"+errs[1].Message)
+
+		_, ok = AsValidationErrors(errors.New("其他错误"))
+		So(ok, ShouldBeFalse)
+	})
+}
@@ -2,6 +2,9 @@ package infra
 
 import (
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/tietang/props/kvs"
@@ -11,6 +14,11 @@ import (
 type BootApplication struct {
 	conf           kvs.ConfigSource
 	starterContext StarterContext
+	stopOnce       sync.Once
+	// 是否就绪 1就绪 0没有就绪
+	ready int32
+	// 启动计划 按启动顺序排列的已启用的 starter
+	starters []Starter
 }
 
 func New(conf kvs.ConfigSource) *BootApplication {
@@ -19,10 +27,13 @@ func New(conf kvs.ConfigSource) *BootApplication {
 		starterContext: StarterContext{},
 	}
 	b.starterContext[KeyProps] = conf
+	b.starterContext[KeyApplication] = b
 	return b
 }
 
 func (b *BootApplication) Start() {
+	// 0.计算启动计划
+	b.starters = StarterRegister.Plan(b.conf)
 	// 1.初始化所有starter
 	b.init()
 	// 2.安装所有starter
@@ -32,42 +43,95 @@ func (b *BootApplication) Start() {
 }
 
 func (b *BootApplication) init() {
-	for _, starter := range GetStarters() {
+	for _, starter := range b.starters {
 		starter.Init(b.starterContext)
 	}
 }
 
 func (b *BootApplication) setup() {
-	for _, starter := range GetStarters() {
+	for _, starter := range b.starters {
 		starter.Setup(b.starterContext)
 	}
 }
 
 func (b *BootApplication) start() {
 	log.Info("Starting starters...")
-	for i, starter := range GetStarters() {
+	// 最后1个可阻塞的 starter 在其他 starter 都启动后直接启动并阻塞
+	var last Starter
+	for _, starter := range b.starters {
+		if starter.StartBlocking() {
+			last = starter
+		}
+	}
+	for _, starter := range b.starters {
 		typ := reflect.TypeOf(starter)
 		log.Debug("Starting: ", typ.String())
+		if starter == last {
+			continue
+		}
 		if starter.StartBlocking() {
-			// 如果是最后一个可阻塞的 直接启动并阻塞
-			if i+1 == len(GetStarters()) {
-				starter.Start(b.starterContext)
-			} else {
-				// 如果不是 使用goroutine来异步启动 防止阻塞后面的starter
-				go starter.Start(b.starterContext)
-			}
+			// 其他可阻塞的 使用goroutine来异步启动 防止阻塞后面的starter
+			go starter.Start(b.starterContext)
 		} else {
 			// 非阻塞直接运行
 			starter.Start(b.starterContext)
 		}
 	}
+	// 最后1个可阻塞的 starter 通常是 web server 启动后就可以接收请求
+	atomic.StoreInt32(&b.ready, 1)
+	log.Info("Application ready")
+	if last != nil {
+		last.Start(b.starterContext)
+	}
 }
 
+// 停止应用 多次调用只执行1次 并发调用时等待第1次调用执行完成
+// 1.执行 pre-stop 钩子
+// 2.按启动计划的相反顺序停止所有starter 最先停止 web server 最后关闭数据库
+// 3.执行 post-stop 钩子
+// 所有starter停止的总时间不超过 app.shutdown.timeout 超时的starter不再等待
 func (b *BootApplication) Stop() {
+	b.stopOnce.Do(b.stop)
+}
+
+func (b *BootApplication) stop() {
 	log.Info("Stoping starters...")
-	for _, v := range GetStarters() {
-		typ := reflect.TypeOf(v)
+	// 停止前先标记为没有就绪 负载均衡不再转发新的请求
+	atomic.StoreInt32(&b.ready, 0)
+	// 负载均衡探测到没有就绪需要一段时间 等待 app.shutdown.drainDelay 后再停止 web server
+	// 等待期间仍然正常处理请求
+	if delay := b.conf.GetDurationDefault("app.shutdown.drainDelay", 0); delay > 0 {
+		log.Infof("等待 %s 负载均衡摘除流量", delay)
+		time.Sleep(delay)
+	}
+	timeout := b.conf.GetDurationDefault("app.shutdown.timeout", 30*time.Second)
+	deadline := time.Now().Add(timeout)
+	runHooks("pre-stop", preStopHooks, b.starterContext)
+	starters := b.starters
+	for i := len(starters) - 1; i >= 0; i-- {
+		starter := starters[i]
+		typ := reflect.TypeOf(starter)
 		log.Debug("Stoping: ", typ.String())
-		v.Stop(b.starterContext)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("Stop %s panic: %v", typ.String(), r)
+				}
+			}()
+			starter.Stop(b.starterContext)
+		}()
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		select {
+		case <-done:
+		case <-time.After(remaining):
+			log.Errorf("Stop %s 超时 不再等待", typ.String())
+		}
 	}
+	runHooks("post-stop", postStopHooks, b.starterContext)
+	log.Info("Stopped")
 }
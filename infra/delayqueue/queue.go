@@ -0,0 +1,89 @@
+package delayqueue
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/props/kvs"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 到期处理函数 参数是到期的 key
+type Handler func(key string)
+
+// 延时队列 key 在到期时间之后交给 Handler 处理
+// 同1个 key 重复添加时更新到期时间
+type Queue interface {
+	// 添加或更新 key 的到期时间 已经到期的 key 会尽快被处理
+	Add(key string, due time.Time) error
+	// 移除 key
+	Remove(key string) error
+	// 开始投递到期的 key
+	Start(handler Handler)
+	// 停止投递 等待正在处理的 key 处理完成
+	Stop()
+}
+
+// 根据配置 delayqueue.provider 创建延时队列
+//  memory: 进程内的分层时间轮 重启后需要从数据库重新加载
+//  redis: redis 有序集合 集群部署时所有节点共享1个队列
+func New(name string, conf kvs.ConfigSource) Queue {
+	workers := conf.GetIntDefault("delayqueue.workers", 4)
+	switch provider := conf.GetDefault("delayqueue.provider", "memory"); provider {
+	case "memory":
+		tick := conf.GetDurationDefault("delayqueue.tick", time.Second)
+		size := conf.GetIntDefault("delayqueue.wheelSize", 60)
+		return NewTimingWheel(tick, size, workers)
+	case "redis":
+		interval := conf.GetDurationDefault("delayqueue.pollInterval", time.Second)
+		return NewRedisQueue(base.NewRedisPool(conf), "delayqueue:"+name, interval, workers)
+	default:
+		logrus.Panic("不支持的延时队列 delayqueue.provider=", provider)
+	}
+	return nil
+}
+
+// 把到期的 key 交给固定数量的 worker 处理
+type dispatcher struct {
+	ch chan string
+	wg sync.WaitGroup
+}
+
+func newDispatcher(workers int, handler Handler) *dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	d := &dispatcher{ch: make(chan string, workers*16)}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for key := range d.ch {
+				handle(handler, key)
+			}
+		}()
+	}
+	return d
+}
+
+func (d *dispatcher) dispatch(key string) {
+	d.ch <- key
+}
+
+// 停止接收 等待已经投递的 key 处理完成
+func (d *dispatcher) close() {
+	close(d.ch)
+	d.wg.Wait()
+}
+
+func handle(handler Handler, key string) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("延时队列处理 %s panic: %v\n%s", key, r, debug.Stack())
+		}
+	}()
+	handler(key)
+}
@@ -0,0 +1,115 @@
+package delayqueue
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+// 每次最多取出的到期 key 数量
+const redisPollBatch = 100
+
+// 基于 redis 有序集合的延时队列 score 是到期时间的毫秒数
+// 集群部署时所有节点共享1个有序集合 ZREM 成功的节点负责处理该 key 1个 key 只会被1个节点处理
+// 节点在 ZREM 之后处理之前宕机 该 key 会丢失 由定时任务的兜底扫描处理
+type RedisQueue struct {
+	pool     *redis.Pool
+	key      string
+	interval time.Duration
+	workers  int
+
+	dispatcher *dispatcher
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func NewRedisQueue(pool *redis.Pool, key string, interval time.Duration, workers int) *RedisQueue {
+	return &RedisQueue{
+		pool:     pool,
+		key:      key,
+		interval: interval,
+		workers:  workers,
+	}
+}
+
+func (q *RedisQueue) Check(ctx context.Context) error {
+	conn := q.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("PING")
+	return err
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (q *RedisQueue) Add(key string, due time.Time) error {
+	conn := q.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("ZADD", q.key, toMillis(due), key)
+	return err
+}
+
+func (q *RedisQueue) Remove(key string) error {
+	conn := q.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("ZREM", q.key, key)
+	return err
+}
+
+func (q *RedisQueue) Start(handler Handler) {
+	q.dispatcher = newDispatcher(q.workers, handler)
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+	go func() {
+		defer close(q.done)
+		ticker := time.NewTicker(q.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-q.stop:
+				return
+			case <-ticker.C:
+				if err := q.poll(); err != nil {
+					logrus.Error("延时队列读取失败: ", err)
+				}
+			}
+		}
+	}()
+}
+
+// 取出到期的 key 逐个 ZREM 认领
+func (q *RedisQueue) poll() error {
+	conn := q.pool.Get()
+	defer conn.Close()
+	for {
+		keys, err := redis.Strings(conn.Do("ZRANGEBYSCORE", q.key, "-inf", toMillis(time.Now()),
+			"LIMIT", 0, redisPollBatch))
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			n, err := redis.Int(conn.Do("ZREM", q.key, key))
+			if err != nil {
+				return err
+			}
+			if n == 1 {
+				q.dispatcher.dispatch(key)
+			}
+		}
+		if len(keys) < redisPollBatch {
+			return nil
+		}
+	}
+}
+
+func (q *RedisQueue) Stop() {
+	if q.stop == nil {
+		return
+	}
+	close(q.stop)
+	<-q.done
+	q.dispatcher.close()
+}
@@ -0,0 +1,179 @@
+package delayqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// 分层时间轮
+// 第1层每个槽是1个 tick 整层覆盖 tick*size 时间 更远的到期时间放到上1层 上1层每个槽覆盖下1层整层的时间 以此类推
+// 下1层转完1圈时 上1层当前槽中的 key 重新放入下层 最终在第1层的槽到期时投递
+// 投递时间最多比到期时间晚1个 tick 不会提前投递
+type TimingWheel struct {
+	mu    sync.Mutex
+	tick  time.Duration
+	root  *wheel
+	index map[string]int64
+	now   func() time.Time
+
+	workers    int
+	dispatcher *dispatcher
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+type wheel struct {
+	tick int64
+	size int64
+	// 当前槽的开始时间 当前槽的下标
+	cur  int64
+	head int64
+	// 每个槽保存 key -> 到期时间
+	buckets  []map[string]int64
+	overflow *wheel
+}
+
+func newWheel(tick, size, start int64) *wheel {
+	w := &wheel{
+		tick:    tick,
+		size:    size,
+		cur:     start,
+		buckets: make([]map[string]int64, size),
+	}
+	for i := range w.buckets {
+		w.buckets[i] = make(map[string]int64)
+	}
+	return w
+}
+
+func (w *wheel) interval() int64 {
+	return w.tick * w.size
+}
+
+// 取出当前槽 时间轮前进1格
+func (w *wheel) turn() map[string]int64 {
+	bucket := w.buckets[w.head]
+	w.buckets[w.head] = make(map[string]int64)
+	w.cur += w.tick
+	w.head = (w.head + 1) % w.size
+	return bucket
+}
+
+func NewTimingWheel(tick time.Duration, size, workers int) *TimingWheel {
+	if tick <= 0 {
+		tick = time.Second
+	}
+	if size <= 1 {
+		size = 60
+	}
+	tw := &TimingWheel{
+		tick:    tick,
+		index:   make(map[string]int64),
+		now:     time.Now,
+		workers: workers,
+	}
+	start := tw.now().Truncate(tick).UnixNano()
+	tw.root = newWheel(int64(tick), int64(size), start)
+	return tw
+}
+
+// 放入能容纳到期时间的最低1层 已经到期的 key 放入第1层的当前槽 下1个 tick 投递
+func (tw *TimingWheel) place(key string, due int64) {
+	w := tw.root
+	at := due
+	if at < w.cur {
+		at = w.cur
+	}
+	for at >= w.cur+w.interval() {
+		if w.overflow == nil {
+			w.overflow = newWheel(w.interval(), w.size, w.cur+w.interval())
+		}
+		w = w.overflow
+	}
+	slot := (w.head + (at-w.cur)/w.tick) % w.size
+	w.buckets[slot][key] = due
+}
+
+// 时间轮前进到 now 返回到期的 key
+func (tw *TimingWheel) advance(now int64) []string {
+	var fired []string
+	root := tw.root
+	for root.cur+root.tick <= now {
+		for key, due := range root.turn() {
+			// 被移除或更新过到期时间的 key 跳过
+			if d, ok := tw.index[key]; !ok || d != due {
+				continue
+			}
+			delete(tw.index, key)
+			fired = append(fired, key)
+		}
+		// 下1层转完1圈 上1层当前槽中的 key 放入下层
+		lower := root
+		for up := root.overflow; up != nil && up.cur == lower.cur; up = up.overflow {
+			for key, due := range up.turn() {
+				if d, ok := tw.index[key]; ok && d == due {
+					tw.place(key, due)
+				}
+			}
+			lower = up
+		}
+	}
+	return fired
+}
+
+func (tw *TimingWheel) Add(key string, due time.Time) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	d := due.UnixNano()
+	tw.index[key] = d
+	tw.place(key, d)
+	return nil
+}
+
+// 只从索引中移除 槽中的 key 到期时跳过
+func (tw *TimingWheel) Remove(key string) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	delete(tw.index, key)
+	return nil
+}
+
+// 队列中 key 的数量
+func (tw *TimingWheel) Len() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return len(tw.index)
+}
+
+func (tw *TimingWheel) Start(handler Handler) {
+	tw.dispatcher = newDispatcher(tw.workers, handler)
+	tw.stop = make(chan struct{})
+	tw.done = make(chan struct{})
+	go func() {
+		defer close(tw.done)
+		ticker := time.NewTicker(tw.tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tw.stop:
+				return
+			case <-ticker.C:
+				tw.mu.Lock()
+				fired := tw.advance(tw.now().UnixNano())
+				tw.mu.Unlock()
+				for _, key := range fired {
+					tw.dispatcher.dispatch(key)
+				}
+			}
+		}
+	}()
+}
+
+func (tw *TimingWheel) Stop() {
+	if tw.stop == nil {
+		return
+	}
+	close(tw.stop)
+	<-tw.done
+	tw.dispatcher.close()
+}
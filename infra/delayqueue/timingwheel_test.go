@@ -0,0 +1,58 @@
+package delayqueue
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimingWheel_Advance(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	newTimingWheel := func() *TimingWheel {
+		tw := NewTimingWheel(time.Second, 8, 1)
+		tw.root = newWheel(int64(time.Second), 8, start.UnixNano())
+		return tw
+	}
+	Convey("到期投递 不提前 最多晚1个 tick", t, func() {
+		tw := newTimingWheel()
+		r := rand.New(rand.NewSource(1))
+		want := make(map[string]time.Time)
+		// 8*8*8 秒之后的 key 需要多层时间轮
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprint(i)
+			due := start.Add(time.Duration(r.Int63n(int64(2 * time.Hour))))
+			want[key] = due
+			So(tw.Add(key, due), ShouldBeNil)
+		}
+		// 移除
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprint(i)
+			So(tw.Remove(key), ShouldBeNil)
+			delete(want, key)
+		}
+		// 更新到期时间
+		want["10"] = start.Add(90 * time.Minute)
+		So(tw.Add("10", want["10"]), ShouldBeNil)
+		So(tw.Len(), ShouldEqual, len(want))
+
+		fired := make(map[string]bool)
+		for now := start; now.Before(start.Add(3 * time.Hour)); now = now.Add(time.Second) {
+			for _, key := range tw.advance(now.UnixNano()) {
+				So(fired[key], ShouldBeFalse)
+				fired[key] = true
+				So(want[key].After(now), ShouldBeFalse)
+				So(now.Sub(want[key]), ShouldBeLessThanOrEqualTo, time.Second)
+			}
+		}
+		So(len(fired), ShouldEqual, len(want))
+		So(tw.Len(), ShouldEqual, 0)
+	})
+	Convey("已经到期的 key 下1个 tick 投递", t, func() {
+		tw := newTimingWheel()
+		So(tw.Add("expired", start.Add(-time.Hour)), ShouldBeNil)
+		So(tw.advance(start.Add(time.Second).UnixNano()), ShouldResemble, []string{"expired"})
+	})
+}
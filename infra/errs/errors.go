@@ -0,0 +1,143 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/filter"
+)
+
+// 业务错误 领域代码返回预定义的错误 web 和 RPC 接口通过 ToRes 转换为统一的响应
+//  var ErrEnvelopeNotFound = errs.New(errs.NotFound, "envelope.not_found", "红包不存在")
+//  return ErrEnvelopeNotFound.With("envelopeNo", dto.EnvelopeNo)
+// With Wrap 返回副本 不会修改预定义的错误 errors.Is 按消息key 判断是否是同1个错误
+
+// 错误分类 决定响应码和 http 状态码 参考 statuses
+type Code int
+
+const (
+	// 系统内部错误 没有分类的错误都按内部错误处理
+	Internal Code = iota
+	// 请求格式错误 比如请求体不是合法的 json
+	BadRequest
+	// 参数验证没有通过
+	InvalidArgument
+	// 内容违规 敏感词 长度 表情等
+	ContentViolation
+	// 数据不存在
+	NotFound
+	// 数据已经存在
+	AlreadyExists
+	// 没有操作权限
+	PermissionDenied
+	// 当前状态不允许该操作 比如红包已经被领取
+	FailedPrecondition
+	// 余额不足
+	InsufficientFunds
+	// 操作太频繁
+	TooManyRequests
+)
+
+type Error struct {
+	Code Code `json:"-"`
+	// 消息key 客户端按 key 区分错误和做多语言 比如 envelope.not_found
+	Key string `json:"key"`
+	// 中文描述
+	Message string `json:"-"`
+	// 错误详情 比如红包编号
+	Details map[string]interface{} `json:"details,omitempty"`
+	// 原因 只用于日志 不返回给客户端
+	Cause error `json:"-"`
+}
+
+func New(code Code, key, message string) *Error {
+	return &Error{Code: code, Key: key, Message: message}
+}
+
+// 描述 详情按 key 排序 原因在最后
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	keys := make([]string, 0, len(e.Details))
+	for k := range e.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Details[k])
+	}
+	if e.Cause != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Cause.Error())
+	}
+	return b.String()
+}
+
+// 增加1项详情 返回副本
+func (e *Error) With(key string, value interface{}) *Error {
+	c := *e
+	c.Details = make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	c.Details[key] = value
+	return &c
+}
+
+// 设置原因 返回副本
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// 消息key 相同就是同1个错误 With Wrap 返回的副本和预定义的错误相同
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Key == e.Key
+}
+
+var (
+	ErrInternal         = New(Internal, "internal", "系统繁忙,请稍后再试")
+	ErrBadRequest       = New(BadRequest, "bad_request", "请求参数格式错误")
+	ErrValidation       = New(InvalidArgument, "validation", "参数验证错误")
+	ErrContentViolation = New(ContentViolation, "content_violation", "内容违规")
+	ErrNotFound         = New(NotFound, "not_found", "没有查询到数据")
+)
+
+// 转换为业务错误 参数验证错误 内容违规错误转换为对应的分类 其他错误作为内部错误的原因
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if fields, ok := base.AsValidationErrors(err); ok {
+		e = ErrValidation.With("fields", fields)
+		e.Message = fields.Error()
+		return e
+	}
+	if v, ok := err.(*filter.ViolationError); ok {
+		e = ErrContentViolation.With("rule", v.Rule)
+		e.Message = v.Error()
+		return e
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// 错误的分类 nil 和没有分类的错误返回 Internal
+func CodeOf(err error) Code {
+	if e := From(err); e != nil {
+		return e.Code
+	}
+	return Internal
+}
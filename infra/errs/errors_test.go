@@ -0,0 +1,78 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/filter"
+)
+
+var errTestNotFound = New(NotFound, "test.not_found", "数据不存在")
+
+func TestError(t *testing.T) {
+	Convey("With Wrap 返回副本", t, func() {
+		cause := errors.New("connection refused")
+		e := errTestNotFound.With("id", "1").Wrap(cause)
+		So(errTestNotFound.Details, ShouldBeEmpty)
+		So(errTestNotFound.Cause, ShouldBeNil)
+		So(e.Details["id"], ShouldEqual, "1")
+		So(e.Error(), ShouldEqual, "数据不存在 id=1: connection refused")
+
+		So(errors.Is(e, errTestNotFound), ShouldBeTrue)
+		So(errors.Is(e, cause), ShouldBeTrue)
+		So(errors.Is(e, ErrNotFound), ShouldBeFalse)
+	})
+
+	Convey("转换为业务错误", t, func() {
+		So(From(nil), ShouldBeNil)
+		So(CodeOf(errTestNotFound), ShouldEqual, NotFound)
+
+		e := From(errors.New("sql: connection refused"))
+		So(e.Code, ShouldEqual, Internal)
+		So(e.Message, ShouldNotContainSubstring, "sql")
+
+		e = From(base.ValidationErrors{{Field: "amount", Tag: "amount", Param: "2", Message: "amount格式错误"}})
+		So(e.Code, ShouldEqual, InvalidArgument)
+		So(e.Message, ShouldEqual, "amount格式错误")
+
+		e = From(&filter.ViolationError{Rule: "sensitive", Message: "包含敏感词"})
+		So(e.Code, ShouldEqual, ContentViolation)
+		So(e.Details["rule"], ShouldEqual, "sensitive")
+	})
+
+	Convey("映射为响应码和 http 状态码", t, func() {
+		So(ResCode(nil), ShouldEqual, base.ResCodeOk)
+		So(HttpStatus(nil), ShouldEqual, http.StatusOK)
+		So(ResCode(errTestNotFound), ShouldEqual, base.ResCodeBizErr)
+		So(HttpStatus(errTestNotFound), ShouldEqual, http.StatusNotFound)
+		So(ResCode(errors.New("x")), ShouldEqual, base.ResCodeInternalServerErr)
+		So(HttpStatus(errors.New("x")), ShouldEqual, http.StatusInternalServerError)
+		for code := Internal; code <= TooManyRequests; code++ {
+			_, ok := statuses[code]
+			So(ok, ShouldBeTrue)
+		}
+	})
+
+	Convey("RPC 错误描述是响应 json", t, func() {
+		So(RpcError(nil), ShouldBeNil)
+		err := RpcError(errTestNotFound.With("id", "1"))
+		res := struct {
+			Code    base.ResCode `json:"code"`
+			Message string       `json:"message"`
+			Data    struct {
+				Key     string                 `json:"key"`
+				Details map[string]interface{} `json:"details"`
+			} `json:"data"`
+		}{}
+		So(json.Unmarshal([]byte(err.Error()), &res), ShouldBeNil)
+		So(res.Code, ShouldEqual, base.ResCodeBizErr)
+		So(res.Message, ShouldEqual, "数据不存在")
+		So(res.Data.Key, ShouldEqual, "test.not_found")
+		So(res.Data.Details["id"], ShouldEqual, "1")
+	})
+}
@@ -0,0 +1,69 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 错误分类对应的响应码和 http 状态码 web 和 RPC 接口共用
+var statuses = map[Code]struct {
+	res  base.ResCode
+	http int
+}{
+	Internal:           {base.ResCodeInternalServerErr, http.StatusInternalServerError},
+	BadRequest:         {base.ResCodeRequestParamsErr, http.StatusBadRequest},
+	InvalidArgument:    {base.ResCodeValidationErr, http.StatusBadRequest},
+	ContentViolation:   {base.ResCodeContentViolation, http.StatusBadRequest},
+	NotFound:           {base.ResCodeBizErr, http.StatusNotFound},
+	AlreadyExists:      {base.ResCodeBizErr, http.StatusConflict},
+	PermissionDenied:   {base.ResCodeBizErr, http.StatusForbidden},
+	FailedPrecondition: {base.ResCodeBizErr, http.StatusConflict},
+	InsufficientFunds:  {base.ResCodeBizTransferredFailure, http.StatusUnprocessableEntity},
+	TooManyRequests:    {base.ResCodeBizErr, http.StatusTooManyRequests},
+}
+
+// 错误对应的响应码
+func ResCode(err error) base.ResCode {
+	if err == nil {
+		return base.ResCodeOk
+	}
+	return statuses[CodeOf(err)].res
+}
+
+// 错误对应的 http 状态码
+func HttpStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return statuses[CodeOf(err)].http
+}
+
+// 错误转换为响应 Data 是错误的消息key 和详情
+// 内部错误只返回通用描述 原因由调用方记录日志
+func ToRes(err error) base.Res {
+	if err == nil {
+		return base.Res{Code: base.ResCodeOk}
+	}
+	e := From(err)
+	return base.Res{
+		Code:    statuses[e.Code].res,
+		Message: e.Message,
+		Data:    e,
+	}
+}
+
+// RPC 接口返回的错误 net/rpc 只传递错误描述
+// 描述是 ToRes 响应的 json 调用方可以反序列化为 base.Res
+func RpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	b, e := json.Marshal(ToRes(err))
+	if e != nil {
+		return err
+	}
+	return errors.New(string(b))
+}
@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"fmt"
+)
+
+// 文本内容过滤器 可以对文本进行校验或改写
+// 校验不通过返回 *ViolationError 改写后的文本交给下一个过滤器
+type Filter interface {
+	Filter(text string) (string, error)
+}
+
+// 函数适配为过滤器
+type FilterFunc func(text string) (string, error)
+
+func (f FilterFunc) Filter(text string) (string, error) {
+	return f(text)
+}
+
+// 内容违规错误 区别于系统错误 web层返回专用的验证错误码
+type ViolationError struct {
+	// 违规的过滤器名称 length emoji sensitive
+	Rule string
+	// 违规描述
+	Message string
+}
+
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("内容违规[%s]: %s", e.Rule, e.Message)
+}
+
+func IsViolation(err error) bool {
+	_, ok := err.(*ViolationError)
+	return ok
+}
+
+// 过滤器链 按添加顺序依次执行 任一过滤器返回错误则中断
+type Chain struct {
+	filters []Filter
+}
+
+func NewChain(filters ...Filter) *Chain {
+	return &Chain{filters: filters}
+}
+
+// 在链尾追加过滤器
+func (c *Chain) Use(f Filter) *Chain {
+	c.filters = append(c.filters, f)
+	return c
+}
+
+func (c *Chain) Filter(text string) (string, error) {
+	var err error
+	for _, f := range c.filters {
+		text, err = f.Filter(text)
+		if err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
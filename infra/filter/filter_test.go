@@ -0,0 +1,41 @@
+package filter
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMatcher_FindFirst(t *testing.T) {
+	m := newMatcher([]string{"赌博", "博彩网站", "ABC", "he", "she", "hers"})
+	Convey("敏感词匹配", t, func() {
+		So(m.FindFirst("恭喜发财"), ShouldEqual, "")
+		So(m.FindFirst("一起去赌博吧"), ShouldEqual, "赌博")
+		So(m.FindFirst("赌 博"), ShouldEqual, "赌博")
+		So(m.FindFirst("博彩网站"), ShouldEqual, "博彩网站")
+		So(m.FindFirst("ａｂｃ"), ShouldEqual, "abc")
+		// 失败指针 ushers 中包含 she he hers
+		So(m.FindFirst("ushers"), ShouldEqual, "she")
+		So(newMatcher(nil).FindFirst("赌博"), ShouldEqual, "")
+	})
+}
+
+func TestChain_Filter(t *testing.T) {
+	chain := NewChain(
+		TrimFilter(),
+		&LengthFilter{MaxLength: 16},
+		&EmojiFilter{AllowEmoji: false},
+		EscapeFilter(),
+	)
+	Convey("过滤器链", t, func() {
+		out, err := chain.Filter("  <b>新年</b>  ")
+		So(err, ShouldBeNil)
+		So(out, ShouldEqual, "&lt;b&gt;新年&lt;/b&gt;")
+
+		_, err = chain.Filter("恭喜发财恭喜发财恭喜发财恭喜发财恭喜发财")
+		So(IsViolation(err), ShouldBeTrue)
+
+		_, err = chain.Filter("恭喜发财🧧")
+		So(IsViolation(err), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 长度限制 按字符计算 与 MySQL varchar(n) 的字符长度一致
+type LengthFilter struct {
+	MaxLength int
+}
+
+func (f *LengthFilter) Filter(text string) (string, error) {
+	if n := utf8.RuneCountInString(text); n > f.MaxLength {
+		return "", &ViolationError{
+			Rule:    "length",
+			Message: fmt.Sprintf("长度%d超过最大长度%d", n, f.MaxLength),
+		}
+	}
+	return text, nil
+}
+
+// 表情和控制字符策略 控制字符一律不允许 表情由 AllowEmoji 决定
+type EmojiFilter struct {
+	AllowEmoji bool
+}
+
+func (f *EmojiFilter) Filter(text string) (string, error) {
+	for _, r := range text {
+		if unicode.IsControl(r) {
+			return "", &ViolationError{Rule: "emoji", Message: "不允许包含控制字符"}
+		}
+		if !f.AllowEmoji && isEmoji(r) {
+			return "", &ViolationError{Rule: "emoji", Message: "不允许包含表情符号"}
+		}
+	}
+	return text, nil
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // 表情 符号 国旗
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // 杂项符号 装饰符号
+		return true
+	case r == 0xFE0F || r == 0x200D: // 表情变体选择符 零宽连接符
+		return true
+	}
+	return false
+}
+
+// 首尾空白去除
+func TrimFilter() Filter {
+	return FilterFunc(func(text string) (string, error) {
+		return strings.TrimSpace(text), nil
+	})
+}
+
+// HTML转义 避免在活动页面渲染时注入脚本
+func EscapeFilter() Filter {
+	return FilterFunc(func(text string) (string, error) {
+		return html.EscapeString(text), nil
+	})
+}
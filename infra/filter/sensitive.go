@@ -0,0 +1,196 @@
+package filter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/width"
+)
+
+// 敏感词匹配 Aho-Corasick 自动机
+// 所有敏感词构建1棵字典树 再为每个节点计算失败指针 一次扫描文本即可匹配全部敏感词
+// 匹配前对文本和敏感词做相同的规范化 全角转半角 统一小写 去掉空白字符 避免用空格隔开敏感词绕过检查
+type matcher struct {
+	nodes []acNode
+}
+
+type acNode struct {
+	children map[rune]int
+	fail     int
+	// 以该节点结尾的敏感词长度 0 表示不是敏感词结尾
+	wordLen int
+}
+
+func normalizeWord(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, width.Fold.String(s))
+}
+
+func newMatcher(words []string) *matcher {
+	m := &matcher{nodes: []acNode{{children: map[rune]int{}}}}
+	// 1.构建字典树
+	for _, w := range words {
+		w = normalizeWord(strings.TrimSpace(w))
+		if w == "" {
+			continue
+		}
+		cur := 0
+		for _, r := range w {
+			next, ok := m.nodes[cur].children[r]
+			if !ok {
+				m.nodes = append(m.nodes, acNode{children: map[rune]int{}})
+				next = len(m.nodes) - 1
+				m.nodes[cur].children[r] = next
+			}
+			cur = next
+		}
+		m.nodes[cur].wordLen = len([]rune(w))
+	}
+	// 2.广度优先计算失败指针
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			f := m.nodes[cur].fail
+			for f > 0 {
+				if _, ok := m.nodes[f].children[r]; ok {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			if next, ok := m.nodes[f].children[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			// 失败指针上的敏感词也要在该节点被识别
+			if m.nodes[child].wordLen == 0 {
+				m.nodes[child].wordLen = m.nodes[m.nodes[child].fail].wordLen
+			}
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// 返回文本中第1个敏感词 没有则返回空字符串
+func (m *matcher) FindFirst(text string) string {
+	runes := []rune(normalizeWord(text))
+	cur := 0
+	for i, r := range runes {
+		for cur > 0 {
+			if _, ok := m.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if next, ok := m.nodes[cur].children[r]; ok {
+			cur = next
+		}
+		if n := m.nodes[cur].wordLen; n > 0 {
+			return string(runes[i+1-n : i+1])
+		}
+	}
+	return ""
+}
+
+// 敏感词过滤器 词库从本地文件加载 每行1个敏感词 # 开头为注释
+// 文件修改后自动重新加载 加载过程不影响正在进行的匹配
+type SensitiveWordFilter struct {
+	path    string
+	matcher atomic.Value
+	modTime time.Time
+	stop    chan struct{}
+}
+
+// 词库文件不存在时使用空词库 文件出现后由 Watch 自动加载
+func NewSensitiveWordFilter(path string) (*SensitiveWordFilter, error) {
+	f := &SensitiveWordFilter{path: path, stop: make(chan struct{})}
+	f.matcher.Store(newMatcher(nil))
+	if err := f.Load(); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		logrus.Errorf("敏感词库文件不存在 %s 等待文件创建后自动加载", path)
+	}
+	return f, nil
+}
+
+// 加载词库
+func (f *SensitiveWordFilter) Load() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	words := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	f.matcher.Store(newMatcher(words))
+	f.modTime = stat.ModTime()
+	logrus.Infof("敏感词库加载完成 %s 共%d个敏感词", f.path, len(words))
+	return nil
+}
+
+// 定时检查词库文件修改时间 文件变化时重新加载
+func (f *SensitiveWordFilter) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				stat, err := os.Stat(f.path)
+				if err != nil {
+					logrus.Error("敏感词库文件检查失败:", err)
+					continue
+				}
+				if stat.ModTime().Equal(f.modTime) {
+					continue
+				}
+				if err := f.Load(); err != nil {
+					// 加载失败继续使用原词库
+					logrus.Error("敏感词库重新加载失败:", err)
+				}
+			}
+		}
+	}()
+}
+
+func (f *SensitiveWordFilter) Close() {
+	close(f.stop)
+}
+
+func (f *SensitiveWordFilter) Filter(text string) (string, error) {
+	m := f.matcher.Load().(*matcher)
+	if word := m.FindFirst(text); word != "" {
+		return "", &ViolationError{Rule: "sensitive", Message: "包含敏感词"}
+	}
+	return text, nil
+}
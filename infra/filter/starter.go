@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+)
+
+// 红包祝福语过滤器链
+var blessingFilter Filter
+
+func BlessingFilter() Filter {
+	if blessingFilter == nil {
+		panic("内容过滤器还没有被初始化")
+	}
+	return blessingFilter
+}
+
+// 内容过滤器 starter
+// 过滤器链顺序: 去除首尾空白 -> 长度 -> 表情 -> 敏感词 -> HTML转义 -> 转义后字段长度
+type ContentFilterStarter struct {
+	infra.BaseStarter
+	sensitive *SensitiveWordFilter
+}
+
+func (s *ContentFilterStarter) Init(ctx infra.StarterContext) {
+	logrus.Info("ContentFilterStarter Init()")
+	props := ctx.Props()
+	chain := NewChain(
+		TrimFilter(),
+		&LengthFilter{MaxLength: props.GetIntDefault("filter.blessing.maxLength", 32)},
+		&EmojiFilter{AllowEmoji: props.GetBoolDefault("filter.blessing.allowEmoji", false)},
+	)
+	// 词库文件路径 相对路径基于程序运行的工作目录
+	path := props.GetDefault("filter.words.file", "")
+	if path != "" {
+		sensitive, err := NewSensitiveWordFilter(path)
+		if err != nil {
+			// 敏感词库是内容安全的基础 加载失败禁止启动
+			logrus.Panic("敏感词库加载失败:", err)
+		}
+		s.sensitive = sensitive
+		chain.Use(sensitive)
+	} else {
+		logrus.Warn("没有配置敏感词库 filter.words.file")
+	}
+	chain.Use(EscapeFilter())
+	// red_envelope_goods.blessing varchar(64) 转义会使内容变长
+	chain.Use(&LengthFilter{MaxLength: 64})
+	blessingFilter = chain
+}
+
+func (s *ContentFilterStarter) Start(ctx infra.StarterContext) {
+	if s.sensitive != nil {
+		interval := ctx.Props().GetDurationDefault("filter.words.reloadInterval", 30*time.Second)
+		s.sensitive.Watch(interval)
+	}
+}
+
+func (s *ContentFilterStarter) Stop(ctx infra.StarterContext) {
+	if s.sensitive != nil {
+		s.sensitive.Close()
+	}
+}
@@ -0,0 +1,120 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 健康状态
+const (
+	HealthUp   = "UP"
+	HealthDown = "DOWN"
+	// 组件部分不可用 但是仍然可以提供服务 不影响就绪状态 比如从库全部不可用时读操作回退到主库
+	HealthDegraded = "DEGRADED"
+)
+
+// 可选接口 starter 实现健康检查 就绪检查时汇总所有 starter 的状态
+// ctx 带有超时时间 检查应该在超时前返回
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// 健康检查返回 DegradedError 时组件状态为 DEGRADED 应用仍然是就绪状态
+type DegradedError struct {
+	Err error
+}
+
+func (e *DegradedError) Error() string {
+	return e.Err.Error()
+}
+
+func Degraded(err error) error {
+	return &DegradedError{Err: err}
+}
+
+// 组件的健康状态
+type ComponentHealth struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// 应用的健康状态
+type Health struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// 应用是否就绪 启动完成前和开始停止后都不是就绪状态
+func (b *BootApplication) Ready() bool {
+	return atomic.LoadInt32(&b.ready) == 1
+}
+
+// 并发执行所有已启动 starter 的健康检查 每个检查最长 timeout
+// 应用没有就绪或者任意1个组件检查失败 状态为 DOWN
+func (b *BootApplication) Health(timeout time.Duration) Health {
+	health := Health{
+		Status:     HealthUp,
+		Components: make(map[string]ComponentHealth),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, starter := range b.starters {
+		checker, ok := starter.(HealthChecker)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, checker HealthChecker) {
+			defer wg.Done()
+			component := check(checker, timeout)
+			mu.Lock()
+			health.Components[name] = component
+			mu.Unlock()
+		}(StarterName(starter), checker)
+	}
+	wg.Wait()
+	if !b.Ready() {
+		health.Status = HealthDown
+	}
+	for _, c := range health.Components {
+		if c.Status == HealthDown {
+			health.Status = HealthDown
+		}
+	}
+	return health
+}
+
+func check(checker HealthChecker, timeout time.Duration) (c ComponentHealth) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.Latency = time.Since(start).String()
+	c.Status = HealthUp
+	if err != nil {
+		c.Status = HealthDown
+		if _, ok := err.(*DegradedError); ok {
+			c.Status = HealthDegraded
+		}
+		c.Error = err.Error()
+	}
+	return c
+}
@@ -0,0 +1,57 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type checkStarter struct {
+	testStarter
+	err   error
+	delay time.Duration
+}
+
+func (s *checkStarter) Check(ctx context.Context) error {
+	select {
+	case <-time.After(s.delay):
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestBootApplication_Health(t *testing.T) {
+	Convey("健康检查汇总", t, func() {
+		b := &BootApplication{starters: []Starter{
+			&checkStarter{testStarter: testStarter{name: "db"}},
+			&checkStarter{testStarter: testStarter{name: "redis"}},
+			&testStarter{name: "web"},
+		}}
+		So(b.Health(time.Second).Status, ShouldEqual, HealthDown)
+
+		b.ready = 1
+		h := b.Health(time.Second)
+		So(h.Status, ShouldEqual, HealthUp)
+		So(len(h.Components), ShouldEqual, 2)
+
+		b.starters[1] = &checkStarter{testStarter: testStarter{name: "redis"}, err: errors.New("connection refused")}
+		h = b.Health(time.Second)
+		So(h.Status, ShouldEqual, HealthDown)
+		So(h.Components["redis"].Error, ShouldEqual, "connection refused")
+		So(h.Components["db"].Status, ShouldEqual, HealthUp)
+
+		b.starters[1] = &checkStarter{testStarter: testStarter{name: "redis"}, err: Degraded(errors.New("replica down"))}
+		h = b.Health(time.Second)
+		So(h.Status, ShouldEqual, HealthUp)
+		So(h.Components["redis"].Status, ShouldEqual, HealthDegraded)
+		So(h.Components["redis"].Error, ShouldEqual, "replica down")
+
+		b.starters[1] = &checkStarter{testStarter: testStarter{name: "redis"}, delay: time.Second}
+		h = b.Health(10 * time.Millisecond)
+		So(h.Components["redis"].Status, ShouldEqual, HealthDown)
+	})
+}
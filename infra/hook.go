@@ -0,0 +1,36 @@
+package infra
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// 应用停止时执行的钩子
+type Hook func(ctx StarterContext)
+
+var (
+	preStopHooks  []Hook
+	postStopHooks []Hook
+)
+
+// 注册 pre-stop 钩子 在所有starter停止之前执行 比如从注册中心下线 停止接收新的请求
+func RegisterPreStopHook(hook Hook) {
+	preStopHooks = append(preStopHooks, hook)
+}
+
+// 注册 post-stop 钩子 在所有starter停止之后执行 比如刷新日志缓冲
+func RegisterPostStopHook(hook Hook) {
+	postStopHooks = append(postStopHooks, hook)
+}
+
+func runHooks(name string, hooks []Hook, ctx StarterContext) {
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("%s hook panic: %v", name, r)
+				}
+			}()
+			hook(ctx)
+		}()
+	}
+}
@@ -0,0 +1,111 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/tietang/dbx"
+)
+
+// 基于数据库租约行的分布式锁 映射 distributed_lock 表
+// 每个锁1行记录 owner 是持有者 expired_at 是租约到期时间 过期时间使用数据库时间 避免节点之间的时钟误差
+type LeaseProvider struct {
+	db *dbx.Database
+}
+
+func (p *LeaseProvider) Check(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+func NewLeaseProvider(db *dbx.Database) *LeaseProvider {
+	return &LeaseProvider{db: db}
+}
+
+func (p *LeaseProvider) NewLocker(name string, expiry time.Duration) Locker {
+	return &leaseLocker{db: p.db, name: name, expiry: expiry}
+}
+
+type leaseLocker struct {
+	db     *dbx.Database
+	name   string
+	expiry time.Duration
+	owner  string
+}
+
+func (l *leaseLocker) micros() int64 {
+	return int64(l.expiry / time.Microsecond)
+}
+
+// 没有锁记录时插入 锁记录已过期时抢占 [乐观锁] 多个节点同时获取只有1个能成功
+func (l *leaseLocker) Lock() error {
+	owner := ksuid.New().String()
+	var rows int64
+	err := l.db.Tx(func(runner *dbx.TxRunner) error {
+		sql := "insert ignore into distributed_lock(name,owner,expired_at) " +
+			" values(?,?,date_add(now(3), interval ? microsecond))"
+		rs, err := runner.Exec(sql, l.name, owner, l.micros())
+		if err != nil {
+			return err
+		}
+		if rows, err = rs.RowsAffected(); err != nil || rows == 1 {
+			return err
+		}
+		sql = "update distributed_lock set owner=?,expired_at=date_add(now(3), interval ? microsecond) " +
+			" where name=? and expired_at<now(3)"
+		rs, err = runner.Exec(sql, owner, l.micros(), l.name)
+		if err != nil {
+			return err
+		}
+		rows, err = rs.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrNotObtained
+	}
+	l.owner = owner
+	return nil
+}
+
+func (l *leaseLocker) Extend() error {
+	var rows int64
+	err := l.db.Tx(func(runner *dbx.TxRunner) error {
+		sql := "update distributed_lock set expired_at=date_add(now(3), interval ? microsecond) " +
+			" where name=? and owner=? and expired_at>=now(3)"
+		rs, err := runner.Exec(sql, l.micros(), l.name, l.owner)
+		if err != nil {
+			return err
+		}
+		rows, err = rs.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (l *leaseLocker) Unlock() error {
+	var rows int64
+	err := l.db.Tx(func(runner *dbx.TxRunner) error {
+		rs, err := runner.Exec("delete from distributed_lock where name=? and owner=?", l.name, l.owner)
+		if err != nil {
+			return err
+		}
+		rows, err = rs.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrLockLost
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package lock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// 进程内的锁 只在单节点部署和开发环境使用
+type LocalProvider struct {
+	mu    sync.Mutex
+	locks map[string]localLock
+}
+
+type localLock struct {
+	owner     string
+	expiredAt time.Time
+}
+
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{locks: make(map[string]localLock)}
+}
+
+func (p *LocalProvider) NewLocker(name string, expiry time.Duration) Locker {
+	return &localLocker{provider: p, name: name, expiry: expiry}
+}
+
+type localLocker struct {
+	provider *LocalProvider
+	name     string
+	expiry   time.Duration
+	owner    string
+}
+
+func (l *localLocker) Lock() error {
+	p := l.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if held, ok := p.locks[l.name]; ok && held.expiredAt.After(now) {
+		return ErrNotObtained
+	}
+	l.owner = ksuid.New().String()
+	p.locks[l.name] = localLock{owner: l.owner, expiredAt: now.Add(l.expiry)}
+	return nil
+}
+
+func (l *localLocker) Extend() error {
+	p := l.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	held, ok := p.locks[l.name]
+	if !ok || held.owner != l.owner || !held.expiredAt.After(now) {
+		return ErrLockLost
+	}
+	held.expiredAt = now.Add(l.expiry)
+	p.locks[l.name] = held
+	return nil
+}
+
+func (l *localLocker) Unlock() error {
+	p := l.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	held, ok := p.locks[l.name]
+	if !ok || held.owner != l.owner {
+		return ErrLockLost
+	}
+	delete(p.locks, l.name)
+	return nil
+}
@@ -0,0 +1,46 @@
+package lock
+
+import (
+	"errors"
+	"time"
+)
+
+// 锁已经被其他节点持有
+var ErrNotObtained = errors.New("锁已经被其他节点持有")
+
+// 锁已经过期或被其他节点获取
+var ErrLockLost = errors.New("锁已经丢失")
+
+// 分布式锁 获取锁不会一直等待 锁被其他节点持有时返回 ErrNotObtained
+// 锁的过期时间在创建时指定 持有锁的节点宕机后 锁过期自动释放
+type Locker interface {
+	// 获取锁
+	Lock() error
+	// 延长锁的过期时间 锁已经丢失时返回 ErrLockLost
+	Extend() error
+	// 释放锁 只释放自己持有的锁
+	Unlock() error
+}
+
+// 锁的提供者 redis mysql lease local 通过配置 lock.provider 选择
+type Provider interface {
+	NewLocker(name string, expiry time.Duration) Locker
+}
+
+var provider Provider
+
+func GetProvider() Provider {
+	if provider == nil {
+		panic("分布式锁还没有被初始化")
+	}
+	return provider
+}
+
+// 使用当前配置的锁提供者创建带看门狗的锁
+func NewMutex(name string, expiry time.Duration) *Mutex {
+	return &Mutex{
+		locker: GetProvider().NewLocker(name, expiry),
+		name:   name,
+		expiry: expiry,
+	}
+}
@@ -0,0 +1,68 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+func TestLocalProvider(t *testing.T) {
+	p := NewLocalProvider()
+	Convey("进程内的锁", t, func() {
+		a := p.NewLocker("lock:test", time.Second)
+		b := p.NewLocker("lock:test", time.Second)
+		So(a.Lock(), ShouldBeNil)
+		So(b.Lock(), ShouldEqual, ErrNotObtained)
+		So(a.Extend(), ShouldBeNil)
+		// 只能释放自己持有的锁
+		So(b.Unlock(), ShouldEqual, ErrLockLost)
+		So(a.Unlock(), ShouldBeNil)
+		So(b.Lock(), ShouldBeNil)
+		So(b.Unlock(), ShouldBeNil)
+	})
+	Convey("锁过期后可以被其他持有者获取", t, func() {
+		a := p.NewLocker("lock:expiry", 50*time.Millisecond)
+		b := p.NewLocker("lock:expiry", 50*time.Millisecond)
+		So(a.Lock(), ShouldBeNil)
+		time.Sleep(60 * time.Millisecond)
+		So(b.Lock(), ShouldBeNil)
+		So(a.Extend(), ShouldEqual, ErrLockLost)
+		So(b.Unlock(), ShouldBeNil)
+	})
+}
+
+func TestMutex_Watchdog(t *testing.T) {
+	p := NewLocalProvider()
+	Convey("看门狗续期", t, func() {
+		expiry := 60 * time.Millisecond
+		m := &Mutex{locker: p.NewLocker("lock:watchdog", expiry), name: "lock:watchdog", expiry: expiry}
+		So(m.Lock(), ShouldBeNil)
+		// 持有时间超过过期时间 看门狗续期后其他持有者仍然不能获取锁
+		time.Sleep(3 * expiry)
+		other := p.NewLocker("lock:watchdog", expiry)
+		So(other.Lock(), ShouldEqual, ErrNotObtained)
+		select {
+		case <-m.Lost():
+			t.Fatal("锁不应该丢失")
+		default:
+		}
+		So(m.Unlock(), ShouldBeNil)
+		// 没有持有锁时释放锁直接返回
+		So(m.Unlock(), ShouldBeNil)
+		So(other.Lock(), ShouldBeNil)
+	})
+}
+
+func TestProviderName(t *testing.T) {
+	Convey("根据仓储后端确定锁的提供者", t, func() {
+		So(providerName("", base.BackendMysql), ShouldEqual, "mysql")
+		So(providerName("", base.BackendSqlite), ShouldEqual, "local")
+		So(providerName("", base.BackendMemory), ShouldEqual, "local")
+		So(providerName("lease", base.BackendSqlite), ShouldEqual, "local")
+		So(providerName("lease", base.BackendMysql), ShouldEqual, "lease")
+		So(providerName("redis", base.BackendSqlite), ShouldEqual, "redis")
+	})
+}
@@ -0,0 +1,87 @@
+package lock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 带看门狗的锁
+// 持有锁期间看门狗每隔 1/3 过期时间延长1次锁的过期时间 任务执行时间超过过期时间也不会被其他节点获取到锁
+// 持有锁的节点宕机后看门狗停止 锁过期后自动释放
+type Mutex struct {
+	locker Locker
+	name   string
+	expiry time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+	lost chan struct{}
+}
+
+// 获取锁 获取成功后启动看门狗
+func (m *Mutex) Lock() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return ErrNotObtained
+	}
+	if err := m.locker.Lock(); err != nil {
+		return err
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	m.lost = make(chan struct{})
+	go m.watch(m.stop, m.done, m.lost)
+	return nil
+}
+
+// 锁丢失时关闭 任务可以据此提前结束
+func (m *Mutex) Lost() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lost
+}
+
+// 停止看门狗并释放锁 没有持有锁时直接返回
+func (m *Mutex) Unlock() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop == nil {
+		return nil
+	}
+	close(m.stop)
+	<-m.done
+	m.stop = nil
+	return m.locker.Unlock()
+}
+
+func (m *Mutex) watch(stop, done, lost chan struct{}) {
+	defer close(done)
+	interval := m.expiry / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := m.locker.Extend()
+			if err == ErrLockLost {
+				// 锁已经过期或被其他节点获取 不再续期
+				logrus.Errorf("锁%s已经丢失", m.name)
+				close(lost)
+				return
+			}
+			if err != nil {
+				// 网络等临时错误 下次继续续期
+				logrus.Errorf("锁%s续期失败: %s", m.name, err)
+			}
+		}
+	}
+}
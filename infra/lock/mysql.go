@@ -0,0 +1,91 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/tietang/dbx"
+)
+
+// 基于 MySQL GET_LOCK 的分布式锁
+// GET_LOCK 的锁属于数据库连接 获取锁时从连接池取出1个连接独占到释放锁为止
+// 持有锁的节点宕机后 数据库连接断开 锁由 MySQL 自动释放 所以不需要过期时间
+type MysqlProvider struct {
+	db *dbx.Database
+}
+
+func (p *MysqlProvider) Check(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+func NewMysqlProvider(db *dbx.Database) *MysqlProvider {
+	return &MysqlProvider{db: db}
+}
+
+func (p *MysqlProvider) NewLocker(name string, expiry time.Duration) Locker {
+	return &mysqlLocker{db: p.db, name: name}
+}
+
+type mysqlLocker struct {
+	db   *dbx.Database
+	name string
+	conn *sql.Conn
+}
+
+func (l *mysqlLocker) Lock() error {
+	ctx := context.Background()
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	// 超时时间 0 锁被持有时立即返回
+	var ok sql.NullInt64
+	err = conn.QueryRowContext(ctx, "select get_lock(?, 0)", l.name).Scan(&ok)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !ok.Valid || ok.Int64 != 1 {
+		conn.Close()
+		return ErrNotObtained
+	}
+	l.conn = conn
+	return nil
+}
+
+// 锁跟随连接存在 续期只需要确认锁仍然被当前连接持有 同时保持连接活跃
+func (l *mysqlLocker) Extend() error {
+	if l.conn == nil {
+		return ErrLockLost
+	}
+	var held sql.NullInt64
+	err := l.conn.QueryRowContext(context.Background(),
+		"select is_used_lock(?) = connection_id()", l.name).Scan(&held)
+	if err != nil {
+		return err
+	}
+	if !held.Valid || held.Int64 != 1 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (l *mysqlLocker) Unlock() error {
+	if l.conn == nil {
+		return ErrLockLost
+	}
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+	var released sql.NullInt64
+	err := l.conn.QueryRowContext(context.Background(), "select release_lock(?)", l.name).Scan(&released)
+	if err != nil {
+		return err
+	}
+	if !released.Valid || released.Int64 != 1 {
+		return ErrLockLost
+	}
+	return nil
+}
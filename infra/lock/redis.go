@@ -0,0 +1,83 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redsync/redsync"
+	"github.com/gomodule/redigo/redis"
+	"github.com/segmentio/ksuid"
+	"github.com/tietang/props/kvs"
+
+	"github.com/solozyx/red-envelope/comm"
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 基于 redsync 的 redis 分布式锁
+type RedisProvider struct {
+	pool  *redis.Pool
+	rsync *redsync.Redsync
+	ip    string
+}
+
+func NewRedisProvider(conf kvs.ConfigSource) *RedisProvider {
+	pool := base.NewRedisPool(conf)
+	return &RedisProvider{
+		pool:  pool,
+		rsync: redsync.New([]redsync.Pool{pool}),
+		ip:    comm.GetIP(),
+	}
+}
+
+func (p *RedisProvider) Check(ctx context.Context) error {
+	conn := p.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("PING")
+	return err
+}
+
+func (p *RedisProvider) NewLocker(name string, expiry time.Duration) Locker {
+	mutex := p.rsync.NewMutex(name,
+		redsync.SetExpiry(expiry),
+		redsync.SetTries(3),
+		redsync.SetGenValueFunc(func() (s string, e error) {
+			// 锁的值标识持有锁的节点 每次获取锁都不相同
+			return fmt.Sprintf("%s:%s", p.ip, ksuid.New().String()), nil
+		}))
+	return &redisLocker{mutex: mutex}
+}
+
+type redisLocker struct {
+	mutex *redsync.Mutex
+}
+
+func (l *redisLocker) Lock() error {
+	err := l.mutex.Lock()
+	if err == redsync.ErrFailed {
+		return ErrNotObtained
+	}
+	return err
+}
+
+func (l *redisLocker) Extend() error {
+	ok, err := l.mutex.Extend()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (l *redisLocker) Unlock() error {
+	ok, err := l.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package lock
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 分布式锁 starter 要放在数据库 starter 之后
+// lock.provider 可选 redis mysql lease local
+//  redis: redsync 需要 redis.addr
+//  mysql: MySQL GET_LOCK 锁属于数据库连接
+//  lease: 数据库租约行 distributed_lock 表
+//  local: 进程内的锁 只适用于单节点部署
+// 没有配置时 mysql 后端使用 mysql sqlite 和 memory 后端只能单节点部署 使用 local
+type LockStarter struct {
+	infra.BaseStarter
+}
+
+func (s *LockStarter) DependsOn() []string {
+	return []string{"dbxDatabase"}
+}
+
+// 健康检查 检查分布式锁依赖的 redis 或数据库
+func (s *LockStarter) Check(ctx context.Context) error {
+	if checker, ok := provider.(infra.HealthChecker); ok {
+		return checker.Check(ctx)
+	}
+	return nil
+}
+
+func (s *LockStarter) Setup(ctx infra.StarterContext) {
+	name := providerName(ctx.Props().GetDefault("lock.provider", ""), base.Backend())
+	switch name {
+	case "redis":
+		provider = NewRedisProvider(ctx.Props())
+	case "mysql":
+		provider = NewMysqlProvider(base.DbxDatabase())
+	case "lease":
+		provider = NewLeaseProvider(base.DbxDatabase())
+	case "local":
+		logrus.Warn("使用进程内的锁 多节点部署时不能保证任务只在1个节点执行")
+		provider = NewLocalProvider()
+	default:
+		logrus.Panic("不支持的分布式锁 lock.provider=", name)
+	}
+	logrus.Info("分布式锁 lock.provider=", name)
+}
+
+// 根据仓储后端确定锁的提供者
+// mysql 和 lease 使用 MySQL 的 GET_LOCK 和时间函数 其他后端改为使用 local
+func providerName(name, backend string) string {
+	if name == "" {
+		if backend == base.BackendMysql {
+			return "mysql"
+		}
+		return "local"
+	}
+	if (name == "mysql" || name == "lease") && backend != base.BackendMysql {
+		logrus.Warnf("lock.provider=%s 需要 MySQL 数据库 当前 repository.backend=%s 改为使用 local", name, backend)
+		return "local"
+	}
+	return name
+}
@@ -0,0 +1,129 @@
+package memdb
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// 唯一索引冲突
+var ErrDuplicate = errors.New("memdb: duplicate entry")
+
+// 进程内的内存数据库 按表保存持久化对象的副本 主要用于没有数据库的测试环境
+// 事务串行执行 事务中的写操作记录回滚日志 事务回滚时按相反顺序恢复
+// 自增 id 和 MySQL 一样 回滚后不会回收
+type Database struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+type table struct {
+	seq  int64
+	rows map[int64]interface{}
+}
+
+func New() *Database {
+	return &Database{tables: make(map[string]*table)}
+}
+
+// 在事务中执行 fn 返回错误或 panic 时回滚
+// 事务中不能再开启新的事务 需要加入事务时把 *Tx 传递下去
+func (db *Database) Tx(fn func(tx *Tx) error) (err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tx := &Tx{db: db}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+// 清空所有表
+func (db *Database) Reset() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.tables = make(map[string]*table)
+}
+
+// 内存数据库事务 只在 Database.Tx 的 fn 中有效
+type Tx struct {
+	db   *Database
+	undo []func()
+}
+
+func (tx *Tx) table(name string) *table {
+	t, ok := tx.db.tables[name]
+	if !ok {
+		t = &table{rows: make(map[int64]interface{})}
+		tx.db.tables[name] = t
+	}
+	return t
+}
+
+// 分配自增 id
+func (tx *Tx) NextId(name string) int64 {
+	t := tx.table(name)
+	t.seq++
+	return t.seq
+}
+
+// 写入1行 id 已存在时覆盖 row 应该是值类型 避免外部修改影响已保存的数据
+func (tx *Tx) Put(name string, id int64, row interface{}) {
+	t := tx.table(name)
+	old, exists := t.rows[id]
+	tx.undo = append(tx.undo, func() {
+		if exists {
+			t.rows[id] = old
+		} else {
+			delete(t.rows, id)
+		}
+	})
+	t.rows[id] = row
+}
+
+// 删除1行 id 不存在时什么也不做
+func (tx *Tx) Delete(name string, id int64) {
+	t := tx.table(name)
+	old, exists := t.rows[id]
+	if !exists {
+		return
+	}
+	tx.undo = append(tx.undo, func() {
+		t.rows[id] = old
+	})
+	delete(t.rows, id)
+}
+
+// 按 id 查询1行
+func (tx *Tx) Get(name string, id int64) (interface{}, bool) {
+	row, ok := tx.table(name).rows[id]
+	return row, ok
+}
+
+// 按 id 从小到大遍历 fn 返回 false 时停止
+func (tx *Tx) Scan(name string, fn func(id int64, row interface{}) bool) {
+	t := tx.table(name)
+	ids := make([]int64, 0, len(t.rows))
+	for id := range t.rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if !fn(id, t.rows[id]) {
+			return
+		}
+	}
+}
+
+func (tx *Tx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
@@ -0,0 +1,66 @@
+package memdb
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type row struct {
+	Id   int64
+	Name string
+}
+
+func TestDatabase_Tx(t *testing.T) {
+	Convey("内存数据库事务", t, func() {
+		db := New()
+		err := db.Tx(func(tx *Tx) error {
+			id := tx.NextId("t")
+			tx.Put("t", id, row{Id: id, Name: "a"})
+			return nil
+		})
+		So(err, ShouldBeNil)
+
+		// 回滚时恢复修改和删除新写入的行
+		err = db.Tx(func(tx *Tx) error {
+			tx.Put("t", 1, row{Id: 1, Name: "b"})
+			id := tx.NextId("t")
+			tx.Put("t", id, row{Id: id, Name: "c"})
+			return errors.New("rollback")
+		})
+		So(err, ShouldNotBeNil)
+
+		db.Tx(func(tx *Tx) error {
+			r, ok := tx.Get("t", 1)
+			So(ok, ShouldBeTrue)
+			So(r.(row).Name, ShouldEqual, "a")
+			_, ok = tx.Get("t", 2)
+			So(ok, ShouldBeFalse)
+			// 自增 id 不回收
+			So(tx.NextId("t"), ShouldEqual, 3)
+			return nil
+		})
+
+		// 回滚时恢复删除的行
+		err = db.Tx(func(tx *Tx) error {
+			tx.Delete("t", 1)
+			_, ok := tx.Get("t", 1)
+			So(ok, ShouldBeFalse)
+			return errors.New("rollback")
+		})
+		So(err, ShouldNotBeNil)
+
+		So(func() {
+			db.Tx(func(tx *Tx) error {
+				tx.Put("t", 1, row{Id: 1, Name: "panic"})
+				panic("boom")
+			})
+		}, ShouldPanic)
+		db.Tx(func(tx *Tx) error {
+			r, _ := tx.Get("t", 1)
+			So(r.(row).Name, ShouldEqual, "a")
+			return nil
+		})
+	})
+}
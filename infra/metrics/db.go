@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 数据库连接池指标 每次抓取时读取主库和所有从库的 sql.DBStats
+// db 标签是 primary 或从库名称
+type dbStatsCollector struct {
+	maxOpen      *prometheus.Desc
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+	closed       *prometheus.Desc
+}
+
+func newDBStatsCollector() *dbStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, []string{"db"}, nil)
+	}
+	return &dbStatsCollector{
+		maxOpen:      desc("max_open_connections", "最大连接数"),
+		open:         desc("open_connections", "已建立的连接数"),
+		inUse:        desc("in_use_connections", "使用中的连接数"),
+		idle:         desc("idle_connections", "空闲连接数"),
+		waitCount:    desc("wait_count_total", "等待连接的次数"),
+		waitDuration: desc("wait_duration_seconds_total", "等待连接的总时间"),
+		closed:       desc("closed_connections_total", "因超过空闲数量或生命周期关闭的连接数"),
+	}
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.closed
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, s := range base.DatabaseStats() {
+		ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse), name)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle), name)
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.closed, prometheus.CounterValue,
+			float64(s.MaxIdleClosed+s.MaxLifetimeClosed), name)
+	}
+}
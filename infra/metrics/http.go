@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+// iris 中间件 统计 http 请求耗时
+func httpHandler(ctx iris.Context) {
+	start := time.Now()
+	ctx.Next()
+	route := ctx.Path()
+	if r := ctx.GetCurrentRoute(); r != nil {
+		route = r.Path()
+	}
+	httpDuration.WithLabelValues(ctx.Method(), route, strconv.Itoa(ctx.GetStatusCode())).
+		Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 业务指标和基础设施指标 注册到 prometheus 默认的注册表
+// 默认注册表已经包含 Go 运行时和进程指标 由 MetricsStarter 通过 /metrics 暴露
+const namespace = "red_envelope"
+
+// 结果标签的取值
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	// 分布式锁已经被其他节点持有
+	ResultBusy = "busy"
+	// 获取到分布式锁
+	ResultAcquired = "acquired"
+)
+
+// 业务操作 按结果统计次数和耗时
+type Operation struct {
+	total    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newOperation(name, help string) *Operation {
+	return &Operation{
+		total: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name + "_total",
+			Help:      help + "次数",
+		}, []string{"result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      name + "_duration_seconds",
+			Help:      help + "耗时",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+	}
+}
+
+// 记录1次操作 result 是结果标签 取值要有限 不能使用错误信息
+func (o *Operation) Observe(start time.Time, result string) {
+	o.total.WithLabelValues(result).Inc()
+	o.duration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// err 为 nil 时结果是 success 否则是 failure
+func (o *Operation) ObserveErr(start time.Time, err error) {
+	if err != nil {
+		o.Observe(start, ResultFailure)
+		return
+	}
+	o.Observe(start, ResultSuccess)
+}
+
+func (o *Operation) collectors() []prometheus.Collector {
+	return []prometheus.Collector{o.total, o.duration}
+}
+
+var (
+	// 发红包 包括保存红包商品和扣款
+	SendOut = newOperation("envelope_send_out", "发红包")
+	// 收红包
+	Receive = newOperation("envelope_receive", "收红包")
+	// 账户转账 结果是转账状态 success insufficient_funds failure
+	// 在外层事务中执行的转账 外层事务回滚时已经统计的结果不会撤销
+	Transfer = newOperation("account_transfer", "账户转账")
+	// 1个过期红包的退款流程
+	Refund = newOperation("envelope_refund", "过期红包退款")
+
+	// 收红包时乐观锁更新剩余数量和金额失败的次数 红包已领完或并发领取冲突
+	ReceiveConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "envelope_receive_conflicts_total",
+		Help:      "收红包乐观锁更新失败次数",
+	})
+
+	// 定时任务执行耗时 只统计获取到分布式锁后的执行
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_run_duration_seconds",
+		Help:      "定时任务执行耗时",
+		Buckets:   []float64{.1, .5, 1, 5, 10, 30, 60, 300, 600},
+	}, []string{"job", "result"})
+	// 定时任务获取分布式锁的结果 acquired busy failure
+	JobLock = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_lock_total",
+		Help:      "定时任务获取分布式锁次数",
+	}, []string{"job", "result"})
+
+	// http 请求耗时 按路由模板统计 路径参数不会产生新的标签值
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "http 请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	for _, o := range []*Operation{SendOut, Receive, Transfer, Refund} {
+		prometheus.MustRegister(o.collectors()...)
+	}
+	prometheus.MustRegister(ReceiveConflicts, JobDuration, JobLock, httpDuration)
+}
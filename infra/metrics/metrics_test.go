@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOperation(t *testing.T) {
+	Convey("按结果统计业务操作", t, func() {
+		o := newOperation("test_operation", "测试")
+		start := time.Now()
+		o.ObserveErr(start, nil)
+		o.ObserveErr(start, nil)
+		o.ObserveErr(start, errors.New("失败"))
+		o.Observe(start, "insufficient_funds")
+		So(testutil.ToFloat64(o.total.WithLabelValues(ResultSuccess)), ShouldEqual, 2)
+		So(testutil.ToFloat64(o.total.WithLabelValues(ResultFailure)), ShouldEqual, 1)
+		So(testutil.ToFloat64(o.total.WithLabelValues("insufficient_funds")), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/kataras/iris"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 指标 starter 在 iris 上注册 metrics.path 接口 默认 /metrics 和管理接口一样需要 admin.token
+// 统计所有 http 接口的耗时 和数据库连接池状态
+type MetricsStarter struct {
+	infra.BaseStarter
+}
+
+func (s *MetricsStarter) DependsOn() []string {
+	return []string{"irisServer", "dbxDatabase"}
+}
+
+func (s *MetricsStarter) Setup(ctx infra.StarterContext) {
+	prometheus.MustRegister(newDBStatsCollector())
+	app := base.Iris()
+	// UseGlobal 对已经注册和之后注册的路由都生效
+	app.UseGlobal(httpHandler)
+	path := ctx.Props().GetDefault("metrics.path", "/metrics")
+	app.Get(path, base.AdminAuthHandler(), iris.FromStd(promhttp.Handler()))
+	logrus.Info("指标接口: ", path)
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tietang/dbx"
+	"github.com/tietang/props/kvs"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 数据库迁移命令
+//  migrate up: 执行所有还没有执行的迁移
+//  migrate down [n]: 回退 n 个迁移 默认1个
+//  migrate status: 查看当前版本和还没有执行的迁移
+func Command(conf kvs.ConfigSource, args []string) error {
+	dialect := conf.GetDefault("repository.backend", base.BackendMysql)
+	db, err := open(conf, dialect)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	runner := NewRunner(db, dialect)
+
+	cmd := "status"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "up":
+		count, err := runner.Up()
+		fmt.Printf("执行了 %d 个迁移\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return errors.New("回退数量必须是正整数: " + args[1])
+			}
+		}
+		count, err := runner.Down(steps)
+		fmt.Printf("回退了 %d 个迁移\n", count)
+		return err
+	case "status":
+		applied, err := runner.Applied()
+		if err != nil {
+			return err
+		}
+		for _, a := range applied {
+			fmt.Printf("applied  %4d %-32s %s\n", a.Version, a.Name, a.AppliedAt.Format("2006-01-02 15:04:05"))
+		}
+		pending, err := runner.Pending()
+		for _, m := range pending {
+			fmt.Printf("pending  %4d %s\n", m.Version, m.Name)
+		}
+		return err
+	default:
+		return errors.New("不支持的迁移命令: " + cmd)
+	}
+}
+
+func open(conf kvs.ConfigSource, dialect string) (*sql.DB, error) {
+	switch dialect {
+	case base.BackendMysql:
+		settings := dbx.Settings{}
+		if err := kvs.Unmarshal(conf, &settings, "mysql"); err != nil {
+			return nil, err
+		}
+		db, err := dbx.Open(settings)
+		if err != nil {
+			return nil, err
+		}
+		return db.DB, nil
+	case base.BackendSqlite:
+		return base.OpenSqlite(conf)
+	}
+	return nil, errors.New("不支持的数据库迁移方言: " + dialect)
+}
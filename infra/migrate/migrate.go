@@ -0,0 +1,279 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+var ErrDatabaseAhead = errors.New("数据库版本高于程序的最新迁移版本 请使用新版本的程序")
+
+// 1个版本的数据库迁移
+// Up 升级时按顺序执行 Down 回退时按顺序执行 每条 SQL 1个语句
+// MySQL 的 DDL 会隐式提交 迁移不在事务中执行 每个版本执行成功后记录到 schema_version 表
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+	// 其他数据库方言的 SQL 按 repository.backend 名称索引 Up Down 是 MySQL 方言
+	Dialects map[string]Statements
+}
+
+type Statements struct {
+	Up   []string
+	Down []string
+}
+
+// 指定方言的 SQL 没有该方言的 SQL 时返回错误
+func (m Migration) statements(dialect string) (Statements, error) {
+	if dialect == base.BackendMysql {
+		return Statements{Up: m.Up, Down: m.Down}, nil
+	}
+	if s, ok := m.Dialects[dialect]; ok {
+		return s, nil
+	}
+	return Statements{}, fmt.Errorf("数据库迁移 %d %s 没有 %s 方言的 SQL", m.Version, m.Name, dialect)
+}
+
+// 已注册的迁移 按版本号排序
+var migrations []Migration
+
+// 注册迁移 版本号从1开始递增 在 migrations 包的 init 中注册
+func Register(m Migration) {
+	if m.Version <= 0 {
+		panic(fmt.Sprintf("迁移版本号必须大于0: %d", m.Version))
+	}
+	for _, e := range migrations {
+		if e.Version == m.Version {
+			panic(fmt.Sprintf("迁移版本号重复: %d", m.Version))
+		}
+	}
+	migrations = append(migrations, m)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// 程序的最新迁移版本
+func Latest() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// 已执行的迁移
+type Applied struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+const (
+	// 多个节点同时启动时只有1个节点执行迁移
+	lockName    = "schema_migrate"
+	lockTimeout = 60
+)
+
+var createVersionTable = map[string]string{
+	base.BackendMysql: "create table if not exists `schema_version` (" +
+		"`version` int(10) unsigned not null comment '迁移版本号', " +
+		"`name` varchar(128) not null default '' comment '迁移名称', " +
+		"`applied_at` datetime(3) not null default current_timestamp(3) comment '执行时间', " +
+		"primary key (`version`)" +
+		") engine = InnoDB DEFAULT charset=utf8",
+	base.BackendSqlite: "create table if not exists `schema_version` (" +
+		"`version` integer not null primary key, " +
+		"`name` text not null default '', " +
+		"`applied_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))" +
+		")",
+}
+
+// 迁移执行器 所有操作在同1个数据库连接上执行
+// MySQL 多个节点共享数据库 执行时持有 GET_LOCK 锁 SQLite 只在本进程中使用 不加锁
+type Runner struct {
+	db      *sql.DB
+	dialect string
+}
+
+// dialect 是 repository.backend 的名称 mysql 或 sqlite
+func NewRunner(db *sql.DB, dialect string) *Runner {
+	return &Runner{db: db, dialect: dialect}
+}
+
+func (r *Runner) withConn(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	createTable, ok := createVersionTable[r.dialect]
+	if !ok {
+		return errors.New("不支持的数据库迁移方言: " + r.dialect)
+	}
+	if r.dialect == base.BackendMysql {
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "select get_lock(?, ?)", lockName, lockTimeout).Scan(&locked); err != nil {
+			return err
+		}
+		if locked.Int64 != 1 {
+			return errors.New("等待数据库迁移锁超时")
+		}
+		defer conn.ExecContext(ctx, "select release_lock(?)", lockName)
+	}
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+	return fn(ctx, conn)
+}
+
+func current(ctx context.Context, conn *sql.Conn) (int, error) {
+	var version sql.NullInt64
+	err := conn.QueryRowContext(ctx, "select max(version) from schema_version").Scan(&version)
+	return int(version.Int64), err
+}
+
+// 当前数据库版本 没有执行过迁移时为0
+func (r *Runner) Current() (version int, err error) {
+	err = r.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		version, err = current(ctx, conn)
+		return err
+	})
+	return version, err
+}
+
+// 检查数据库版本 返回还没有执行的迁移 数据库版本高于程序时返回 ErrDatabaseAhead
+func (r *Runner) Pending() (pending []Migration, err error) {
+	err = r.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		version, err := current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		pending, err = pendingAfter(version)
+		return err
+	})
+	return pending, err
+}
+
+func pendingAfter(version int) ([]Migration, error) {
+	if version > Latest() {
+		return nil, ErrDatabaseAhead
+	}
+	pending := make([]Migration, 0)
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// 按版本号顺序执行所有还没有执行的迁移 返回执行的迁移数量
+func (r *Runner) Up() (count int, err error) {
+	err = r.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		version, err := current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		pending, err := pendingAfter(version)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			s, err := m.statements(r.dialect)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("数据库迁移 up %d %s", m.Version, m.Name)
+			if err := execAll(ctx, conn, s.Up); err != nil {
+				return fmt.Errorf("数据库迁移 %d %s 执行失败: %s", m.Version, m.Name, err)
+			}
+			_, err := conn.ExecContext(ctx, "insert into schema_version(version, name) values(?, ?)", m.Version, m.Name)
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// 从当前版本开始回退 steps 个迁移 返回回退的迁移数量
+func (r *Runner) Down(steps int) (count int, err error) {
+	err = r.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		for ; count < steps; count++ {
+			version, err := current(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if version == 0 {
+				return nil
+			}
+			m, ok := find(version)
+			if !ok {
+				return ErrDatabaseAhead
+			}
+			s, err := m.statements(r.dialect)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("数据库迁移 down %d %s", m.Version, m.Name)
+			if err := execAll(ctx, conn, s.Down); err != nil {
+				return fmt.Errorf("数据库迁移 %d %s 回退失败: %s", m.Version, m.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "delete from schema_version where version=?", m.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// 已执行的迁移记录
+func (r *Runner) Applied() (applied []Applied, err error) {
+	err = r.withConn(func(ctx context.Context, conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, "select version, name, applied_at from schema_version order by version")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var a Applied
+			if err := rows.Scan(&a.Version, &a.Name, &a.AppliedAt); err != nil {
+				return err
+			}
+			applied = append(applied, a)
+		}
+		return rows.Err()
+	})
+	return applied, err
+}
+
+func find(version int) (Migration, bool) {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+func execAll(ctx context.Context, conn *sql.Conn, statements []string) error {
+	for _, s := range statements {
+		if _, err := conn.ExecContext(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegister(t *testing.T) {
+	saved := migrations
+	defer func() { migrations = saved }()
+
+	Convey("迁移注册和版本检查", t, func() {
+		migrations = nil
+		Register(Migration{Version: 2, Name: "two"})
+		Register(Migration{Version: 1, Name: "one"})
+		Register(Migration{Version: 3, Name: "three"})
+		So(Latest(), ShouldEqual, 3)
+		So(migrations[0].Name, ShouldEqual, "one")
+		So(func() { Register(Migration{Version: 2}) }, ShouldPanic)
+		So(func() { Register(Migration{Version: 0}) }, ShouldPanic)
+
+		pending, err := pendingAfter(1)
+		So(err, ShouldBeNil)
+		So(len(pending), ShouldEqual, 2)
+		So(pending[0].Version, ShouldEqual, 2)
+
+		pending, err = pendingAfter(3)
+		So(err, ShouldBeNil)
+		So(pending, ShouldBeEmpty)
+
+		_, err = pendingAfter(4)
+		So(err, ShouldEqual, ErrDatabaseAhead)
+	})
+}
+
+func TestMigration_Statements(t *testing.T) {
+	Convey("按方言选择迁移 SQL", t, func() {
+		m := Migration{
+			Version:  1,
+			Name:     "one",
+			Up:       []string{"mysql up"},
+			Down:     []string{"mysql down"},
+			Dialects: map[string]Statements{"sqlite": {Up: []string{"sqlite up"}}},
+		}
+		s, err := m.statements("mysql")
+		So(err, ShouldBeNil)
+		So(s.Up, ShouldResemble, []string{"mysql up"})
+		s, err = m.statements("sqlite")
+		So(err, ShouldBeNil)
+		So(s.Up, ShouldResemble, []string{"sqlite up"})
+		_, err = m.statements("postgres")
+		So(err, ShouldNotBeNil)
+	})
+}
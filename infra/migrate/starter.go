@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 数据库迁移 starter 在数据库 starter 之后检查数据库版本
+// 数据库版本高于程序时禁止启动 配置 mysql.migrate.auto=true 时自动执行还没有执行的迁移
+// sqlite 后端使用 sqlite.migrate.auto memory 后端不需要迁移
+type MigrationStarter struct {
+	infra.BaseStarter
+}
+
+func (s *MigrationStarter) Name() string {
+	return "migrate"
+}
+
+func (s *MigrationStarter) DependsOn() []string {
+	return []string{"dbxDatabase"}
+}
+
+func (s *MigrationStarter) Setup(ctx infra.StarterContext) {
+	dialect := base.Backend()
+	if dialect == base.BackendMemory {
+		return
+	}
+	runner := NewRunner(base.DbxDatabase().DB, dialect)
+	if ctx.Props().GetBoolDefault(dialect+".migrate.auto", false) {
+		count, err := runner.Up()
+		if err != nil {
+			logrus.Panic("数据库迁移失败: ", err)
+		}
+		logrus.Infof("数据库迁移执行了 %d 个版本 当前版本 %d", count, Latest())
+		return
+	}
+	pending, err := runner.Pending()
+	if err != nil {
+		logrus.Panic("数据库版本检查失败: ", err)
+	}
+	for _, m := range pending {
+		logrus.Warnf("数据库迁移 %d %s 还没有执行", m.Version, m.Name)
+	}
+}
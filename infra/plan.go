@@ -0,0 +1,136 @@
+package infra
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/props/kvs"
+)
+
+// 启动计划中的 starter
+type planItem struct {
+	starter Starter
+	name    string
+	// 注册顺序
+	index   int
+	enabled bool
+	// 还没有启动的依赖数量
+	pending int
+	// 依赖当前 starter 的 starter
+	dependents []*planItem
+}
+
+// 排在前面的先启动 非阻塞的在前 然后按优先级 最后按注册顺序
+func (p *planItem) before(o *planItem) bool {
+	if p.starter.StartBlocking() != o.starter.StartBlocking() {
+		return !p.starter.StartBlocking()
+	}
+	if p.starter.Priority() != o.starter.Priority() {
+		return p.starter.Priority() < o.starter.Priority()
+	}
+	return p.index < o.index
+}
+
+// 计算启动计划 返回按启动顺序排列的已启用的 starter
+// 1.starter.<name>.enabled=false 的 starter 不启动 默认启用
+// 2.按依赖关系拓扑排序 被依赖的 starter 先启动
+// 3.同时可以启动的 starter 按 before 排序
+// 依赖的 starter 没有注册或没有启用 存在循环依赖时 禁止启动
+func (r *starterRegister) Plan(conf kvs.ConfigSource) []Starter {
+	items := make([]*planItem, 0, len(r.starters))
+	byName := make(map[string]*planItem, len(r.starters))
+	for i, s := range r.starters {
+		item := &planItem{
+			starter: s,
+			name:    StarterName(s),
+			index:   i,
+			enabled: conf.GetBoolDefault("starter."+StarterName(s)+".enabled", true),
+		}
+		if _, ok := byName[item.name]; ok {
+			panic("starter 名称重复: " + item.name)
+		}
+		byName[item.name] = item
+		items = append(items, item)
+	}
+
+	var ready []*planItem
+	enabled := 0
+	for _, item := range items {
+		if !item.enabled {
+			continue
+		}
+		enabled++
+		for _, dep := range item.starter.DependsOn() {
+			d, ok := byName[dep]
+			if !ok {
+				panic(fmt.Sprintf("starter %s 依赖的 %s 没有注册", item.name, dep))
+			}
+			if !d.enabled {
+				panic(fmt.Sprintf("starter %s 依赖的 %s 没有启用", item.name, dep))
+			}
+			item.pending++
+			d.dependents = append(d.dependents, item)
+		}
+		if item.pending == 0 {
+			ready = append(ready, item)
+		}
+	}
+
+	plan := make([]*planItem, 0, enabled)
+	for len(ready) > 0 {
+		next := 0
+		for i := 1; i < len(ready); i++ {
+			if ready[i].before(ready[next]) {
+				next = i
+			}
+		}
+		item := ready[next]
+		ready = append(ready[:next], ready[next+1:]...)
+		plan = append(plan, item)
+		for _, d := range item.dependents {
+			d.pending--
+			if d.pending == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(plan) < enabled {
+		cycle := make([]string, 0)
+		for _, item := range items {
+			if item.enabled && item.pending > 0 {
+				cycle = append(cycle, item.name)
+			}
+		}
+		panic("starter 存在循环依赖: " + strings.Join(cycle, ", "))
+	}
+
+	logrus.Info(formatPlan(plan, items))
+	starters := make([]Starter, 0, len(plan))
+	for _, item := range plan {
+		starters = append(starters, item.starter)
+	}
+	return starters
+}
+
+func formatPlan(plan, items []*planItem) string {
+	buf := bytes.NewBufferString("Boot plan:")
+	for i, item := range plan {
+		fmt.Fprintf(buf, "\n  %2d. %-16s %-36s priority=%d", i+1, item.name,
+			reflect.TypeOf(item.starter).String(), item.starter.Priority())
+		if item.starter.StartBlocking() {
+			buf.WriteString(" blocking")
+		}
+		if deps := item.starter.DependsOn(); len(deps) > 0 {
+			fmt.Fprintf(buf, " depends=%s", strings.Join(deps, ","))
+		}
+	}
+	for _, item := range items {
+		if !item.enabled {
+			fmt.Fprintf(buf, "\n   -. %-16s %-36s disabled", item.name, reflect.TypeOf(item.starter).String())
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,67 @@
+package infra
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tietang/props/kvs"
+)
+
+type testStarter struct {
+	BaseStarter
+	name     string
+	priority int
+	deps     []string
+	blocking bool
+}
+
+func (s *testStarter) Name() string        { return s.name }
+func (s *testStarter) Priority() int       { return s.priority }
+func (s *testStarter) DependsOn() []string { return s.deps }
+func (s *testStarter) StartBlocking() bool { return s.blocking }
+
+func planNames(r *starterRegister, conf map[string]string) []string {
+	names := make([]string, 0)
+	for _, s := range r.Plan(kvs.NewMapConfigSource("test", conf)) {
+		names = append(names, StarterName(s))
+	}
+	return names
+}
+
+func TestStarterRegister_Plan(t *testing.T) {
+	Convey("启动计划", t, func() {
+		r := new(starterRegister)
+		r.Register(&testStarter{name: "web", priority: DefaultPriority, deps: []string{"server"}})
+		r.Register(&testStarter{name: "server", priority: DefaultPriority, deps: []string{"props"}, blocking: true})
+		r.Register(&testStarter{name: "db", priority: DefaultPriority, deps: []string{"props"}})
+		r.Register(&testStarter{name: "job", priority: DefaultPriority, deps: []string{"db"}})
+		r.Register(&testStarter{name: "props", priority: HighestPriority})
+
+		Convey("按依赖和优先级排序 阻塞的在依赖允许的情况下排在最后", func() {
+			So(planNames(r, nil), ShouldResemble, []string{"props", "db", "job", "server", "web"})
+		})
+		Convey("按配置禁用", func() {
+			So(planNames(r, map[string]string{"starter.job.enabled": "false"}),
+				ShouldResemble, []string{"props", "db", "server", "web"})
+		})
+		Convey("依赖的 starter 被禁用时禁止启动", func() {
+			So(func() { planNames(r, map[string]string{"starter.db.enabled": "false"}) }, ShouldPanic)
+		})
+		Convey("循环依赖", func() {
+			r.Register(&testStarter{name: "a", deps: []string{"b"}})
+			r.Register(&testStarter{name: "b", deps: []string{"a"}})
+			So(func() { planNames(r, nil) }, ShouldPanic)
+		})
+		Convey("依赖没有注册", func() {
+			r.Register(&testStarter{name: "c", deps: []string{"unknown"}})
+			So(func() { planNames(r, nil) }, ShouldPanic)
+		})
+	})
+}
+
+func TestStarterName(t *testing.T) {
+	Convey("默认 starter 名称", t, func() {
+		So(StarterName(&WebApiStarter{}), ShouldEqual, "webApi")
+		So(StarterName(&testStarter{name: "x"}), ShouldEqual, "x")
+	})
+}
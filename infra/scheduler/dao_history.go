@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+)
+
+// error varchar(512)
+const maxErrorLength = 512
+
+type JobHistoryDao struct {
+	runner *dbx.TxRunner
+}
+
+// 执行记录的写入
+func (dao *JobHistoryDao) Insert(po *JobHistory) (int64, error) {
+	rs, err := dao.runner.Insert(po)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.LastInsertId()
+}
+
+// 任务执行结束 记录执行结果和耗时
+func (dao *JobHistoryDao) Finish(id int64, status RunStatus, errMsg string, finishedAt time.Time, durationMs int64) (int64, error) {
+	if runes := []rune(errMsg); len(runes) > maxErrorLength {
+		errMsg = string(runes[:maxErrorLength])
+	}
+	sql := "update job_history set status=?,error=?,finished_at=?,duration_ms=? where id=?"
+	rs, err := dao.runner.Exec(sql, status, errMsg, finishedAt, durationMs, id)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// 查询任务最近的执行记录 jobName 为空时查询所有任务
+func (dao *JobHistoryDao) FindRecent(jobName string, size int) []*JobHistory {
+	var out []*JobHistory
+	var err error
+	if jobName == "" {
+		err = dao.runner.Find(&out, "select * from job_history order by id desc limit ?", size)
+	} else {
+		err = dao.runner.Find(&out, "select * from job_history where job_name=? order by id desc limit ?", jobName, size)
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return out
+}
+
+// 删除任务在 before 之前开始执行的记录
+func (dao *JobHistoryDao) DeleteBefore(jobName string, before time.Time) (int64, error) {
+	rs, err := dao.runner.Exec("delete from job_history where job_name=? and started_at<?", jobName, before)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+)
+
+type JobStateDao struct {
+	runner *dbx.TxRunner
+}
+
+// 查询任务的暂停状态 没有记录时返回 nil
+func (dao *JobStateDao) GetOne(jobName string) (*JobState, error) {
+	po := &JobState{}
+	ok, err := dao.runner.Get(po, "select * from job_state where job_name=?", jobName)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	return po, nil
+}
+
+// 查询所有任务的暂停状态
+func (dao *JobStateDao) FindAll() ([]*JobState, error) {
+	var out []*JobState
+	err := dao.runner.Find(&out, "select * from job_state")
+	if err != nil {
+		logrus.Error(err)
+	}
+	return out, err
+}
+
+// 设置任务的暂停状态 没有记录时写入
+// 先查询再更新 MySQL 值没有变化时更新的影响行数是0 不能用影响行数判断记录是否存在
+func (dao *JobStateDao) SetPaused(jobName string, paused int) error {
+	po, err := dao.GetOne(jobName)
+	if err != nil {
+		return err
+	}
+	if po == nil {
+		_, err = dao.runner.Exec("insert into job_state(job_name,paused) values(?,?)", jobName, paused)
+	} else {
+		_, err = dao.runner.Exec("update job_state set paused=? where job_name=?", paused, jobName)
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
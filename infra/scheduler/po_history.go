@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// 任务执行状态 执行中 成功 失败
+type RunStatus int
+
+const (
+	RunStatusRunning   RunStatus = 1
+	RunStatusSucceeded RunStatus = 2
+	RunStatusFailed    RunStatus = 3
+)
+
+// 任务触发方式 执行计划 启动时 手动触发
+type RunTrigger int
+
+const (
+	TriggerSchedule RunTrigger = 1
+	TriggerStartup  RunTrigger = 2
+	TriggerManual   RunTrigger = 3
+)
+
+// 任务执行记录 映射 job_history 表
+type JobHistory struct {
+	Id         int64      `db:"id,omitempty" json:"id"`
+	JobName    string     `db:"job_name" json:"jobName"`
+	Node       string     `db:"node" json:"node"`
+	Trigger    RunTrigger `db:"trigger_type" json:"trigger"`
+	Status     RunStatus  `db:"status" json:"status"`
+	Error      string     `db:"error" json:"error"`
+	StartedAt  time.Time  `db:"started_at" json:"startedAt"`
+	FinishedAt time.Time  `db:"finished_at" json:"finishedAt"`
+	DurationMs int64      `db:"duration_ms" json:"durationMs"`
+	CreatedAt  time.Time  `db:"created_at,omitempty" json:"-"`
+	UpdatedAt  time.Time  `db:"updated_at,omitempty" json:"-"`
+}
@@ -0,0 +1,13 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// 任务暂停状态 映射 job_state 表 所有节点共用
+type JobState struct {
+	JobName   string    `db:"job_name"`
+	Paused    int       `db:"paused"` // 0执行 1暂停
+	CreatedAt time.Time `db:"created_at,omitempty"`
+	UpdatedAt time.Time `db:"updated_at,omitempty"`
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/dbx"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/memdb"
+)
+
+// 执行记录仓储 方法语义和 JobHistoryDao 相同
+type JobHistoryRepository interface {
+	Insert(po *JobHistory) (int64, error)
+	Finish(id int64, status RunStatus, errMsg string, finishedAt time.Time, durationMs int64) (int64, error)
+	FindRecent(jobName string, size int) []*JobHistory
+	DeleteBefore(jobName string, before time.Time) (int64, error)
+}
+
+// 暂停状态仓储 方法语义和 JobStateDao 相同
+type JobStateRepository interface {
+	GetOne(jobName string) (*JobState, error)
+	FindAll() ([]*JobState, error)
+	SetPaused(jobName string, paused int) error
+}
+
+// 根据事务类型创建仓储 tx 来自 base.Transact
+func NewJobHistoryRepository(tx base.Transaction) JobHistoryRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &JobHistoryDao{runner: t}
+	case *memdb.Tx:
+		return &memJobHistoryRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
+
+func NewJobStateRepository(tx base.Transaction) JobStateRepository {
+	switch t := tx.(type) {
+	case *dbx.TxRunner:
+		return &JobStateDao{runner: t}
+	case *memdb.Tx:
+		return &memJobStateRepository{tx: t}
+	}
+	logrus.Panicf("不支持的事务类型: %T", tx)
+	return nil
+}
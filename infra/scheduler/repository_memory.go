@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+)
+
+// 内存数据库的仓储实现 表中保存持久化对象的值 返回副本
+
+const (
+	tableJobHistory = "job_history"
+	tableJobState   = "job_state"
+)
+
+type memJobHistoryRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memJobHistoryRepository) Insert(po *JobHistory) (int64, error) {
+	row := *po
+	row.Id = r.tx.NextId(tableJobHistory)
+	row.CreatedAt = time.Now()
+	row.UpdatedAt = row.CreatedAt
+	r.tx.Put(tableJobHistory, row.Id, row)
+	return row.Id, nil
+}
+
+func (r *memJobHistoryRepository) Finish(id int64, status RunStatus, errMsg string, finishedAt time.Time, durationMs int64) (int64, error) {
+	row, ok := r.tx.Get(tableJobHistory, id)
+	if !ok {
+		return 0, nil
+	}
+	if runes := []rune(errMsg); len(runes) > maxErrorLength {
+		errMsg = string(runes[:maxErrorLength])
+	}
+	h := row.(JobHistory)
+	h.Status = status
+	h.Error = errMsg
+	h.FinishedAt = finishedAt
+	h.DurationMs = durationMs
+	h.UpdatedAt = time.Now()
+	r.tx.Put(tableJobHistory, id, h)
+	return 1, nil
+}
+
+// 按 id 倒序 jobName 为空时查询所有任务
+func (r *memJobHistoryRepository) FindRecent(jobName string, size int) []*JobHistory {
+	var out []*JobHistory
+	r.tx.Scan(tableJobHistory, func(id int64, row interface{}) bool {
+		h := row.(JobHistory)
+		if jobName == "" || h.JobName == jobName {
+			out = append(out, &h)
+		}
+		return true
+	})
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	if size < len(out) {
+		out = out[:size]
+	}
+	return out
+}
+
+func (r *memJobHistoryRepository) DeleteBefore(jobName string, before time.Time) (int64, error) {
+	var ids []int64
+	r.tx.Scan(tableJobHistory, func(id int64, row interface{}) bool {
+		h := row.(JobHistory)
+		if h.JobName == jobName && h.StartedAt.Before(before) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	for _, id := range ids {
+		r.tx.Delete(tableJobHistory, id)
+	}
+	return int64(len(ids)), nil
+}
+
+type memJobStateRepository struct {
+	tx *memdb.Tx
+}
+
+func (r *memJobStateRepository) find(jobName string) (int64, *JobState) {
+	var id int64
+	var out *JobState
+	r.tx.Scan(tableJobState, func(rowId int64, row interface{}) bool {
+		s := row.(JobState)
+		if s.JobName == jobName {
+			id, out = rowId, &s
+			return false
+		}
+		return true
+	})
+	return id, out
+}
+
+func (r *memJobStateRepository) GetOne(jobName string) (*JobState, error) {
+	_, s := r.find(jobName)
+	return s, nil
+}
+
+func (r *memJobStateRepository) FindAll() ([]*JobState, error) {
+	var out []*JobState
+	r.tx.Scan(tableJobState, func(id int64, row interface{}) bool {
+		s := row.(JobState)
+		out = append(out, &s)
+		return true
+	})
+	return out, nil
+}
+
+// job_name 是主键 没有记录时写入
+func (r *memJobStateRepository) SetPaused(jobName string, paused int) error {
+	now := time.Now()
+	id, s := r.find(jobName)
+	if s == nil {
+		id = r.tx.NextId(tableJobState)
+		s = &JobState{JobName: jobName, CreatedAt: now}
+	}
+	s.Paused = paused
+	s.UpdatedAt = now
+	r.tx.Put(tableJobState, id, *s)
+	return nil
+}
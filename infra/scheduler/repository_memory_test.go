@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/solozyx/red-envelope/infra/memdb"
+)
+
+func TestMemJobRepository(t *testing.T) {
+	Convey("内存任务仓储", t, func() {
+		db := memdb.New()
+		err := db.Tx(func(tx *memdb.Tx) error {
+			history := NewJobHistoryRepository(tx)
+			now := time.Now()
+			old, _ := history.Insert(&JobHistory{JobName: "refund", StartedAt: now.Add(-2 * time.Hour)})
+			id, _ := history.Insert(&JobHistory{JobName: "refund", StartedAt: now, Status: RunStatusRunning})
+			history.Insert(&JobHistory{JobName: "publish", StartedAt: now})
+
+			rows, err := history.Finish(id, RunStatusSucceeded, "", now, 10)
+			So(err, ShouldBeNil)
+			So(rows, ShouldEqual, 1)
+			recent := history.FindRecent("refund", 10)
+			So(len(recent), ShouldEqual, 2)
+			So(recent[0].Id, ShouldEqual, id)
+			So(recent[0].Status, ShouldEqual, RunStatusSucceeded)
+			So(len(history.FindRecent("", 2)), ShouldEqual, 2)
+
+			count, err := history.DeleteBefore("refund", now.Add(-time.Hour))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+			So(history.FindRecent("refund", 10)[0].Id, ShouldNotEqual, old)
+			So(len(history.FindRecent("refund", 10)), ShouldEqual, 1)
+
+			state := NewJobStateRepository(tx)
+			s, err := state.GetOne("refund")
+			So(err, ShouldBeNil)
+			So(s, ShouldBeNil)
+			So(state.SetPaused("refund", 1), ShouldBeNil)
+			So(state.SetPaused("refund", 0), ShouldBeNil)
+			s, _ = state.GetOne("refund")
+			So(s.Paused, ShouldEqual, 0)
+			all, _ := state.FindAll()
+			So(len(all), ShouldEqual, 1)
+			return nil
+		})
+		So(err, ShouldBeNil)
+	})
+}
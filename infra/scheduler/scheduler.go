@@ -0,0 +1,410 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/errs"
+	"github.com/solozyx/red-envelope/infra/lock"
+	"github.com/solozyx/red-envelope/infra/metrics"
+)
+
+// 定时任务
+// 应用停止或者任务的分布式锁丢失时 ctx 被取消 任务应该尽快结束
+type Job interface {
+	Run(ctx context.Context) error
+}
+
+// 函数形式的定时任务
+type JobFunc func(ctx context.Context) error
+
+func (f JobFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+var (
+	ErrJobNotFound  = errs.New(errs.NotFound, "job.not_found", "任务不存在")
+	ErrJobRunning   = errs.New(errs.FailedPrecondition, "job.running", "任务正在执行")
+	ErrJobTriggered = errs.New(errs.FailedPrecondition, "job.triggered", "任务已经触发 等待执行")
+)
+
+// 已注册的任务 在 SchedulerStarter 初始化之前注册
+var registered = make(map[string]Job)
+
+// 按名称注册任务 执行计划在配置 [jobs] 中指定
+//  jobs.<name>.enabled: 是否启用 默认启用
+//  jobs.<name>.spec: cron 表达式或固定间隔 没有配置时使用 jobs.<name>.interval
+//  jobs.<name>.paused: 是否暂停 管理接口暂停或恢复过的任务使用 job_state 表中的状态
+//  jobs.<name>.runOnStart: 启动时是否立即执行1次
+//  jobs.<name>.lockExpiry: 分布式锁的过期时间 任务执行期间由看门狗自动续期
+func Register(name string, job Job) {
+	if _, ok := registered[name]; ok {
+		panic("任务重复注册: " + name)
+	}
+	registered[name] = job
+}
+
+// 任务状态
+type JobInfo struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`
+	Paused  bool      `json:"paused"`
+	Running bool      `json:"running"`
+	NextRun time.Time `json:"nextRun"`
+	LastRun time.Time `json:"lastRun"`
+	LastErr string    `json:"lastErr"`
+}
+
+type entry struct {
+	name       string
+	job        Job
+	spec       string
+	schedule   Schedule
+	runOnStart bool
+	lockExpiry time.Duration
+	mutex      *lock.Mutex
+	running    int32
+	trigger    chan struct{}
+
+	// job_state 表中没有记录时使用配置的暂停状态
+	configPaused bool
+	// 暂停状态的缓存 每次执行前和查询任务状态时从 job_state 表更新
+	paused int32
+
+	mu      sync.Mutex
+	nextRun time.Time
+	lastRun time.Time
+	lastErr string
+}
+
+// 任务调度器 每个任务1个 goroutine 按执行计划执行
+// 同1个任务在所有节点中同时只有1个在执行 由分布式锁保证
+type Scheduler struct {
+	entries map[string]*entry
+	node    string
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	// 所有任务执行 ctx 的父 ctx 停止调度时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 执行记录保留时长 0表示不清理
+	historyRetention time.Duration
+}
+
+func newScheduler() *Scheduler {
+	node, err := os.Hostname()
+	if err != nil {
+		node = "unknown"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		entries: make(map[string]*entry),
+		node:    fmt.Sprintf("%s:%d", node, os.Getpid()),
+		stop:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (s *Scheduler) start() {
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+}
+
+// 停止调度 通知正在执行的任务结束 并等待任务返回
+func (s *Scheduler) shutdown() {
+	close(s.stop)
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(e *entry) {
+	defer s.wg.Done()
+	if e.runOnStart {
+		s.run(e, TriggerStartup)
+	}
+	for {
+		next := e.schedule.Next(time.Now())
+		e.mu.Lock()
+		e.nextRun = next
+		e.mu.Unlock()
+		// 没有下一次执行时间 只能手动触发
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timeout = timer.C
+		}
+		select {
+		case <-s.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-e.trigger:
+			if timer != nil {
+				timer.Stop()
+			}
+			s.run(e, TriggerManual)
+		case <-timeout:
+			s.run(e, TriggerSchedule)
+		}
+	}
+}
+
+// 获取分布式锁后执行任务 并记录执行历史
+// 暂停状态在获取分布式锁之后检查 所有节点都能看到其他节点的暂停和恢复 手动触发不检查暂停状态
+func (s *Scheduler) run(e *entry, trigger RunTrigger) {
+	atomic.StoreInt32(&e.running, 1)
+	defer atomic.StoreInt32(&e.running, 0)
+	if err := e.mutex.Lock(); err != nil {
+		if err == lock.ErrNotObtained {
+			metrics.JobLock.WithLabelValues(e.name, metrics.ResultBusy).Inc()
+		} else {
+			metrics.JobLock.WithLabelValues(e.name, metrics.ResultFailure).Inc()
+		}
+		logrus.Debugf("任务%s已经有节点在执行: %s", e.name, err)
+		return
+	}
+	metrics.JobLock.WithLabelValues(e.name, metrics.ResultAcquired).Inc()
+	defer func() {
+		if err := e.mutex.Unlock(); err != nil {
+			logrus.Errorf("任务%s释放锁失败: %s", e.name, err)
+		}
+	}()
+	if trigger != TriggerManual && s.isPaused(e) {
+		logrus.Debugf("任务%s已暂停", e.name)
+		return
+	}
+
+	startedAt := time.Now()
+	history := &JobHistory{
+		JobName:    e.name,
+		Node:       s.node,
+		Trigger:    trigger,
+		Status:     RunStatusRunning,
+		StartedAt:  startedAt,
+		FinishedAt: startedAt,
+	}
+	var id int64
+	err := base.Transact(context.Background(), func(tx base.Transaction) (err error) {
+		id, err = NewJobHistoryRepository(tx).Insert(history)
+		return err
+	})
+	if err != nil {
+		// 执行记录写入失败不影响任务执行
+		logrus.Errorf("任务%s执行记录写入失败: %s", e.name, err)
+	}
+
+	logrus.Debugf("任务%s开始执行", e.name)
+	ctx, cancel := context.WithCancel(s.ctx)
+	go func() {
+		// 锁丢失后其他节点可能已经开始执行 当前节点的任务需要尽快结束
+		select {
+		case <-e.mutex.Lost():
+			logrus.Warnf("任务%s的分布式锁已丢失", e.name)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	runErr := safeRun(ctx, e.job)
+	cancel()
+	finishedAt := time.Now()
+	status, errMsg, result := RunStatusSucceeded, "", metrics.ResultSuccess
+	if runErr != nil {
+		status, errMsg, result = RunStatusFailed, runErr.Error(), metrics.ResultFailure
+		logrus.Errorf("任务%s执行失败: %s", e.name, runErr)
+	}
+	metrics.JobDuration.WithLabelValues(e.name, result).Observe(finishedAt.Sub(startedAt).Seconds())
+	e.mu.Lock()
+	e.lastRun = startedAt
+	e.lastErr = errMsg
+	e.mu.Unlock()
+
+	if id > 0 {
+		err = base.Transact(context.Background(), func(tx base.Transaction) error {
+			_, err := NewJobHistoryRepository(tx).Finish(id, status, errMsg, finishedAt, int64(finishedAt.Sub(startedAt)/time.Millisecond))
+			return err
+		})
+		if err != nil {
+			logrus.Errorf("任务%s执行结果记录失败: %s", e.name, err)
+		}
+	}
+	if s.historyRetention > 0 {
+		s.cleanHistory(e)
+	}
+	logrus.Debugf("任务%s执行结束 耗时%s", e.name, finishedAt.Sub(startedAt))
+}
+
+// 从 job_state 表读取任务的暂停状态并更新缓存 读取失败时使用缓存的状态
+func (s *Scheduler) isPaused(e *entry) bool {
+	var state *JobState
+	err := base.Transact(context.Background(), func(tx base.Transaction) (err error) {
+		state, err = NewJobStateRepository(tx).GetOne(e.name)
+		return err
+	})
+	if err != nil {
+		logrus.Errorf("任务%s暂停状态读取失败: %s", e.name, err)
+	} else {
+		e.setPaused(state)
+	}
+	return atomic.LoadInt32(&e.paused) == 1
+}
+
+// 更新暂停状态的缓存 没有记录时使用配置的暂停状态
+func (e *entry) setPaused(state *JobState) {
+	paused := e.configPaused
+	if state != nil {
+		paused = state.Paused == 1
+	}
+	if paused {
+		atomic.StoreInt32(&e.paused, 1)
+	} else {
+		atomic.StoreInt32(&e.paused, 0)
+	}
+}
+
+// 删除超过保留时长的执行记录 在分布式锁内执行 同1个任务只有1个节点在清理
+func (s *Scheduler) cleanHistory(e *entry) {
+	before := time.Now().Add(-s.historyRetention)
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		count, err := NewJobHistoryRepository(tx).DeleteBefore(e.name, before)
+		if count > 0 {
+			logrus.Debugf("任务%s删除了%d条过期的执行记录", e.name, count)
+		}
+		return err
+	})
+	if err != nil {
+		logrus.Errorf("任务%s过期执行记录删除失败: %s", e.name, err)
+	}
+}
+
+// 任务中的 panic 不能让调度 goroutine 退出
+func safeRun(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("任务 panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("任务 panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) get(name string) (*entry, error) {
+	e, ok := s.entries[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return e, nil
+}
+
+// 所有任务的状态 按名称排序 暂停状态从 job_state 表读取
+func (s *Scheduler) Jobs() []JobInfo {
+	var states []*JobState
+	err := base.Transact(context.Background(), func(tx base.Transaction) (err error) {
+		states, err = NewJobStateRepository(tx).FindAll()
+		return err
+	})
+	if err != nil {
+		logrus.Error("任务暂停状态读取失败: ", err)
+	} else {
+		byName := make(map[string]*JobState, len(states))
+		for _, state := range states {
+			byName[state.JobName] = state
+		}
+		for _, e := range s.entries {
+			e.setPaused(byName[e.name])
+		}
+	}
+	infos := make([]JobInfo, 0, len(s.entries))
+	for _, e := range s.entries {
+		e.mu.Lock()
+		infos = append(infos, JobInfo{
+			Name:    e.name,
+			Spec:    e.spec,
+			Paused:  atomic.LoadInt32(&e.paused) == 1,
+			Running: atomic.LoadInt32(&e.running) == 1,
+			NextRun: e.nextRun,
+			LastRun: e.lastRun,
+			LastErr: e.lastErr,
+		})
+		e.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// 暂停任务 状态保存在 job_state 表 对所有节点生效 正在执行的任务不受影响
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// 恢复任务 对所有节点生效
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	e, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	state := &JobState{JobName: name}
+	if paused {
+		state.Paused = 1
+	}
+	err = base.Transact(context.Background(), func(tx base.Transaction) error {
+		return NewJobStateRepository(tx).SetPaused(name, state.Paused)
+	})
+	if err != nil {
+		return err
+	}
+	e.setPaused(state)
+	return nil
+}
+
+// 立即执行1次任务 暂停的任务也可以手动触发
+// 任务正在当前节点执行 或已经触发还没有开始执行时 返回错误 不会重复触发
+// 其他节点正在执行时 当前节点获取分布式锁失败 本次触发被跳过
+func (s *Scheduler) Trigger(name string) error {
+	e, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&e.running) == 1 {
+		return ErrJobRunning.With("name", name)
+	}
+	select {
+	case e.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrJobTriggered.With("name", name)
+	}
+}
+
+// 任务最近的执行记录
+func (s *Scheduler) History(name string, size int) []*JobHistory {
+	var out []*JobHistory
+	err := base.Transact(context.Background(), func(tx base.Transaction) error {
+		out = NewJobHistoryRepository(tx).FindRecent(name, size)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 任务执行计划 返回 t 之后的下一次执行时间 零值表示不再执行
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// 固定间隔执行
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cron 表达式
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// 日 星期 是否是 * 都不是 * 时满足其一即可 和标准 cron 一致
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var (
+	secondBounds = bounds{0, 59}
+	minuteBounds = bounds{0, 59}
+	hourBounds   = bounds{0, 23}
+	domBounds    = bounds{1, 31}
+	monthBounds  = bounds{1, 12}
+	dowBounds    = bounds{0, 7}
+)
+
+// 预定义的 cron 表达式
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// 解析任务执行计划 支持以下格式
+//  @every 1m: 固定间隔
+//  1m: 固定间隔 兼容 jobs.xxx.interval 的配置
+//  */5 * * * *: 5段 cron 表达式 分 时 日 月 星期
+//  0 */5 * * * *: 6段 cron 表达式 秒 分 时 日 月 星期
+//  @hourly @daily @weekly @monthly @yearly: 预定义的 cron 表达式
+func ParseSpec(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errors.New("任务执行计划为空")
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		return parseInterval(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+	}
+	if cron, ok := descriptors[spec]; ok {
+		spec = cron
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+		return parseInterval(spec)
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron 表达式需要5段或6段: %s", spec)
+	}
+	return parseCron(fields)
+}
+
+func parseInterval(s string) (Schedule, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	if d < time.Second {
+		return nil, fmt.Errorf("任务执行间隔不能小于1秒: %s", s)
+	}
+	return intervalSchedule{interval: d}, nil
+}
+
+func parseCron(fields []string) (Schedule, error) {
+	var err error
+	s := &cronSchedule{}
+	parsers := []struct {
+		field  *uint64
+		bounds bounds
+	}{
+		{&s.second, secondBounds},
+		{&s.minute, minuteBounds},
+		{&s.hour, hourBounds},
+		{&s.dom, domBounds},
+		{&s.month, monthBounds},
+		{&s.dow, dowBounds},
+	}
+	for i, p := range parsers {
+		*p.field, err = parseField(fields[i], p.bounds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 星期日 0 和 7 都可以
+	if s.dow&(1<<7) > 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[3] == "*" || fields[3] == "?"
+	s.dowStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+// 解析1段 cron 表达式 支持 * ? a a-b */n a-b/n 和逗号分隔的列表
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		rangeAndStep := strings.SplitN(expr, "/", 2)
+		start, end := b.min, b.max
+		step := 1
+		switch r := rangeAndStep[0]; {
+		case r == "*" || r == "?":
+		case strings.Contains(r, "-"):
+			lowAndHigh := strings.SplitN(r, "-", 2)
+			var err error
+			if start, err = parseNumber(lowAndHigh[0], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseNumber(lowAndHigh[1], b); err != nil {
+				return 0, err
+			}
+		default:
+			n, err := parseNumber(r, b)
+			if err != nil {
+				return 0, err
+			}
+			start = n
+			end = n
+			// a/n 表示从 a 开始到最大值
+			if len(rangeAndStep) == 2 {
+				end = b.max
+			}
+		}
+		if len(rangeAndStep) == 2 {
+			n, err := strconv.Atoi(rangeAndStep[1])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("cron 表达式步长错误: %s", expr)
+			}
+			step = n
+		}
+		if start > end {
+			return 0, fmt.Errorf("cron 表达式范围错误: %s", expr)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func parseNumber(s string, b bounds) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cron 表达式数值错误: %s", s)
+	}
+	if n < b.min || n > b.max {
+		return 0, fmt.Errorf("cron 表达式数值 %d 超出范围 [%d,%d]", n, b.min, b.max)
+	}
+	return n, nil
+}
+
+// 从 t 的下一秒开始 由大到小逐个字段寻找匹配的时间
+// 某个字段进位后 更小的字段都从最小值重新开始 跨年后重新检查所有字段
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, loc)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) > 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) > 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseSpec(t *testing.T) {
+	base := time.Date(2020, 1, 31, 23, 59, 30, 500, time.Local)
+	next := func(spec string, from time.Time) time.Time {
+		s, err := ParseSpec(spec)
+		So(err, ShouldBeNil)
+		return s.Next(from)
+	}
+	Convey("固定间隔", t, func() {
+		So(next("@every 1m", base), ShouldEqual, base.Add(time.Minute))
+		So(next("10s", base), ShouldEqual, base.Add(10*time.Second))
+	})
+	Convey("cron 表达式", t, func() {
+		So(next("* * * * *", base), ShouldEqual, time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local))
+		So(next("*/5 * * * * *", base), ShouldEqual, time.Date(2020, 1, 31, 23, 59, 35, 0, time.Local))
+		So(next("30 2 * * *", base), ShouldEqual, time.Date(2020, 2, 1, 2, 30, 0, 0, time.Local))
+		// 2020-02-29 闰年
+		So(next("0 0 29 2 *", base), ShouldEqual, time.Date(2020, 2, 29, 0, 0, 0, 0, time.Local))
+		// 2020-02-03 星期一
+		So(next("0 9 * * 1-5", base), ShouldEqual, time.Date(2020, 2, 3, 9, 0, 0, 0, time.Local))
+		So(next("@monthly", base), ShouldEqual, time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local))
+		// 星期日 7 和 0 相同 2020-02-02 星期日
+		So(next("0 0 * * 7", base), ShouldEqual, time.Date(2020, 2, 2, 0, 0, 0, 0, time.Local))
+	})
+	Convey("错误的执行计划", t, func() {
+		for _, spec := range []string{"", "abc", "500ms", "* * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+			_, err := ParseSpec(spec)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
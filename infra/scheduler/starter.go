@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/lock"
+)
+
+var scheduler *Scheduler
+
+func GetScheduler() *Scheduler {
+	if scheduler == nil {
+		panic("任务调度器还没有被初始化")
+	}
+	return scheduler
+}
+
+// 任务调度器 starter 依赖数据库和分布式锁 starter
+type SchedulerStarter struct {
+	infra.BaseStarter
+}
+
+func (s *SchedulerStarter) DependsOn() []string {
+	return []string{"dbxDatabase", "lock"}
+}
+
+// 读取任务的执行计划 执行计划配置错误禁止启动
+func (s *SchedulerStarter) Init(ctx infra.StarterContext) {
+	logrus.Info("SchedulerStarter Init()")
+	props := ctx.Props()
+	sc := newScheduler()
+	sc.historyRetention = props.GetDurationDefault("jobs.historyRetention", 30*24*time.Hour)
+	for name, job := range registered {
+		prefix := "jobs." + name
+		if !props.GetBoolDefault(prefix+".enabled", true) {
+			logrus.Infof("任务 %s 没有启用 %s.enabled=false", name, prefix)
+			continue
+		}
+		spec := props.GetDefault(prefix+".spec", props.GetDefault(prefix+".interval", ""))
+		schedule, err := ParseSpec(spec)
+		if err != nil {
+			logrus.Panicf("任务%s的执行计划配置错误 %s.spec=%s: %s", name, prefix, spec, err)
+		}
+		e := &entry{
+			name:       name,
+			job:        job,
+			spec:       spec,
+			schedule:   schedule,
+			runOnStart: props.GetBoolDefault(prefix+".runOnStart", false),
+			lockExpiry: props.GetDurationDefault(prefix+".lockExpiry", 1*time.Minute),
+			trigger:    make(chan struct{}, 1),
+		}
+		if props.GetBoolDefault(prefix+".paused", false) {
+			e.configPaused = true
+			e.paused = 1
+		}
+		sc.entries[name] = e
+		logrus.Infof("注册任务 %s 执行计划 %s", name, spec)
+	}
+	scheduler = sc
+}
+
+// 分布式锁在 LockStarter 的 Setup 阶段初始化
+func (s *SchedulerStarter) Setup(ctx infra.StarterContext) {
+	for _, e := range scheduler.entries {
+		e.mutex = lock.NewMutex("lock:job:"+e.name, e.lockExpiry)
+	}
+}
+
+func (s *SchedulerStarter) Start(ctx infra.StarterContext) {
+	scheduler.start()
+}
+
+func (s *SchedulerStarter) Stop(ctx infra.StarterContext) {
+	scheduler.shutdown()
+}
@@ -2,13 +2,15 @@ package infra
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tietang/props/kvs"
 )
 
 const (
-	KeyProps = "_conf"
+	KeyProps       = "_conf"
+	KeyApplication = "_app"
 )
 
 // 基础资源上下文
@@ -22,6 +24,14 @@ func (s StarterContext) Props() kvs.ConfigSource {
 	return p.(kvs.ConfigSource)
 }
 
+func (s StarterContext) Application() *BootApplication {
+	app := s[KeyApplication]
+	if app == nil {
+		panic("应用还没有被创建")
+	}
+	return app.(*BootApplication)
+}
+
 // 资源启动器接口
 type Starter interface {
 	// 1.系统启动，初始化一些基础资源
@@ -34,6 +44,39 @@ type Starter interface {
 	StartBlocking() bool
 	// 4.资源停止和销毁
 	Stop(StarterContext)
+	// 启动优先级 没有依赖关系的 starter 按优先级从小到大启动
+	Priority() int
+	// 依赖的 starter 名称 被依赖的 starter 先启动后停止
+	DependsOn() []string
+}
+
+// starter 的启动优先级
+const (
+	HighestPriority = 0
+	DefaultPriority = 100
+	LowestPriority  = 1000
+)
+
+// 可选接口 自定义 starter 名称
+// 默认名称是类型名去掉 Starter 后缀并且首字母小写 比如 DbxDatabaseStarter 的名称是 dbxDatabase
+type NamedStarter interface {
+	Name() string
+}
+
+// starter 名称 用于声明依赖和配置启用开关 starter.<name>.enabled
+func StarterName(s Starter) string {
+	if n, ok := s.(NamedStarter); ok {
+		return n.Name()
+	}
+	typ := reflect.TypeOf(s)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	name := strings.TrimSuffix(typ.Name(), "Starter")
+	if name == "" {
+		return typ.String()
+	}
+	return strings.ToLower(name[:1]) + name[1:]
 }
 
 // 简单的验证 BaseStarter 是否实现了Starter接口
@@ -47,29 +90,35 @@ func (b *BaseStarter) Setup(ctx StarterContext) {}
 func (b *BaseStarter) Start(ctx StarterContext) {}
 func (b *BaseStarter) StartBlocking() bool      { return false }
 func (b *BaseStarter) Stop(ctx StarterContext)  {}
+func (b *BaseStarter) Priority() int            { return DefaultPriority }
+func (b *BaseStarter) DependsOn() []string      { return nil }
 
 // 启动器注册器
 type starterRegister struct {
-	nonBlockingStarters []Starter
-	blockingStarters    []Starter
+	// 按注册顺序
+	starters []Starter
 }
 
 // 注册启动器
 func (r *starterRegister) Register(starter Starter) {
-	if starter.StartBlocking() {
-		r.blockingStarters = append(r.blockingStarters, starter)
-	} else {
-		r.nonBlockingStarters = append(r.nonBlockingStarters, starter)
-	}
+	r.starters = append(r.starters, starter)
 	typ := reflect.TypeOf(starter)
 	logrus.Infof("Register starter: %s", typ.String())
 }
 
-// 返回所有 Starter
+// 返回所有 Starter 非阻塞的在前 阻塞的在后
 func (r *starterRegister) AllStarters() []Starter {
-	starters := make([]Starter, 0)
-	starters = append(starters, r.nonBlockingStarters...)
-	starters = append(starters, r.blockingStarters...)
+	starters := make([]Starter, 0, len(r.starters))
+	for _, s := range r.starters {
+		if !s.StartBlocking() {
+			starters = append(starters, s)
+		}
+	}
+	for _, s := range r.starters {
+		if s.StartBlocking() {
+			starters = append(starters, s)
+		}
+	}
 	return starters
 }
 
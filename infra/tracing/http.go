@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kataras/iris"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// iris 中间件 每个请求1个 span 调用方通过 traceparent 请求头传入的 trace 作为父 span
+// span 通过 base.SetRequestContext 传给 base.RequestContext 处理函数再传给服务
+func httpHandler(ctx iris.Context) {
+	r := ctx.Request()
+	// 不使用 r.Context() 原因参考 base.RequestContext
+	parent := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(r.Header))
+	c, span := base.Tracer().Start(parent, r.Method+" "+r.URL.Path,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.target", r.URL.Path)))
+	defer span.End()
+	base.SetRequestContext(ctx, c)
+
+	ctx.Next()
+
+	// 路由模板作为 span 名称 路径参数不会产生新的名称
+	if route := ctx.GetCurrentRoute(); route != nil {
+		span.SetName(r.Method + " " + route.Path())
+		span.SetAttributes(attribute.String("http.route", route.Path()))
+	}
+	status := ctx.GetStatusCode()
+	span.SetAttributes(
+		attribute.Int("http.status_code", status),
+		attribute.String(base.RequestIdField, ctx.Values().GetString(base.RequestIdField)))
+	if status >= iris.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// net/rpc 没有请求头 trace context 放在 RPC 参数中传递 参考 services.RpcContext
+
+// RPC 调用方 把 ctx 中的 trace context 转换为 RPC 参数
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// RPC 服务端 恢复调用方的 trace context 开始服务端 span 和 base.EndSpan 配合使用
+func StartServer(parent context.Context, carrier map[string]string, name string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(parent, propagation.MapCarrier(carrier))
+	return base.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+}
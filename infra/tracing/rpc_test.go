@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRpcPropagation(t *testing.T) {
+	Convey("RPC 参数传递 trace context", t, func() {
+		provider := sdktrace.NewTracerProvider()
+		defer provider.Shutdown(context.Background())
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+
+		ctx, client := provider.Tracer("test").Start(context.Background(), "client")
+		carrier := Inject(ctx)
+		So(carrier["traceparent"], ShouldNotBeEmpty)
+
+		_, server := StartServer(context.Background(), carrier, "server")
+		So(server.SpanContext().TraceID(), ShouldEqual, client.SpanContext().TraceID())
+		server.End()
+		client.End()
+
+		// 调用方没有传入时开始新的 trace
+		_, root := StartServer(context.Background(), nil, "server")
+		So(root.SpanContext().TraceID(), ShouldNotEqual, client.SpanContext().TraceID())
+		So(trace.SpanContextFromContext(context.Background()).IsValid(), ShouldBeFalse)
+		root.End()
+	})
+}
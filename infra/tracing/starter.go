@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tietang/props/kvs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+// 链路追踪 starter 读取配置文件 [tracing]
+//  exporter: none | stdout | file | otlp none 时不记录 span
+//  file.path: file 导出的文件 每行1个 span
+//  otlp.endpoint: OTLP gRPC 地址 默认本地 collector localhost:4317
+//  otlp.insecure: 是否不使用 TLS
+//  sampler.ratio: 采样率 上游已经采样的请求总是记录
+// 启用后 iris 请求 服务方法 事务 SQL 语句 RPC 调用都会创建 span
+type TracingStarter struct {
+	infra.BaseStarter
+	provider *sdktrace.TracerProvider
+	file     *os.File
+}
+
+func (s *TracingStarter) Name() string {
+	return "tracing"
+}
+
+func (s *TracingStarter) DependsOn() []string {
+	return []string{"props"}
+}
+
+// 在 Init 阶段设置全局的 TracerProvider 数据库等 starter 在 Setup 阶段使用
+func (s *TracingStarter) Init(ctx infra.StarterContext) {
+	conf := ctx.Props()
+	// 跨进程传递 W3C trace context 和 baggage
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	name := conf.GetDefault("tracing.exporter", "none")
+	if name == "none" {
+		return
+	}
+	exporter, err := s.newExporter(conf, name)
+	if err != nil {
+		logrus.Panic("创建链路追踪导出器失败: ", err)
+	}
+	ratio := conf.GetFloat64Default("tracing.sampler.ratio", 1)
+	s.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", conf.GetDefault("app.name", "red_envelope")))),
+	)
+	otel.SetTracerProvider(s.provider)
+	logrus.Infof("链路追踪 exporter=%s sampler.ratio=%v", name, ratio)
+}
+
+func (s *TracingStarter) newExporter(conf kvs.ConfigSource, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		path, _ := filepath.Abs(conf.GetDefault("tracing.file.path", "logs/trace.json"))
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		s.file = file
+		return stdouttrace.New(stdouttrace.WithWriter(file))
+	case "otlp":
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(conf.GetDefault("tracing.otlp.endpoint", "localhost:4317")),
+		}
+		if conf.GetBoolDefault("tracing.otlp.insecure", true) {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		// 不等待连接建立 collector 没有启动不影响应用启动
+		return otlptracegrpc.New(context.Background(), opts...)
+	}
+	logrus.Panic("不支持的链路追踪导出器 tracing.exporter=", name)
+	return nil, nil
+}
+
+// 注册 iris 中间件 iris 没有启用时跳过
+func (s *TracingStarter) Setup(ctx infra.StarterContext) {
+	if s.provider == nil {
+		return
+	}
+	if app := base.Iris(); app != nil {
+		// UseGlobal 对已经注册和之后注册的路由都生效
+		app.UseGlobal(httpHandler)
+	}
+}
+
+// 导出还没有导出的 span 后关闭
+func (s *TracingStarter) Stop(ctx infra.StarterContext) {
+	if s.provider == nil {
+		return
+	}
+	if err := s.provider.Shutdown(context.Background()); err != nil {
+		logrus.Error("关闭链路追踪失败: ", err)
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+}
@@ -15,6 +15,11 @@ type WebApiStarter struct {
 	BaseStarter
 }
 
+// web api 注册路由和参数校验 依赖 iris 和参数验证 starter
+func (s *WebApiStarter) DependsOn() []string {
+	return []string{"irisServer", "validator"}
+}
+
 func (s *WebApiStarter) Setup(ctx StarterContext) {
 	for _, i := range GetApiInitializers() {
 		i.Init()
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/solozyx/red-envelope/core/envelopes"
+	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/delayqueue"
+)
+
+// 红包过期延时队列 starter
+// 红包发布时按过期时间加入延时队列 到期后立即退款 定时任务 refund 作为兜底扫描
+// 进程内的时间轮在启动时从数据库重新加载还没有退款的红包
+type EnvelopeExpiryStarter struct {
+	infra.BaseStarter
+	queue delayqueue.Queue
+}
+
+func (s *EnvelopeExpiryStarter) DependsOn() []string {
+	return []string{"props", "dbxDatabase"}
+}
+
+// 健康检查 redis 延时队列检查 redis 连接
+func (s *EnvelopeExpiryStarter) Check(ctx context.Context) error {
+	if checker, ok := s.queue.(infra.HealthChecker); ok {
+		return checker.Check(ctx)
+	}
+	return nil
+}
+
+func (s *EnvelopeExpiryStarter) Setup(ctx infra.StarterContext) {
+	s.queue = delayqueue.New("envelope:expiry", ctx.Props())
+	envelopes.UseExpiryQueue(s.queue)
+}
+
+func (s *EnvelopeExpiryStarter) Start(ctx infra.StarterContext) {
+	count, err := envelopes.LoadExpiryQueue()
+	if err != nil {
+		logrus.Error("过期延时队列加载失败: ", err)
+	}
+	logrus.Infof("过期延时队列加载了 %d 个红包", count)
+	s.queue.Start(s.expire)
+}
+
+func (s *EnvelopeExpiryStarter) Stop(ctx infra.StarterContext) {
+	s.queue.Stop()
+}
+
+func (s *EnvelopeExpiryStarter) expire(envelopeNo string) {
+	props := base.Props()
+	domain := &envelopes.ExpiredEnvelopeDomain{
+		MaxAttempts:  props.GetIntDefault("jobs.refund.maxAttempts", 5),
+		ClaimTimeout: props.GetDurationDefault("jobs.refund.claimTimeout", 5*time.Minute),
+	}
+	if err := domain.ExpireByEnvelopeNo(envelopeNo); err != nil {
+		logrus.Error("过期红包退款失败: ", envelopeNo, err)
+	}
+}
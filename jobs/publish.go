@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/solozyx/red-envelope/core/envelopes"
+	"github.com/solozyx/red-envelope/infra/scheduler"
+)
+
+func init() {
+	scheduler.Register("publish", &PublishScheduledJob{})
+}
+
+// 预约红包发布 定时任务
+// 发布操作通过乐观锁更新红包状态 多个节点同时执行也只有1个节点能发布成功
+type PublishScheduledJob struct{}
+
+func (j *PublishScheduledJob) Run(ctx context.Context) error {
+	domain := new(envelopes.ScheduledEnvelopeDomain)
+	return domain.Publish()
+}
@@ -1,80 +1,44 @@
 package jobs
 
 import (
-	"fmt"
+	"context"
 	"time"
 
-	"github.com/go-redsync/redsync"
-	"github.com/gomodule/redigo/redis"
 	"github.com/sirupsen/logrus"
 
-	"github.com/solozyx/red-envelope/comm"
 	"github.com/solozyx/red-envelope/core/envelopes"
-	"github.com/solozyx/red-envelope/infra"
+	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/scheduler"
 )
 
-// 过期红包退款 定时任务
-type RefundExpiredJobStarter struct {
-	infra.BaseStarter
-	ticker *time.Ticker
-	mutex  *redsync.Mutex
+func init() {
+	scheduler.Register("refund", &RefundExpiredJob{})
 }
 
-func (r *RefundExpiredJobStarter) Init(ctx infra.StarterContext) {
-	// 创建定时器
-	d := ctx.Props().GetDurationDefault("jobs.refund.interval", 1*time.Minute)
-	r.ticker = time.NewTicker(d)
-
-	// redis
-	maxIdle := ctx.Props().GetIntDefault("redis.maxIdle", 2)
-	maxActive := ctx.Props().GetIntDefault("redis.maxActive", 5)
-	idleTimeout := ctx.Props().GetDurationDefault("redis.idleTimeout", 20*time.Second)
-	addr := ctx.Props().GetDefault("redis.addr", "127.0.0.1:6379")
-
-	pools := make([]redsync.Pool, 0)
-	pool := &redis.Pool{
-		Dial: func() (conn redis.Conn, e error) {
-			return redis.Dial("tcp", addr)
-		},
-		MaxIdle:     maxIdle,
-		MaxActive:   maxActive,
-		IdleTimeout: idleTimeout,
+// 过期红包退款 定时任务
+// 应用停止时完成正在退款的红包后结束
+// 先恢复中断的退款流程 再扫描过期红包发起退款 配置 jobs.refund.runOnStart 在启动时恢复上次宕机时没有完成的退款流程
+type RefundExpiredJob struct{}
+
+func (j *RefundExpiredJob) Run(ctx context.Context) error {
+	props := base.Props()
+	domain := &envelopes.ExpiredEnvelopeDomain{
+		// 并发退款的 worker 数量
+		Concurrency: props.GetIntDefault("jobs.refund.concurrency", 4),
+		// 单个红包最大退款尝试次数
+		MaxAttempts: props.GetIntDefault("jobs.refund.maxAttempts", 5),
+		// 退款认领超时时间
+		ClaimTimeout: props.GetDurationDefault("jobs.refund.claimTimeout", 5*time.Minute),
 	}
-	pools = append(pools, pool)
-	rsync := redsync.New(pools)
-
-	ip := comm.GetIP()
-
-	r.mutex = rsync.NewMutex("lock:RefundExpired",
-		redsync.SetExpiry(50*time.Second),
-		redsync.SetTries(3),
-		redsync.SetGenValueFunc(func() (s string, e error) {
-			now := time.Now()
-			logrus.Infof("节点%s正在执行过期红包的退款业务", ip)
-			return fmt.Sprintf("%d:%s", now.Unix(), ip), nil
-		}))
-}
-
-func (r *RefundExpiredJobStarter) Start(ctx infra.StarterContext) {
-	// Go协程异步执行红包过期退款 定时任务
-	go func() {
-		// 迭代 r.Ticker.C channel 获取到值则触发定时任务
-		for {
-			c := <-r.ticker.C
-			err := r.mutex.Lock()
-			if err == nil {
-				logrus.Debug("过期红包退款开始...", c)
-				// 红包过期退款业务
-				domain := new(envelopes.ExpiredEnvelopeDomain)
-				domain.Expired()
-			} else {
-				logrus.Info("已经有节点在运行该任务,err=", err.Error())
-			}
-			r.mutex.Unlock()
-		}
-	}()
-}
-
-func (r *RefundExpiredJobStarter) Stop(ctx infra.StarterContext) {
-	r.ticker.Stop()
+	// 退款订单超过该时间没有更新 认为退款流程已中断 需要恢复
+	recoverAfter := props.GetDurationDefault("jobs.refund.recoverAfter", 1*time.Minute)
+	recoverErr := domain.Recover(ctx, recoverAfter)
+	if recoverErr != nil {
+		logrus.Error(recoverErr)
+	}
+	// 红包过期退款业务
+	if err := domain.Expired(ctx); err != nil {
+		return err
+	}
+	return recoverErr
 }
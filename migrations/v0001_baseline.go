@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 基线版本 和原来 doc 目录下的建表脚本相同 account.sql envelope.sql user.sql 中的表和系统红包账户
+// 使用 if not exists 和 insert ignore 已经用建表脚本初始化的数据库可以直接执行
+// 之后增加的表和列 每个功能1个迁移
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: []string{
+			"create table if not exists `account`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '账户ID',\n" +
+				"    `account_no` varchar(32) NOT NULL COMMENT '账户编号,账户唯一标识',\n" +
+				"    `account_name` varchar(64) NOT NULL COMMENT '账户名称,用来说明账户的简短描述,账户对应的名称或命名,比如xxx积分,xxx零钱',\n" +
+				"    `account_type` tinyint(2) NOT NULL COMMENT '账户类型，用来区分不同的账户：积分，会员，钱包，红包',\n" +
+				"    `currency_code` char(3) not null default 'CNY' comment '货币类型：CNY人民币，EUR欧元，USD美元。。。',\n" +
+				"    `user_id` varchar(40) not null comment '用户编号，账户所属用户',\n" +
+				"    `username` varchar(64) default '' not null comment '用户名称',\n" +
+				"    `balance` decimal(30,6) unsigned not null default '0.000000' comment '账户可用余额',\n" +
+				"    `status` tinyint(2) not null comment '账户状态：0初始化，1启用，2停用',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `account_no_idx` (`account_no`) using btree,\n" +
+				"    key `id_user_idx` (`user_id`) using btree\n" +
+				")engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"insert ignore into `account` (account_no, account_name, account_type, user_id, username, status)\n" +
+				"    values ('10000020190101010000000000000001','系统红包账户',2,'000000000000000000000000001','系统红包账户',1)",
+			"create table if not exists `account_log`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL auto_increment,\n" +
+				"    `trade_no` varchar(32) not null comment '交易单号 全局不重复字母或数字 唯一性标识',\n" +
+				"    `log_no` varchar(32) not null comment '流水编号 全局不重复字母或数字 唯一性标识',\n" +
+				"    `account_no` varchar(32) NOT NULL COMMENT '账户编号',\n" +
+				"    `target_account_no` varchar(32) NOT NULL COMMENT '目标账户编号',\n" +
+				"    `user_id` varchar(40) not null comment '用户编号，账户所属用户',\n" +
+				"    `username` varchar(64) default '' not null comment '用户名称',\n" +
+				"    `target_user_id` varchar(40) not null comment '目标用户编号，账户所属用户',\n" +
+				"    `target_username` varchar(64) default '' not null comment '目标用户名称',\n" +
+				"    `amount` decimal(30,6) unsigned not null default '0.000000' comment '交易金额',\n" +
+				"    `balance` decimal(30,6) unsigned not null default '0.000000' comment '该交易后的余额',\n" +
+				"    `change_type` tinyint(2) not null default '0' comment '流水交易类型，0创建账户，>0为收入类型，<0为支出类型',\n" +
+				"    `change_flag` tinyint(2) not null default '0' comment '交易变化标识，-1出账，1进账，枚举',\n" +
+				"    `status` tinyint(2) not null default '0' comment '交易状态',\n" +
+				"    `desc` varchar(128) not null comment '交易描述',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    primary key (`id`) using btree,\n" +
+				"    unique key `id_log_no_idx` (`log_no`) using btree,\n" +
+				"    key `id_user_idx` (`user_id`) using btree,\n" +
+				"    key `id_account_idx` (`account_no`) using btree,\n" +
+				"    key `id_trade_idx` (`trade_no`) using btree\n" +
+				")engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"create table if not exists `user`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '账户ID',\n" +
+				"    `username` varchar(64) NOT NULL COMMENT '账户名称,用来说明账户的简短描述,账户对应的名称或命名,比如xxx积分,xxx零钱',\n" +
+				"    `mobile` varchar(13) NOT NULL COMMENT '手机号, unique key',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `mobile_idx` (`mobile`) using btree\n" +
+				")engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"create table if not exists `red_envelope_goods`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `envelope_no` varchar(32) not null comment '红包编号',\n" +
+				"    `envelope_type` tinyint(2) not null comment '包含普通红包，碰运气红包',\n" +
+				"    `user_id` varchar(40) not null comment '用户编号，红包所属用户',\n" +
+				"    `username` varchar(64) default '' not null comment '用户名称',\n" +
+				"    `blessing` varchar(64) default '恭喜发财' not null comment '红包祝福语',\n" +
+				"    `amount` decimal(30,6) unsigned not null default '0.000000' comment '红包总金额',\n" +
+				"    `amount_one` decimal(30,6) unsigned not null default '0.000000' comment '单个红包金额，只属于普通平均分红包',\n" +
+				"    `quantity` int(10) unsigned not null comment '红包总数量',\n" +
+				"    `remain_amount` decimal(30,6) unsigned not null default '0.000000' comment '红包剩余金额',\n" +
+				"    `remain_quantity` int(10) unsigned not null comment '红包剩余数量',\n" +
+				"    `expired_at` datetime(3) not null comment '过期时间',\n" +
+				"    `status` tinyint(2) not null comment '红包/订单状态：0创建，1发布启用，2过期，3失效',\n" +
+				"    `order_type` tinyint(2) not null comment '订单类型，发布单，退款单',\n" +
+				"    `pay_status` tinyint(2) not null comment '支付状态:未支付，支付中，已支付',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    `origin_envelope_no` varchar(32) not null default '' comment '原红包编号',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `envelope_no_idx` (`envelope_no`) using btree ,\n" +
+				"    key `id_user_idx` (`user_id`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"create table if not exists `red_envelope_item`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `item_no` varchar(32) not null comment '红包订单详情编号',\n" +
+				"    `envelope_no` varchar(32) not null comment '红包编号',\n" +
+				"    `recv_user_id` varchar(40) not null comment '红包接受者用户编号',\n" +
+				"    `recv_username` varchar(64) default '' not null comment '红包接受者用户名称',\n" +
+				"    `amount` decimal(30,6) unsigned not null default '0.000000' comment '收到红包金额',\n" +
+				"    `quantity` int(10) unsigned not null comment '大吼道红包数量',\n" +
+				"    `remain_amount` decimal(30,6) unsigned not null default '0.000000' comment '收到后原红包剩余金额',\n" +
+				"    `account_no` varchar(32) not null comment '红包接受者账户编号',\n" +
+				"    `pay_status` tinyint(2) not null comment '支付状态:未支付，支付中，已支付',\n" +
+				"    `desc` varchar(128) not null comment '交易描述',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `item_no_idx` (`item_no`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+		},
+		Down: baselineDown,
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {Up: sqliteBaselineUp, Down: baselineDown},
+		},
+	})
+}
+
+// 回退时按相反的顺序删除所有表 MySQL 和 SQLite 相同
+var baselineDown = []string{
+	"drop table if exists `red_envelope_item`",
+	"drop table if exists `red_envelope_goods`",
+	"drop table if exists `user`",
+	"drop table if exists `account_log`",
+	"drop table if exists `account`",
+}
@@ -0,0 +1,119 @@
+package migrations
+
+// 基线版本的 SQLite 方言 表和索引和 MySQL 相同
+//  1.金额使用 text 保存 decimal 的字符串 SQLite 的 decimal 类型会转换为浮点数丢失精度
+//  2.时间使用本地时区 精确到毫秒 和程序写入的时间参数可以直接比较
+//  3.SQLite 的索引名称在数据库中唯一 重名的索引加上表名前缀
+//  4.使用触发器实现 MySQL 的 on update current_timestamp
+var sqliteBaselineUp = []string{
+	"create table if not exists `account`\n" +
+		"(\n" +
+		"    `id` integer not null primary key autoincrement,\n" +
+		"    `account_no` text not null,\n" +
+		"    `account_name` text not null,\n" +
+		"    `account_type` integer not null,\n" +
+		"    `currency_code` text not null default 'CNY',\n" +
+		"    `user_id` text not null,\n" +
+		"    `username` text not null default '',\n" +
+		"    `balance` text not null default '0.000000',\n" +
+		"    `status` integer not null,\n" +
+		"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+		"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+		")",
+	"create unique index if not exists `account_no_idx` on `account` (`account_no`)",
+	"create index if not exists `account_user_idx` on `account` (`user_id`)",
+	"insert or ignore into `account` (account_no, account_name, account_type, user_id, username, status)\n" +
+		"    values ('10000020190101010000000000000001','系统红包账户',2,'000000000000000000000000001','系统红包账户',1)",
+	"create trigger if not exists `account_updated_at` after update on `account`\n" +
+		"for each row when new.`updated_at` = old.`updated_at`\n" +
+		"begin\n" +
+		"    update `account` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+		"end",
+	"create table if not exists `account_log`\n" +
+		"(\n" +
+		"    `id` integer not null primary key autoincrement,\n" +
+		"    `trade_no` text not null,\n" +
+		"    `log_no` text not null,\n" +
+		"    `account_no` text not null,\n" +
+		"    `target_account_no` text not null,\n" +
+		"    `user_id` text not null,\n" +
+		"    `username` text not null default '',\n" +
+		"    `target_user_id` text not null,\n" +
+		"    `target_username` text not null default '',\n" +
+		"    `amount` text not null default '0.000000',\n" +
+		"    `balance` text not null default '0.000000',\n" +
+		"    `change_type` integer not null default 0,\n" +
+		"    `change_flag` integer not null default 0,\n" +
+		"    `status` integer not null default 0,\n" +
+		"    `desc` text not null,\n" +
+		"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+		")",
+	"create unique index if not exists `account_log_no_idx` on `account_log` (`log_no`)",
+	"create index if not exists `account_log_user_idx` on `account_log` (`user_id`)",
+	"create index if not exists `account_log_account_idx` on `account_log` (`account_no`)",
+	"create index if not exists `account_log_trade_idx` on `account_log` (`trade_no`)",
+	"create table if not exists `user`\n" +
+		"(\n" +
+		"    `id` integer not null primary key autoincrement,\n" +
+		"    `username` text not null,\n" +
+		"    `mobile` text not null,\n" +
+		"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+		"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+		")",
+	"create unique index if not exists `user_mobile_idx` on `user` (`mobile`)",
+	"create trigger if not exists `user_updated_at` after update on `user`\n" +
+		"for each row when new.`updated_at` = old.`updated_at`\n" +
+		"begin\n" +
+		"    update `user` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+		"end",
+	"create table if not exists `red_envelope_goods`\n" +
+		"(\n" +
+		"    `id` integer not null primary key autoincrement,\n" +
+		"    `envelope_no` text not null,\n" +
+		"    `envelope_type` integer not null,\n" +
+		"    `user_id` text not null,\n" +
+		"    `username` text not null default '',\n" +
+		"    `blessing` text not null default '恭喜发财',\n" +
+		"    `amount` text not null default '0.000000',\n" +
+		"    `amount_one` text not null default '0.000000',\n" +
+		"    `quantity` integer not null,\n" +
+		"    `remain_amount` text not null default '0.000000',\n" +
+		"    `remain_quantity` integer not null,\n" +
+		"    `expired_at` datetime not null,\n" +
+		"    `status` integer not null,\n" +
+		"    `order_type` integer not null,\n" +
+		"    `pay_status` integer not null,\n" +
+		"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+		"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+		"    `origin_envelope_no` text not null default ''\n" +
+		")",
+	"create unique index if not exists `goods_envelope_no_idx` on `red_envelope_goods` (`envelope_no`)",
+	"create index if not exists `goods_user_idx` on `red_envelope_goods` (`user_id`)",
+	"create trigger if not exists `red_envelope_goods_updated_at` after update on `red_envelope_goods`\n" +
+		"for each row when new.`updated_at` = old.`updated_at`\n" +
+		"begin\n" +
+		"    update `red_envelope_goods` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+		"end",
+	"create table if not exists `red_envelope_item`\n" +
+		"(\n" +
+		"    `id` integer not null primary key autoincrement,\n" +
+		"    `item_no` text not null,\n" +
+		"    `envelope_no` text not null,\n" +
+		"    `recv_user_id` text not null,\n" +
+		"    `recv_username` text not null default '',\n" +
+		"    `amount` text not null default '0.000000',\n" +
+		"    `quantity` integer not null,\n" +
+		"    `remain_amount` text not null default '0.000000',\n" +
+		"    `account_no` text not null,\n" +
+		"    `pay_status` integer not null,\n" +
+		"    `desc` text not null,\n" +
+		"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+		"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+		")",
+	"create unique index if not exists `item_no_idx` on `red_envelope_item` (`item_no`)",
+	"create trigger if not exists `red_envelope_item_updated_at` after update on `red_envelope_item`\n" +
+		"for each row when new.`updated_at` = old.`updated_at`\n" +
+		"begin\n" +
+		"    update `red_envelope_item` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+		"end",
+}
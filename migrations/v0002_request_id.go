@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 账户流水 红包商品 红包订单明细记录产生该数据的请求ID
+// 排查问题时通过请求ID 关联访问日志 业务日志和数据库记录
+// 定时任务等没有请求的操作 request_id 为空
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 2,
+		Name:    "request_id",
+		Up: []string{
+			"alter table `account_log` add column `request_id` varchar(64) not null default '' comment '产生该流水的请求ID'",
+			"alter table `red_envelope_goods` add column `request_id` varchar(64) not null default '' comment '发红包的请求ID'",
+			"alter table `red_envelope_item` add column `request_id` varchar(64) not null default '' comment '收红包的请求ID'",
+		},
+		Down: []string{
+			"alter table `red_envelope_item` drop column `request_id`",
+			"alter table `red_envelope_goods` drop column `request_id`",
+			"alter table `account_log` drop column `request_id`",
+		},
+		Dialects: map[string]migrate.Statements{
+			// SQLite 3.35 开始支持 drop column
+			"sqlite": {
+				Up: []string{
+					"alter table `account_log` add column `request_id` text not null default ''",
+					"alter table `red_envelope_goods` add column `request_id` text not null default ''",
+					"alter table `red_envelope_item` add column `request_id` text not null default ''",
+				},
+				Down: []string{
+					"alter table `red_envelope_item` drop column `request_id`",
+					"alter table `red_envelope_goods` drop column `request_id`",
+					"alter table `account_log` drop column `request_id`",
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 群组和群成员 红包可以绑定到群 只有群成员可以领取
+// group_id 为空的红包不限群
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 3,
+		Name:    "chat_group",
+		Up: []string{
+			"create table if not exists `chat_group`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `group_id` varchar(40) not null comment '群编号，由聊天业务方生成',\n" +
+				"    `group_name` varchar(64) default '' not null comment '群名称',\n" +
+				"    `owner_user_id` varchar(40) not null comment '群主用户编号',\n" +
+				"    `status` tinyint(2) not null comment '群状态：1启用，2解散',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `group_id_idx` (`group_id`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"create table if not exists `chat_group_member`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `group_id` varchar(40) not null comment '群编号',\n" +
+				"    `user_id` varchar(40) not null comment '成员用户编号',\n" +
+				"    `username` varchar(64) default '' not null comment '成员用户名称',\n" +
+				"    `status` tinyint(2) not null comment '成员状态：1在群中，2已退出',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `group_user_idx` (`group_id`, `user_id`) using btree,\n" +
+				"    key `id_user_idx` (`user_id`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"alter table `red_envelope_goods` add column `group_id` varchar(40) not null default '' comment '红包所属群编号，空表示不限群'",
+			"alter table `red_envelope_goods` add index `id_group_idx` (`group_id`)",
+		},
+		Down: []string{
+			"alter table `red_envelope_goods` drop index `id_group_idx`",
+			"alter table `red_envelope_goods` drop column `group_id`",
+			"drop table if exists `chat_group_member`",
+			"drop table if exists `chat_group`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `chat_group`\n" +
+						"(\n" +
+						"    `id` integer not null primary key autoincrement,\n" +
+						"    `group_id` text not null,\n" +
+						"    `group_name` text not null default '',\n" +
+						"    `owner_user_id` text not null,\n" +
+						"    `status` integer not null,\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create unique index if not exists `chat_group_id_idx` on `chat_group` (`group_id`)",
+					"create trigger if not exists `chat_group_updated_at` after update on `chat_group`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `chat_group` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+						"end",
+					"create table if not exists `chat_group_member`\n" +
+						"(\n" +
+						"    `id` integer not null primary key autoincrement,\n" +
+						"    `group_id` text not null,\n" +
+						"    `user_id` text not null,\n" +
+						"    `username` text not null default '',\n" +
+						"    `status` integer not null,\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create unique index if not exists `chat_group_member_idx` on `chat_group_member` (`group_id`, `user_id`)",
+					"create index if not exists `chat_group_member_user_idx` on `chat_group_member` (`user_id`)",
+					"create trigger if not exists `chat_group_member_updated_at` after update on `chat_group_member`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `chat_group_member` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+						"end",
+					"alter table `red_envelope_goods` add column `group_id` text not null default ''",
+					"create index if not exists `goods_group_idx` on `red_envelope_goods` (`group_id`)",
+				},
+				Down: []string{
+					"drop index if exists `goods_group_idx`",
+					"alter table `red_envelope_goods` drop column `group_id`",
+					"drop table if exists `chat_group_member`",
+					"drop table if exists `chat_group`",
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 口令红包 只保存口令的哈希值
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 4,
+		Name:    "passphrase",
+		Up: []string{
+			"alter table `red_envelope_goods` modify column `envelope_type` tinyint(2) not null comment '包含普通红包，碰运气红包，口令红包'",
+			"alter table `red_envelope_goods` add column `passphrase_hash` varchar(64) not null default '' comment '口令红包的口令哈希值，不保存明文'",
+		},
+		Down: []string{
+			"alter table `red_envelope_goods` drop column `passphrase_hash`",
+			"alter table `red_envelope_goods` modify column `envelope_type` tinyint(2) not null comment '包含普通红包，碰运气红包'",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"alter table `red_envelope_goods` add column `passphrase_hash` text not null default ''",
+				},
+				Down: []string{
+					"alter table `red_envelope_goods` drop column `passphrase_hash`",
+				},
+			},
+		},
+	})
+}
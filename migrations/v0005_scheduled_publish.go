@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 预约红包 发红包时冻结资金 到发布时间后扣款发布
+// 已有的红包发布时间等于创建时间
+// SQLite 添加列的默认值只能是常量 写入红包时总是设置发布时间
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 5,
+		Name:    "scheduled_publish",
+		Up: []string{
+			"create table if not exists `account_hold`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL auto_increment,\n" +
+				"    `hold_no` varchar(32) not null comment '冻结编号 全局不重复字母或数字 唯一性标识',\n" +
+				"    `trade_no` varchar(32) not null comment '交易单号 1个交易单号只有1笔冻结',\n" +
+				"    `account_no` varchar(32) NOT NULL COMMENT '被冻结资金的账户编号',\n" +
+				"    `user_id` varchar(40) not null comment '用户编号，账户所属用户',\n" +
+				"    `username` varchar(64) default '' not null comment '用户名称',\n" +
+				"    `amount` decimal(30,6) unsigned not null default '0.000000' comment '冻结金额',\n" +
+				"    `status` tinyint(2) not null comment '冻结状态：1冻结中，2已扣款，3已解冻',\n" +
+				"    `desc` varchar(128) not null comment '冻结描述',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree,\n" +
+				"    unique key `id_hold_no_idx` (`hold_no`) using btree,\n" +
+				"    unique key `id_trade_idx` (`trade_no`) using btree,\n" +
+				"    key `id_account_idx` (`account_no`) using btree\n" +
+				")engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+			"alter table `red_envelope_goods` add column `publish_at` datetime(3) not null default current_timestamp(3) comment '发布时间，预约红包在发布前不能领取'",
+			"update `red_envelope_goods` set `publish_at` = `created_at`",
+			"alter table `red_envelope_goods` add index `id_publish_idx` (`status`, `publish_at`)",
+		},
+		Down: []string{
+			"alter table `red_envelope_goods` drop index `id_publish_idx`",
+			"alter table `red_envelope_goods` drop column `publish_at`",
+			"drop table if exists `account_hold`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `account_hold`\n" +
+						"(\n" +
+						"    `id` integer not null primary key autoincrement,\n" +
+						"    `hold_no` text not null,\n" +
+						"    `trade_no` text not null,\n" +
+						"    `account_no` text not null,\n" +
+						"    `user_id` text not null,\n" +
+						"    `username` text not null default '',\n" +
+						"    `amount` text not null default '0.000000',\n" +
+						"    `status` integer not null,\n" +
+						"    `desc` text not null,\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create unique index if not exists `account_hold_no_idx` on `account_hold` (`hold_no`)",
+					"create unique index if not exists `account_hold_trade_idx` on `account_hold` (`trade_no`)",
+					"create index if not exists `account_hold_account_idx` on `account_hold` (`account_no`)",
+					"create trigger if not exists `account_hold_updated_at` after update on `account_hold`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `account_hold` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+						"end",
+					"alter table `red_envelope_goods` add column `publish_at` datetime not null default '1970-01-01 00:00:00.000'",
+					"update `red_envelope_goods` set `publish_at` = `created_at`",
+					"create index if not exists `goods_publish_idx` on `red_envelope_goods` (`status`, `publish_at`)",
+				},
+				Down: []string{
+					"drop index if exists `goods_publish_idx`",
+					"alter table `red_envelope_goods` drop column `publish_at`",
+					"drop table if exists `account_hold`",
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 过期红包的退款尝试记录 多个节点扫描时按记录认领 失败后按次数重试
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 6,
+		Name:    "refund_attempt",
+		Up: []string{
+			"create table if not exists `red_envelope_refund_attempt`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `envelope_no` varchar(32) not null comment '过期红包编号，1个过期红包只有1条退款尝试记录',\n" +
+				"    `attempts` int(10) unsigned not null default '0' comment '已尝试退款次数',\n" +
+				"    `status` tinyint(2) not null comment '退款尝试状态：1待重试，2处理中，3已完成',\n" +
+				"    `last_error` varchar(255) not null default '' comment '最近1次退款失败的原因',\n" +
+				"    `claimed_at` datetime(3) not null default current_timestamp(3) comment '最近1次认领时间',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `envelope_no_idx` (`envelope_no`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+		},
+		Down: []string{
+			"drop table if exists `red_envelope_refund_attempt`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `red_envelope_refund_attempt`\n" +
+						"(\n" +
+						"    `id` integer not null primary key autoincrement,\n" +
+						"    `envelope_no` text not null,\n" +
+						"    `attempts` integer not null default 0,\n" +
+						"    `status` integer not null,\n" +
+						"    `last_error` text not null default '',\n" +
+						"    `claimed_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create unique index if not exists `refund_attempt_envelope_no_idx` on `red_envelope_refund_attempt` (`envelope_no`)",
+					"create trigger if not exists `red_envelope_refund_attempt_updated_at` after update on `red_envelope_refund_attempt`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `red_envelope_refund_attempt` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+						"end",
+				},
+				Down: []string{
+					"drop table if exists `red_envelope_refund_attempt`",
+				},
+			},
+		},
+	})
+}
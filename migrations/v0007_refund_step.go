@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 过期红包退款 saga 的步骤记录 重启后从最后1个步骤继续
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 7,
+		Name:    "refund_step",
+		Up: []string{
+			"create table if not exists `red_envelope_refund_step`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `refund_no` varchar(32) not null comment '退款订单编号，也是退款转账的交易单号',\n" +
+				"    `envelope_no` varchar(32) not null comment '原红包编号',\n" +
+				"    `step` tinyint(2) not null comment '退款步骤：1创建退款订单，2退款转账，3退款完成，4退款失败补偿',\n" +
+				"    `desc` varchar(255) not null default '' comment '步骤描述',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    unique key `refund_step_idx` (`refund_no`, `step`) using btree ,\n" +
+				"    key `envelope_no_idx` (`envelope_no`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+		},
+		Down: []string{
+			"drop table if exists `red_envelope_refund_step`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `red_envelope_refund_step`\n" +
+						"(\n" +
+						"    `id` integer not null primary key autoincrement,\n" +
+						"    `refund_no` text not null,\n" +
+						"    `envelope_no` text not null,\n" +
+						"    `step` integer not null,\n" +
+						"    `desc` text not null default '',\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create unique index if not exists `refund_step_idx` on `red_envelope_refund_step` (`refund_no`, `step`)",
+					"create index if not exists `refund_step_envelope_no_idx` on `red_envelope_refund_step` (`envelope_no`)",
+				},
+				Down: []string{
+					"drop table if exists `red_envelope_refund_step`",
+				},
+			},
+		},
+	})
+}
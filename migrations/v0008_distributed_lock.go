@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 分布式锁的租约行 lock.provider=lease 使用
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 8,
+		Name:    "distributed_lock",
+		Up: []string{
+			"create table if not exists `distributed_lock`\n" +
+				"(\n" +
+				"    `name` varchar(64) not null comment '锁名称',\n" +
+				"    `owner` varchar(64) not null comment '锁持有者，每次获取锁生成不同的标识',\n" +
+				"    `expired_at` datetime(3) not null comment '租约到期时间，到期后其他节点可以获取锁',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`name`) using btree\n" +
+				") engine = InnoDB DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+		},
+		Down: []string{
+			"drop table if exists `distributed_lock`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `distributed_lock`\n" +
+						"(\n" +
+						"    `name` text not null primary key,\n" +
+						"    `owner` text not null,\n" +
+						"    `expired_at` datetime not null,\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create trigger if not exists `distributed_lock_updated_at` after update on `distributed_lock`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `distributed_lock` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `name` = new.`name`;\n" +
+						"end",
+				},
+				Down: []string{
+					"drop table if exists `distributed_lock`",
+				},
+			},
+		},
+	})
+}
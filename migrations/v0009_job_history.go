@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 定时任务的执行记录
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 9,
+		Name:    "job_history",
+		Up: []string{
+			"create table if not exists `job_history`\n" +
+				"(\n" +
+				"    `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',\n" +
+				"    `job_name` varchar(64) not null comment '任务名称',\n" +
+				"    `node` varchar(64) not null default '' comment '执行任务的节点',\n" +
+				"    `trigger_type` tinyint(2) not null comment '触发方式：1执行计划，2启动时，3手动触发',\n" +
+				"    `status` tinyint(2) not null comment '执行状态：1执行中，2成功，3失败',\n" +
+				"    `error` varchar(512) not null default '' comment '执行失败的原因',\n" +
+				"    `started_at` datetime(3) not null comment '开始时间',\n" +
+				"    `finished_at` datetime(3) not null comment '结束时间，执行中时等于开始时间',\n" +
+				"    `duration_ms` bigint(20) not null default '0' comment '执行耗时，毫秒',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`id`) using btree ,\n" +
+				"    key `job_name_idx` (`job_name`, `id`) using btree\n" +
+				") engine = InnoDB AUTO_INCREMENT=171 DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+		},
+		Down: []string{
+			"drop table if exists `job_history`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `job_history`\n" +
+						"(\n" +
+						"    `id` integer not null primary key autoincrement,\n" +
+						"    `job_name` text not null,\n" +
+						"    `node` text not null default '',\n" +
+						"    `trigger_type` integer not null,\n" +
+						"    `status` integer not null,\n" +
+						"    `error` text not null default '',\n" +
+						"    `started_at` datetime not null,\n" +
+						"    `finished_at` datetime not null,\n" +
+						"    `duration_ms` integer not null default 0,\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create index if not exists `job_name_idx` on `job_history` (`job_name`, `id`)",
+					"create trigger if not exists `job_history_updated_at` after update on `job_history`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `job_history` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `id` = new.`id`;\n" +
+						"end",
+				},
+				Down: []string{
+					"drop table if exists `job_history`",
+				},
+			},
+		},
+	})
+}
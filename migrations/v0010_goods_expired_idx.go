@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 过期延时队列启动时按过期时间加载还没有退款的红包
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 10,
+		Name:    "goods_expired_idx",
+		Up: []string{
+			"alter table `red_envelope_goods` add index `id_expired_idx` (`expired_at`)",
+		},
+		Down: []string{
+			"alter table `red_envelope_goods` drop index `id_expired_idx`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create index if not exists `goods_expired_idx` on `red_envelope_goods` (`expired_at`)",
+				},
+				Down: []string{
+					"drop index if exists `goods_expired_idx`",
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/solozyx/red-envelope/infra/migrate"
+)
+
+// 定时任务的暂停状态 所有节点共用 每次执行前在分布式锁内检查
+// 没有记录的任务使用配置 jobs.<name>.paused
+func init() {
+	migrate.Register(migrate.Migration{
+		Version: 11,
+		Name:    "job_state",
+		Up: []string{
+			"create table if not exists `job_state`\n" +
+				"(\n" +
+				"    `job_name` varchar(64) not null comment '任务名称',\n" +
+				"    `paused` tinyint(2) not null default '0' comment '是否暂停：0执行，1暂停',\n" +
+				"    `created_at` datetime(3) not null default current_timestamp(3) comment '创建时间',\n" +
+				"    `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment '更新时间',\n" +
+				"    primary key (`job_name`) using btree\n" +
+				") engine = InnoDB DEFAULT charset=utf8 ROW_FORMAT=DYNAMIC",
+		},
+		Down: []string{
+			"drop table if exists `job_state`",
+		},
+		Dialects: map[string]migrate.Statements{
+			"sqlite": {
+				Up: []string{
+					"create table if not exists `job_state`\n" +
+						"(\n" +
+						"    `job_name` text not null primary key,\n" +
+						"    `paused` integer not null default 0,\n" +
+						"    `created_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),\n" +
+						"    `updated_at` datetime not null default (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))\n" +
+						")",
+					"create trigger if not exists `job_state_updated_at` after update on `job_state`\n" +
+						"for each row when new.`updated_at` = old.`updated_at`\n" +
+						"begin\n" +
+						"    update `job_state` set `updated_at` = (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')) where `job_name` = new.`job_name`;\n" +
+						"end",
+				},
+				Down: []string{
+					"drop table if exists `job_state`",
+				},
+			},
+		},
+	})
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -16,13 +17,14 @@ func GetAccountService() AccountService {
 	return IAccountService
 }
 
+// 写操作的 ctx 传递请求ID 写入日志和账户流水
 type AccountService interface {
 	// 创建账户
-	CreateAccount(dto AccountCreatedDTO) (*AccountDTO, error)
+	CreateAccount(ctx context.Context, dto AccountCreatedDTO) (*AccountDTO, error)
 	// 转账
-	Transfer(dto AccountTransferDTO) (TransferredStatus, error)
+	Transfer(ctx context.Context, dto AccountTransferDTO) (TransferredStatus, error)
 	// 储值
-	StoreValue(dto AccountTransferDTO) (TransferredStatus, error)
+	StoreValue(ctx context.Context, dto AccountTransferDTO) (TransferredStatus, error)
 	// 红包账户查询
 	GetEnvelopeAccountByUserId(userId string) *AccountDTO
 	GetAccount(accountNo string) *AccountDTO
@@ -42,9 +44,9 @@ type AccountCreatedDTO struct {
 	// 账户类型 用来区分不同类型的账户 积分账户 会员卡账户 钱包账户 红包账户
 	AccountType int `json:"accountType"`
 	// 货币类型编码 CNY人民币 EUR欧元 USD美元
-	CurrencyCode string `json:"currencyCode"`
+	CurrencyCode string `json:"currencyCode" validate:"omitempty,currency"`
 	// TODO:NOTICE 金额 在Go中 float32 float64 计算时会丢失精度 所以金额用字符串来传递避免丢失精度
-	Amount string `json:"amount" validate:"required"`
+	Amount string `json:"amount" validate:"required,decimal=2"`
 }
 
 // 账户
@@ -78,7 +80,7 @@ type AccountDTO struct {
 // 账户交易参与者 交易主体 交易对方 信息一致
 type TradeParticipator struct {
 	// 账户编号
-	AccountNo string `validate:"required" json:"accountNo"`
+	AccountNo string `validate:"required,account_no" json:"accountNo"`
 	// 用户编号
 	UserId string `validate:"required" json:"userId"`
 	// 用户名
@@ -94,7 +96,7 @@ type AccountTransferDTO struct {
 	// 交易对方
 	TradeTarget TradeParticipator `validate:"required" json:"tradeTarget"`
 	// 交易金额
-	AmountStr string          `validate:"required" json:"amountStr"`
+	AmountStr string          `validate:"required,amount=2" json:"amountStr"`
 	Amount    decimal.Decimal ``
 	// 转账变化类型
 	ChangeType ChangeType `validate:"required,numeric" json:"changeType"`
@@ -104,6 +106,18 @@ type AccountTransferDTO struct {
 	Desc string `json:"desc"`
 }
 
+// 账户资金冻结 冻结的资金从可用余额扣除 之后扣款转给交易对方或解冻退回
+type AccountHoldDTO struct {
+	// 交易订单号 1个交易订单号只能有1笔冻结
+	TradeNo string `validate:"required" json:"tradeNo"`
+	// 被冻结资金的账户
+	TradeBody TradeParticipator `validate:"required" json:"tradeBody"`
+	// 冻结金额
+	Amount decimal.Decimal `json:"amount"`
+	// 冻结描述
+	Desc string `json:"desc"`
+}
+
 //账户流水
 type AccountLogDTO struct {
 	LogNo           string          //流水编号 全局不重复字符或数字，唯一性标识
@@ -121,4 +135,5 @@ type AccountLogDTO struct {
 	Status          int             //交易状态：
 	Decs            string          //交易描述
 	CreatedAt       time.Time       //创建时间
+	RequestId       string          //产生该流水的请求ID
 }
@@ -28,6 +28,14 @@ const (
 	EnvelopeExpiredRefund ChangeType = 3
 	// 系统方红包资金的过期退款
 	SysEnvelopeExpiredRefund ChangeType = -3
+	// 预约红包资金冻结 从可用余额中扣除
+	AccountHoldFrozen ChangeType = -4
+	// 预约红包取消 冻结资金退回可用余额
+	AccountHoldReleased ChangeType = 4
+	// 预约红包生效 冻结资金转入系统红包账户
+	EnvelopeHoldCaptured ChangeType = 5
+	// 系统方红包资金的取消退款
+	SysEnvelopeCanceledRefund ChangeType = -6
 )
 
 // 资金交易的变化标识
@@ -50,5 +58,14 @@ const (
 	SystemEnvelopeAccountType AccountType = 2
 )
 
+// 冻结资金状态 冻结中 已扣款 已解冻
+type HoldStatus int8
+
+const (
+	HoldStatusHeld     HoldStatus = 1
+	HoldStatusCaptured HoldStatus = 2
+	HoldStatusReleased HoldStatus = 3
+)
+
 // 货币类型
 const DefaultCurrencyCode = "CNY"
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -16,11 +17,12 @@ func GetRedEnvelopeService() RedEnvelopeService {
 }
 
 // 红包服务接口
+// 写操作的 ctx 传递请求ID 写入日志和数据库记录 参考 base.RequestContext
 type RedEnvelopeService interface {
 	// 发红包
-	SendOut(RedEnvelopeSendingDTO) (*RedEnvelopeActivity, error)
+	SendOut(context.Context, RedEnvelopeSendingDTO) (*RedEnvelopeActivity, error)
 	// 收红包 返回订单详情信息
-	Receive(RedEnvelopeReceiveDTO) (*RedEnvelopeItemDTO, error)
+	Receive(context.Context, RedEnvelopeReceiveDTO) (*RedEnvelopeItemDTO, error)
 	// 退款 返回红包商品信息
 	Refund(envelopeNo string) (order *RedEnvelopeGoodsDTO)
 	// 查询红包订单
@@ -29,7 +31,10 @@ type RedEnvelopeService interface {
 	ListSent(string, int, int) []*RedEnvelopeGoodsDTO
 	ListReceived(userId string, page, size int) []*RedEnvelopeItemDTO
 	ListItems(envelopeNo string) []*RedEnvelopeItemDTO
-	ListReceivable(int, int) []*RedEnvelopeGoodsDTO
+	// 查询用户可领取的红包列表 只返回用户所在群的红包和不限群的红包
+	ListReceivable(userId string, offset, size int) []*RedEnvelopeGoodsDTO
+	// 取消红包 只有发红包的人可以取消
+	Cancel(context.Context, RedEnvelopeCancelDTO) error
 }
 
 // 发红包
@@ -40,8 +45,16 @@ type RedEnvelopeSendingDTO struct {
 	Blessing     string `json:"blessing"`
 	// 根据红包类型 EnvelopeType 区分 普通红包指单个红包金额 碰运气红包指红包总金额
 	// Amount   decimal.Decimal `json:"amount" validate:"required,numeric"`
-	Amount   string `json:"amount" validate:"required"`
+	Amount   string `json:"amount" validate:"required,amount=2"`
 	Quantity int    `json:"quantity" validate:"required,numeric"`
+	// 红包发往的群编号 为空表示不限群
+	GroupId string `json:"groupId"`
+	// 口令红包的口令 只用于口令红包
+	Passphrase string `json:"passphrase"`
+	// 预约发布时间 为空或早于当前时间表示立即发布
+	// 预约红包在发布前只冻结发红包人的资金 到发布时间才扣款
+	PublishAt time.Time `json:"publishAt"`
+	RpcContext
 }
 
 func (dto *RedEnvelopeSendingDTO) ToGoods() *RedEnvelopeGoodsDTO {
@@ -52,16 +65,38 @@ func (dto *RedEnvelopeSendingDTO) ToGoods() *RedEnvelopeGoodsDTO {
 		Blessing:     dto.Blessing,
 		Amount:       dto.Amount,
 		Quantity:     dto.Quantity,
+		GroupId:      dto.GroupId,
+		Passphrase:   dto.Passphrase,
+		PublishAt:    dto.PublishAt,
 	}
 }
 
 // 收红包
 type RedEnvelopeReceiveDTO struct {
-	EnvelopeNo   string `json:"envelopeNo" validate:"required"`
+	EnvelopeNo   string `json:"envelopeNo" validate:"required,ksuid"`
 	RecvUsername string `json:"recvUsername" validate:"required"`
 	RecvUserId   string `json:"recvUserId" validate:"required"`
 	// 内部通过 RecvUserId 查询出账号
-	AccountNo string `json:"accountNo"`
+	AccountNo string `json:"accountNo" validate:"omitempty,account_no"`
+	// 口令红包需要提交的口令
+	Passphrase string `json:"passphrase"`
+	RpcContext
+}
+
+// RPC 调用的上下文 嵌入到 RPC 接口的参数中 只用于 RPC 调用
+// RPC 没有请求头 由调用方在参数中传入 http 接口使用请求头 X-Request-Id traceparent
+type RpcContext struct {
+	// 请求ID 为空时服务端生成
+	RequestId string `json:"requestId"`
+	// W3C trace context 调用方通过 tracing.Inject 生成
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// 取消红包
+type RedEnvelopeCancelDTO struct {
+	EnvelopeNo string `json:"envelopeNo" validate:"required,ksuid"`
+	// 发红包用户编号 只有发红包的人可以取消
+	UserId string `json:"userId" validate:"required"`
 }
 
 type RedEnvelopeActivity struct {
@@ -89,6 +124,9 @@ func (this *RedEnvelopeActivity) CopyTo(target *RedEnvelopeActivity) {
 	target.PayStatus = this.PayStatus
 	target.CreatedAt = this.CreatedAt
 	target.UpdatedAt = this.UpdatedAt
+	target.GroupId = this.GroupId
+	target.PublishAt = this.PublishAt
+	target.RequestId = this.RequestId
 }
 
 // 红包商品
@@ -114,6 +152,14 @@ type RedEnvelopeGoodsDTO struct {
 	AccountNo      string          `json:"accountNo"`
 	// 原关联订单号
 	OriginEnvelopeNo string `json:"originEnvelopeNo"`
+	// 所属群编号
+	GroupId string `json:"groupId"`
+	// 口令明文 只在发红包时传入领域层加密 不对外输出
+	Passphrase string `json:"-"`
+	// 发布时间 预约红包在发布时间之前不能领取
+	PublishAt time.Time `json:"publishAt"`
+	// 发红包的请求ID
+	RequestId string `json:"requestId"`
 }
 
 // 红包详情
@@ -131,6 +177,7 @@ type RedEnvelopeItemDTO struct {
 	UpdatedAt    time.Time       `json:"updatedAt"`    // 修改时间
 	Desc         string          `json:"desc"`
 	IsLuckiest   bool            `json:"isLuckiest"` // 是否是最幸运的
+	RequestId    string          `json:"requestId"`  // 收红包的请求ID
 }
 
 func (this *RedEnvelopeItemDTO) CopyTo(target *RedEnvelopeItemDTO) {
@@ -145,4 +192,5 @@ func (this *RedEnvelopeItemDTO) CopyTo(target *RedEnvelopeItemDTO) {
 	target.PayStatus = this.PayStatus
 	target.CreatedAt = this.CreatedAt
 	target.UpdatedAt = this.UpdatedAt
+	target.RequestId = this.RequestId
 }
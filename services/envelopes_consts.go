@@ -40,6 +40,25 @@ const (
 	OrderExpiredRefundFiled   OrderStatus = 6
 )
 
+// 过期红包退款尝试状态 待重试 处理中 已完成
+type RefundAttemptStatus int
+
+const (
+	RefundAttemptPending    RefundAttemptStatus = 1
+	RefundAttemptProcessing RefundAttemptStatus = 2
+	RefundAttemptSucceeded  RefundAttemptStatus = 3
+)
+
+// 退款流程步骤 创建退款订单 退款转账 退款完成 退款失败补偿
+type RefundStep int
+
+const (
+	RefundStepCreated     RefundStep = 1
+	RefundStepTransferred RefundStep = 2
+	RefundStepCompleted   RefundStep = 3
+	RefundStepCompensated RefundStep = 4
+)
+
 // 红包活动 创建 激活 过期 失效
 type ActivityStatus int
 
@@ -50,12 +69,14 @@ const (
 	ActivityDisabled  ActivityStatus = 4
 )
 
-// 红包类型 普通红包 碰运气红包
+// 红包类型 普通红包 碰运气红包 口令红包
 type EnvelopeType int
 
 const (
 	GeneralEnvelopeType EnvelopeType = 1
 	LuckyEnvelopeType   EnvelopeType = 2
+	// 口令红包 金额分配同碰运气红包 收红包时需要输入发红包人设置的口令
+	PassphraseEnvelopeType EnvelopeType = 3
 )
 
 const DefaultTimeFormat = "2006-01-02 15:04:05"
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"time"
+
+	"github.com/solozyx/red-envelope/infra/base"
+)
+
+var IGroupService GroupService
+
+// 用于对外暴露聊天群成员应用服务 唯一的暴露点
+func GetGroupService() GroupService {
+	base.Check(IGroupService)
+	return IGroupService
+}
+
+// 聊天群成员服务接口
+type GroupService interface {
+	// 创建群 创建者自动成为群成员
+	CreateGroup(dto GroupCreatedDTO) (*GroupDTO, error)
+	// 加入群
+	Join(dto GroupMemberDTO) error
+	// 退出群
+	Leave(dto GroupMemberDTO) error
+	// 查询群信息
+	GetGroup(groupId string) *GroupDTO
+	// 是否是群成员
+	IsMember(groupId, userId string) bool
+	// 群成员数量
+	CountMembers(groupId string) int
+	// 查询用户加入的所有群编号
+	ListGroupIds(userId string) []string
+}
+
+// 群创建
+type GroupCreatedDTO struct {
+	// 群编号 由聊天业务方生成
+	GroupId string `json:"groupId" validate:"required"`
+	// 群名称
+	GroupName string `json:"groupName" validate:"required"`
+	// 群主用户编号
+	OwnerUserId string `json:"ownerUserId" validate:"required"`
+	// 群主用户名称
+	OwnerUsername string `json:"ownerUsername" validate:"required"`
+}
+
+// 群
+type GroupDTO struct {
+	GroupId     string      `json:"groupId"`
+	GroupName   string      `json:"groupName"`
+	OwnerUserId string      `json:"ownerUserId"`
+	Status      GroupStatus `json:"status"`
+	// 当前群成员数量
+	MemberCount int       `json:"memberCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// 加入 退出群
+type GroupMemberDTO struct {
+	GroupId  string `json:"groupId" validate:"required"`
+	UserId   string `json:"userId" validate:"required"`
+	Username string `json:"username" validate:"required"`
+}
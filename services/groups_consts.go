@@ -0,0 +1,17 @@
+package services
+
+// 群状态 启用 解散
+type GroupStatus int
+
+const (
+	GroupEnabled   GroupStatus = 1
+	GroupDisbanded GroupStatus = 2
+)
+
+// 群成员状态 在群中 已退出
+type MemberStatus int
+
+const (
+	MemberJoined MemberStatus = 1
+	MemberLeft   MemberStatus = 2
+)
@@ -1,14 +1,18 @@
 package textx
 
 import (
-	"github.com/sirupsen/logrus"
+	"os"
 	"strings"
 
+	"github.com/sirupsen/logrus"
 	"github.com/tietang/props/ini"
 
 	"github.com/solozyx/red-envelope/comm"
 	"github.com/solozyx/red-envelope/infra"
 	"github.com/solozyx/red-envelope/infra/base"
+	"github.com/solozyx/red-envelope/infra/filter"
+	"github.com/solozyx/red-envelope/infra/migrate"
+	_ "github.com/solozyx/red-envelope/migrations"
 )
 
 func init() {
@@ -19,10 +23,17 @@ func init() {
 	logrus.Info("配置文件路径 %s", path)
 	// 加载和解析配置文件
 	conf := ini.NewIniFileCompositeConfigSource(path)
+	// 没有数据库的环境 REPOSITORY_BACKEND=memory 使用内存数据库运行测试
+	if b := os.Getenv("REPOSITORY_BACKEND"); b != "" {
+		conf.Set("repository.backend", b)
+	}
 
 	infra.Register(&base.PropsStarter{})
+	infra.Register(&base.LogStarter{})
 	infra.Register(&base.DbxDatabaseStarter{})
+	infra.Register(&migrate.MigrationStarter{})
 	infra.Register(&base.ValidatorStarter{})
+	infra.Register(&filter.ContentFilterStarter{})
 
 	app := infra.New(conf)
 	app.Start()